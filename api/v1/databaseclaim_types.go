@@ -202,6 +202,151 @@ type DatabaseClaimSpec struct {
 	// +optional
 	// +nullable
 	Tags []Tag `json:"tags,omitempty"`
+
+	// Parameters are engine parameters merged onto the defaults of the parameter group
+	// of a dynamically provisioned host. Parameter names must be permitted by the
+	// allowedParameters and deniedParameters lists of the db-controller configMap.
+	// +optional
+	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// Extensions are installed in the database and kept at the requested version.
+	// Extension names must be permitted by the allowedExtensions list of the db-controller configMap.
+	// +optional
+	Extensions []Extension `json:"extensions,omitempty"`
+
+	// DropRemovedExtensions drops extensions that were previously installed from Extensions
+	// and are no longer listed
+	// +optional
+	DropRemovedExtensions bool `json:"dropRemovedExtensions,omitempty"`
+
+	// Schemas are created in the database owned by the group role of Username.
+	// Grants and default privileges of the group role are kept in sync on every reconcile.
+	// +optional
+	Schemas []Schema `json:"schemas,omitempty"`
+
+	// Databases are additional databases created on the host of the claim, each with its
+	// own user and group role. Their connection info is written to SecretName with the keys
+	// prefixed by SecretKeyPrefix. Only the database named by DatabaseName is migrated
+	// when the claim moves to a new host, additional databases are created empty.
+	// +optional
+	Databases []AdditionalDatabase `json:"databases,omitempty"`
+
+	// AdditionalUsers are users of the database next to Username, each with its own group role,
+	// privilege profile and connection info secret. Their logins are rotated like the ones of Username.
+	// +optional
+	AdditionalUsers []AdditionalUser `json:"additionalUsers,omitempty"`
+
+	// ConnectionLimit is the maximum number of concurrent connections of each login of Username.
+	// The limit is removed when omitted.
+	// +optional
+	// +kubebuilder:validation:Minimum=-1
+	ConnectionLimit *int32 `json:"connectionLimit,omitempty"`
+
+	// DatabaseSettings are configuration parameters set as defaults of the database,
+	// e.g. statement_timeout, lock_timeout or search_path
+	// +optional
+	DatabaseSettings map[string]string `json:"databaseSettings,omitempty"`
+
+	// RoleSettings are configuration parameters set as defaults of the logins of Username,
+	// e.g. statement_timeout or idle_in_transaction_session_timeout
+	// +optional
+	RoleSettings map[string]string `json:"roleSettings,omitempty"`
+}
+
+type PrivilegeProfile string
+
+// Privilege profiles of additional users
+const (
+	// OwnerProfile makes the user a member of the group role of Username
+	OwnerProfile PrivilegeProfile = "owner"
+	// ReadWriteProfile grants reading and writing the tables and sequences
+	ReadWriteProfile PrivilegeProfile = "read-write"
+	// ReadOnlyProfile grants reading the tables and sequences
+	ReadOnlyProfile PrivilegeProfile = "read-only"
+	// CustomProfile grants the privileges listed in Grants
+	CustomProfile PrivilegeProfile = "custom"
+)
+
+// AdditionalUser defines a user of the database with its own privileges
+type AdditionalUser struct {
+	// The username that the application will use for accessing the database.
+	// +required
+	Username string `json:"userName"`
+
+	// Profile defines the privileges of the user. The read-write and read-only profiles apply
+	// to the public schema and to Schemas, on the tables existing at each reconcile.
+	// Privileges granted by a previous custom profile are not revoked.
+	// +optional
+	// +kubebuilder:validation:Enum=owner;read-write;read-only;custom
+	// +kubebuilder:default:=read-write
+	Profile PrivilegeProfile `json:"profile,omitempty"`
+
+	// Grants of the custom profile
+	// +optional
+	Grants []Grant `json:"grants,omitempty"`
+
+	// ConnectionLimit is the maximum number of concurrent connections of each login of the user
+	// +optional
+	// +kubebuilder:validation:Minimum=-1
+	ConnectionLimit *int32 `json:"connectionLimit,omitempty"`
+
+	// The name of the secret to use for storing the ConnectionInfo of the user.
+	// +required
+	SecretName string `json:"secretName"`
+}
+
+// Grant defines table privileges of a custom profile
+type Grant struct {
+	// Privileges on the tables, e.g. SELECT or INSERT
+	// +required
+	Privileges []string `json:"privileges"`
+
+	// Schema of the tables
+	// +optional
+	// +kubebuilder:default:=public
+	Schema string `json:"schema,omitempty"`
+
+	// Tables the privileges are granted on, every table of the schema when omitted
+	// +optional
+	Tables []string `json:"tables,omitempty"`
+}
+
+// AdditionalDatabase defines a database provisioned next to the main database of the claim
+type AdditionalDatabase struct {
+	// Name of the database
+	// +required
+	Name string `json:"name"`
+
+	// The username that the application will use for accessing the database.
+	// +required
+	Username string `json:"userName"`
+
+	// SecretKeyPrefix is prepended to the keys of the connection info of the database.
+	// Defaults to the database name followed by an underscore.
+	// +optional
+	SecretKeyPrefix string `json:"secretKeyPrefix,omitempty"`
+}
+
+// Schema defines a schema to create in the database
+type Schema struct {
+	// Name of the schema
+	// +required
+	Name string `json:"name"`
+}
+
+// Extension defines a postgres extension to install in the database
+type Extension struct {
+	// Name of the extension
+	// +required
+	Name string `json:"name"`
+
+	// Version of the extension, the default version is installed when omitted
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Schema to install the extension objects in, the extension default is used when omitted
+	// +optional
+	Schema string `json:"schema,omitempty"`
 }
 
 // Tag
@@ -221,6 +366,176 @@ type DatabaseClaimStatus struct {
 	//tracks status of DB migration. if empty, not started.
 	//non empty denotes migration in progress, unless it is S_Completed
 	MigrationState string `json:"migrationState,omitempty"`
+	//replication objects of the migration in progress, unique per claim
+	Migration *MigrationStatus `json:"migration,omitempty"`
+	//report of the last completed migration, kept once Migration is removed
+	LastMigration *MigrationReportStatus `json:"lastMigration,omitempty"`
+	//tracks the parameter group of a dynamically provisioned host
+	ParameterGroup *ParameterGroupStatus `json:"parameterGroup,omitempty"`
+	//tracks the cluster parameter group of a dynamically provisioned aurora-postgresql host
+	ClusterParameterGroup *ParameterGroupStatus `json:"clusterParameterGroup,omitempty"`
+	//statements the controller would run for the claim, set when the plan annotation is "true"
+	Plan *PlanStatus `json:"plan,omitempty"`
+}
+
+// MigrationStatus holds the names of the replication objects of a migration, they
+// are kept for the whole migration so that it resumes with the same objects
+type MigrationStatus struct {
+	// Publication created on the source database
+	Publication string `json:"publication,omitempty"`
+
+	// Subscription created on the target database
+	Subscription string `json:"subscription,omitempty"`
+
+	// Replication slot created on the source for the subscription
+	Slot string `json:"slot,omitempty"`
+
+	// Tables of the subscription synchronized with the source, out of TablesTotal
+	TablesSynced int `json:"tablesSynced,omitempty"`
+	TablesTotal  int `json:"tablesTotal,omitempty"`
+
+	// WAL of the source not confirmed by the subscription yet, in bytes
+	LagBytes *int64 `json:"lagBytes,omitempty"`
+
+	// Tables and rows whose count on the target matches the source
+	TablesValidated int   `json:"tablesValidated,omitempty"`
+	RowsValidated   int64 `json:"rowsValidated,omitempty"`
+
+	// Tables not synchronized or validated yet, at most maxMigrationTables of them
+	Tables []MigrationTableStatus `json:"tables,omitempty"`
+
+	// Time the progress was last observed
+	UpdatedAt *metav1.Time `json:"updatedAt,omitempty"`
+
+	// Checks run before the replication starts
+	PreFlight []PreFlightCheckStatus `json:"preFlight,omitempty"`
+
+	// Write privileges revoked on the source once the applications use the target,
+	// granting them back restores the access to the source
+	FrozenGrants []FrozenGrantStatus `json:"frozenGrants,omitempty"`
+
+	// Source the privileges are revoked on
+	Source *MigrationSourceStatus `json:"source,omitempty"`
+
+	// Non-login roles of the source created on the target with the schema, by every
+	// attempt
+	CreatedRoles []string `json:"createdRoles,omitempty"`
+
+	// Roles of the source without an equivalent on the target, their grants and
+	// ownerships were not migrated
+	UnmappedRoles []string `json:"unmappedRoles,omitempty"`
+
+	// Attempts of the current state that failed or had to be retried
+	Attempts int `json:"attempts,omitempty"`
+
+	// LastError of the current state
+	LastError string `json:"lastError,omitempty"`
+
+	// State that exhausted its attempts when the migration state is failed
+	FailedState string `json:"failedState,omitempty"`
+}
+
+// MigrationReportStatus is what a completed migration changed outside of the target
+// database of the claim
+type MigrationReportStatus struct {
+	// Time the migration completed
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+
+	// Non-login roles of the source created on the target, by every attempt
+	CreatedRoles []string `json:"createdRoles,omitempty"`
+
+	// Roles of the source without an equivalent on the target, their grants and
+	// ownerships were not migrated
+	UnmappedRoles []string `json:"unmappedRoles,omitempty"`
+
+	// Write privileges revoked on the source and not granted back yet
+	FrozenGrants []FrozenGrantStatus `json:"frozenGrants,omitempty"`
+
+	// Source the privileges were revoked on
+	Source *MigrationSourceStatus `json:"source,omitempty"`
+
+	// Time the frozen grants were granted back on the source
+	SourceAccessRestoredAt *metav1.Time `json:"sourceAccessRestoredAt,omitempty"`
+}
+
+// MigrationSourceStatus locates the master user of the source of a migration, its
+// password is read from a secret
+type MigrationSourceStatus struct {
+	// Connection info of the master user, without its password
+	ConnectionInfo *DatabaseClaimConnectionInfo `json:"connectionInfo,omitempty"`
+
+	// Secret holding the password of the master user in its password key
+	SecretNamespace string `json:"secretNamespace,omitempty"`
+	SecretName      string `json:"secretName"`
+}
+
+// PreFlightCheckStatus is the outcome of a check run before a migration starts
+type PreFlightCheckStatus struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+// FrozenGrantStatus are write privileges revoked from a role on tables of the source
+type FrozenGrantStatus struct {
+	// Role the privileges were revoked from, empty for PUBLIC
+	Role       string   `json:"role,omitempty"`
+	Privileges []string `json:"privileges"`
+	Tables     []string `json:"tables"`
+}
+
+// MigrationTableStatus is the replication or validation state of a table
+type MigrationTableStatus struct {
+	Name string `json:"name"`
+
+	// State of the table in the subscription: i initialize, d data copy,
+	// f finished copy, s synchronized, r ready
+	State string `json:"state,omitempty"`
+
+	// Rows counted by the validation on the source and the target
+	SourceRows *int64 `json:"sourceRows,omitempty"`
+	TargetRows *int64 `json:"targetRows,omitempty"`
+
+	// Chunks whose checksum did not match, out of the chunks compared
+	ChunksMismatched int `json:"chunksMismatched,omitempty"`
+	ChunksVerified   int `json:"chunksVerified,omitempty"`
+
+	// Mismatch describes the first chunk not matching
+	Mismatch string `json:"mismatch,omitempty"`
+}
+
+// PlanStatus holds the database statements planned for the claim
+type PlanStatus struct {
+	// Statements that would be run, prefixed by the database they run in
+	Statements []string `json:"statements,omitempty"`
+
+	// Time the plan was computed
+	PlannedAt *metav1.Time `json:"plannedAt,omitempty"`
+
+	// Error that stopped the plan
+	Error string `json:"error,omitempty"`
+
+	// ReconcilePaused is set while the plan annotation pauses the reconcile of the claim,
+	// only the password of its user keeps being rotated
+	ReconcilePaused bool `json:"reconcilePaused,omitempty"`
+
+	// RotationError is the error of the last password rotation while the claim is planned
+	RotationError string `json:"rotationError,omitempty"`
+}
+
+// ParameterGroupStatus defines the observed state of the parameter group used by the host
+type ParameterGroupStatus struct {
+	// Name of the crossplane DBParameterGroup or DBClusterParameterGroup
+	Name string `json:"name,omitempty"`
+
+	// Parameters from the claim that were applied on top of the defaults
+	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// PendingReboot is set when a parameter that is only applied after a reboot has changed
+	PendingReboot bool `json:"pendingReboot,omitempty"`
+
+	// Time the parameter group was last updated
+	UpdatedAt *metav1.Time `json:"updatedAt,omitempty"`
 }
 
 type Status struct {
@@ -250,8 +565,127 @@ type Status struct {
 	// Time the user/password was updated/created
 	UserUpdatedAt *metav1.Time `json:"userUpdatedAt,omitempty"`
 
+	// Logins of the user with their credential generation
+	Logins []LoginStatus `json:"logins,omitempty"`
+
 	// DbState of the DB. inprogress, "", ready
 	DbState DbState `json:"DbState,omitempty"`
+
+	// Extensions managed from the claim and their installed state
+	Extensions []ExtensionStatus `json:"extensions,omitempty"`
+
+	// Schemas managed from the claim and the state of their grants
+	Schemas []SchemaStatus `json:"schemas,omitempty"`
+
+	// Additional databases managed from the claim
+	Databases []DatabaseStatus `json:"databases,omitempty"`
+
+	// Additional users managed from the claim
+	Users []UserStatus `json:"users,omitempty"`
+
+	// Database and role settings managed from the claim
+	Settings *SettingsStatus `json:"settings,omitempty"`
+}
+
+// SettingsStatus defines the observed state of the database and role settings of the claim
+type SettingsStatus struct {
+	// Database settings applied from the claim
+	Database map[string]string `json:"database,omitempty"`
+
+	// Role settings applied from the claim
+	Role map[string]string `json:"role,omitempty"`
+
+	// Settings that drifted from the claim and were corrected the last time drift was found
+	CorrectedDrift []string `json:"correctedDrift,omitempty"`
+
+	// Time drift was last corrected
+	CorrectedAt *metav1.Time `json:"correctedAt,omitempty"`
+
+	// Any errors related to applying the settings
+	Error string `json:"error,omitempty"`
+}
+
+// UserStatus defines the observed state of an additional user requested by the claim
+type UserStatus struct {
+	Name string `json:"name"`
+
+	// Connection info of the current login of the user, the password is only stored in the secret
+	ConnectionInfo *DatabaseClaimConnectionInfo `json:"connectionInfo,omitempty"`
+
+	// Time the user/password was updated/created
+	UserUpdatedAt *metav1.Time `json:"userUpdatedAt,omitempty"`
+
+	// Logins of the user with their credential generation
+	Logins []LoginStatus `json:"logins,omitempty"`
+
+	// Any errors related to provisioning this user
+	Error string `json:"error,omitempty"`
+}
+
+// LoginStatus defines the observed state of a login rotated by the controller
+type LoginStatus struct {
+	Username string `json:"username"`
+
+	// Generation of the credential, 0 is the one in the secret, 1 the one it replaced and so on
+	Generation int `json:"generation"`
+
+	// Time the password of the login was set
+	RotatedAt *metav1.Time `json:"rotatedAt,omitempty"`
+
+	// Disabled is set when the login was made NOLOGIN because its grace period expired
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// DatabaseStatus defines the observed state of an additional database requested by the claim
+type DatabaseStatus struct {
+	Name string `json:"name"`
+
+	// Connection info of the database, the password is only stored in the secret
+	ConnectionInfo *DatabaseClaimConnectionInfo `json:"connectionInfo,omitempty"`
+
+	// Time the database was created
+	DbCreatedAt *metav1.Time `json:"dbCreateAt,omitempty"`
+
+	// Time the user/password was updated/created
+	UserUpdatedAt *metav1.Time `json:"userUpdatedAt,omitempty"`
+
+	// Logins of the user of the database with their credential generation
+	Logins []LoginStatus `json:"logins,omitempty"`
+
+	// Any errors related to provisioning this database
+	Error string `json:"error,omitempty"`
+}
+
+// SchemaStatus defines the observed state of a schema requested by the claim
+type SchemaStatus struct {
+	Name string `json:"name"`
+
+	// Drift of ownership or privileges repaired during the last reconcile
+	RepairedDrift []string `json:"repairedDrift,omitempty"`
+
+	// Time drift was last repaired
+	RepairedAt *metav1.Time `json:"repairedAt,omitempty"`
+
+	// Any errors related to creating this schema or repairing its grants
+	Error string `json:"error,omitempty"`
+}
+
+// ExtensionStatus defines the observed state of an extension requested by the claim
+type ExtensionStatus struct {
+	Name string `json:"name"`
+
+	// Installed version of the extension
+	Version string `json:"version,omitempty"`
+
+	// Schema the extension is installed in
+	Schema string `json:"schema,omitempty"`
+
+	// Removed is set for an extension removed from the claim that is still installed,
+	// it is dropped once DropRemovedExtensions is set
+	Removed bool `json:"removed,omitempty"`
+
+	// Any errors related to installing or updating this extension
+	Error string `json:"error,omitempty"`
 }
 
 // DbState keeps track of state of the DB.
@@ -308,12 +742,26 @@ func (c *DatabaseClaimConnectionInfo) Uri() string {
 		url.QueryEscape(c.Username), url.QueryEscape(c.Password), c.Host, c.Port, url.QueryEscape(c.DatabaseName), c.SSLMode)
 }
 
+// MarshalLog logs the connection info with its password masked
+func (c DatabaseClaimConnectionInfo) MarshalLog() interface{} {
+	type connectionInfo DatabaseClaimConnectionInfo
+	masked := connectionInfo(c)
+	if masked.Password != "" {
+		masked.Password = "***"
+	}
+	return masked
+}
+
 func ParseUri(dsn string) (*DatabaseClaimConnectionInfo, error) {
 
 	c := DatabaseClaimConnectionInfo{}
 
 	u, err := url.Parse(dsn)
 	if err != nil {
+		// the error of url.Parse holds the dsn and its password
+		if urlErr, ok := err.(*url.Error); ok {
+			return nil, fmt.Errorf("invalid dsn: %s", urlErr.Err)
+		}
 		return nil, err
 	}
 
@@ -25,6 +25,48 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdditionalDatabase) DeepCopyInto(out *AdditionalDatabase) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdditionalDatabase.
+func (in *AdditionalDatabase) DeepCopy() *AdditionalDatabase {
+	if in == nil {
+		return nil
+	}
+	out := new(AdditionalDatabase)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdditionalUser) DeepCopyInto(out *AdditionalUser) {
+	*out = *in
+	if in.Grants != nil {
+		in, out := &in.Grants, &out.Grants
+		*out = make([]Grant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ConnectionLimit != nil {
+		in, out := &in.ConnectionLimit, &out.ConnectionLimit
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdditionalUser.
+func (in *AdditionalUser) DeepCopy() *AdditionalUser {
+	if in == nil {
+		return nil
+	}
+	out := new(AdditionalUser)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Database) DeepCopyInto(out *Database) {
 	*out = *in
@@ -152,6 +194,54 @@ func (in *DatabaseClaimSpec) DeepCopyInto(out *DatabaseClaimSpec) {
 		*out = make([]Tag, len(*in))
 		copy(*out, *in)
 	}
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Extensions != nil {
+		in, out := &in.Extensions, &out.Extensions
+		*out = make([]Extension, len(*in))
+		copy(*out, *in)
+	}
+	if in.Schemas != nil {
+		in, out := &in.Schemas, &out.Schemas
+		*out = make([]Schema, len(*in))
+		copy(*out, *in)
+	}
+	if in.Databases != nil {
+		in, out := &in.Databases, &out.Databases
+		*out = make([]AdditionalDatabase, len(*in))
+		copy(*out, *in)
+	}
+	if in.AdditionalUsers != nil {
+		in, out := &in.AdditionalUsers, &out.AdditionalUsers
+		*out = make([]AdditionalUser, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ConnectionLimit != nil {
+		in, out := &in.ConnectionLimit, &out.ConnectionLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.DatabaseSettings != nil {
+		in, out := &in.DatabaseSettings, &out.DatabaseSettings
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.RoleSettings != nil {
+		in, out := &in.RoleSettings, &out.RoleSettings
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseClaimSpec.
@@ -169,6 +259,31 @@ func (in *DatabaseClaimStatus) DeepCopyInto(out *DatabaseClaimStatus) {
 	*out = *in
 	in.NewDB.DeepCopyInto(&out.NewDB)
 	in.ActiveDB.DeepCopyInto(&out.ActiveDB)
+	if in.Migration != nil {
+		in, out := &in.Migration, &out.Migration
+		*out = new(MigrationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastMigration != nil {
+		in, out := &in.LastMigration, &out.LastMigration
+		*out = new(MigrationReportStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ParameterGroup != nil {
+		in, out := &in.ParameterGroup, &out.ParameterGroup
+		*out = new(ParameterGroupStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClusterParameterGroup != nil {
+		in, out := &in.ClusterParameterGroup, &out.ClusterParameterGroup
+		*out = new(ParameterGroupStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Plan != nil {
+		in, out := &in.Plan, &out.Plan
+		*out = new(PlanStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseClaimStatus.
@@ -181,6 +296,41 @@ func (in *DatabaseClaimStatus) DeepCopy() *DatabaseClaimStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseStatus) DeepCopyInto(out *DatabaseStatus) {
+	*out = *in
+	if in.ConnectionInfo != nil {
+		in, out := &in.ConnectionInfo, &out.ConnectionInfo
+		*out = new(DatabaseClaimConnectionInfo)
+		**out = **in
+	}
+	if in.DbCreatedAt != nil {
+		in, out := &in.DbCreatedAt, &out.DbCreatedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.UserUpdatedAt != nil {
+		in, out := &in.UserUpdatedAt, &out.UserUpdatedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Logins != nil {
+		in, out := &in.Logins, &out.Logins
+		*out = make([]LoginStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseStatus.
+func (in *DatabaseStatus) DeepCopy() *DatabaseStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DbRoleClaim) DeepCopyInto(out *DbRoleClaim) {
 	*out = *in
@@ -288,6 +438,318 @@ func (in *DbRoleClaimStatus) DeepCopy() *DbRoleClaimStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Extension) DeepCopyInto(out *Extension) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Extension.
+func (in *Extension) DeepCopy() *Extension {
+	if in == nil {
+		return nil
+	}
+	out := new(Extension)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExtensionStatus) DeepCopyInto(out *ExtensionStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExtensionStatus.
+func (in *ExtensionStatus) DeepCopy() *ExtensionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ExtensionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FrozenGrantStatus) DeepCopyInto(out *FrozenGrantStatus) {
+	*out = *in
+	if in.Privileges != nil {
+		in, out := &in.Privileges, &out.Privileges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Tables != nil {
+		in, out := &in.Tables, &out.Tables
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FrozenGrantStatus.
+func (in *FrozenGrantStatus) DeepCopy() *FrozenGrantStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FrozenGrantStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Grant) DeepCopyInto(out *Grant) {
+	*out = *in
+	if in.Privileges != nil {
+		in, out := &in.Privileges, &out.Privileges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Tables != nil {
+		in, out := &in.Tables, &out.Tables
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Grant.
+func (in *Grant) DeepCopy() *Grant {
+	if in == nil {
+		return nil
+	}
+	out := new(Grant)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoginStatus) DeepCopyInto(out *LoginStatus) {
+	*out = *in
+	if in.RotatedAt != nil {
+		in, out := &in.RotatedAt, &out.RotatedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoginStatus.
+func (in *LoginStatus) DeepCopy() *LoginStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(LoginStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationReportStatus) DeepCopyInto(out *MigrationReportStatus) {
+	*out = *in
+	if in.CompletedAt != nil {
+		in, out := &in.CompletedAt, &out.CompletedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.CreatedRoles != nil {
+		in, out := &in.CreatedRoles, &out.CreatedRoles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.UnmappedRoles != nil {
+		in, out := &in.UnmappedRoles, &out.UnmappedRoles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.FrozenGrants != nil {
+		in, out := &in.FrozenGrants, &out.FrozenGrants
+		*out = make([]FrozenGrantStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Source != nil {
+		in, out := &in.Source, &out.Source
+		*out = new(MigrationSourceStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SourceAccessRestoredAt != nil {
+		in, out := &in.SourceAccessRestoredAt, &out.SourceAccessRestoredAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationReportStatus.
+func (in *MigrationReportStatus) DeepCopy() *MigrationReportStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationReportStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationSourceStatus) DeepCopyInto(out *MigrationSourceStatus) {
+	*out = *in
+	if in.ConnectionInfo != nil {
+		in, out := &in.ConnectionInfo, &out.ConnectionInfo
+		*out = new(DatabaseClaimConnectionInfo)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationSourceStatus.
+func (in *MigrationSourceStatus) DeepCopy() *MigrationSourceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationSourceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationStatus) DeepCopyInto(out *MigrationStatus) {
+	*out = *in
+	if in.LagBytes != nil {
+		in, out := &in.LagBytes, &out.LagBytes
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Tables != nil {
+		in, out := &in.Tables, &out.Tables
+		*out = make([]MigrationTableStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.UpdatedAt != nil {
+		in, out := &in.UpdatedAt, &out.UpdatedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.PreFlight != nil {
+		in, out := &in.PreFlight, &out.PreFlight
+		*out = make([]PreFlightCheckStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.FrozenGrants != nil {
+		in, out := &in.FrozenGrants, &out.FrozenGrants
+		*out = make([]FrozenGrantStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Source != nil {
+		in, out := &in.Source, &out.Source
+		*out = new(MigrationSourceStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CreatedRoles != nil {
+		in, out := &in.CreatedRoles, &out.CreatedRoles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.UnmappedRoles != nil {
+		in, out := &in.UnmappedRoles, &out.UnmappedRoles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationStatus.
+func (in *MigrationStatus) DeepCopy() *MigrationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationTableStatus) DeepCopyInto(out *MigrationTableStatus) {
+	*out = *in
+	if in.SourceRows != nil {
+		in, out := &in.SourceRows, &out.SourceRows
+		*out = new(int64)
+		**out = **in
+	}
+	if in.TargetRows != nil {
+		in, out := &in.TargetRows, &out.TargetRows
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationTableStatus.
+func (in *MigrationTableStatus) DeepCopy() *MigrationTableStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationTableStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ParameterGroupStatus) DeepCopyInto(out *ParameterGroupStatus) {
+	*out = *in
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.UpdatedAt != nil {
+		in, out := &in.UpdatedAt, &out.UpdatedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ParameterGroupStatus.
+func (in *ParameterGroupStatus) DeepCopy() *ParameterGroupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ParameterGroupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlanStatus) DeepCopyInto(out *PlanStatus) {
+	*out = *in
+	if in.Statements != nil {
+		in, out := &in.Statements, &out.Statements
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PlannedAt != nil {
+		in, out := &in.PlannedAt, &out.PlannedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlanStatus.
+func (in *PlanStatus) DeepCopy() *PlanStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PlanStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreFlightCheckStatus) DeepCopyInto(out *PreFlightCheckStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreFlightCheckStatus.
+func (in *PreFlightCheckStatus) DeepCopy() *PreFlightCheckStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PreFlightCheckStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *S3BackupConfiguration) DeepCopyInto(out *S3BackupConfiguration) {
 	*out = *in
@@ -323,6 +785,45 @@ func (in *S3BackupConfiguration) DeepCopy() *S3BackupConfiguration {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Schema) DeepCopyInto(out *Schema) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Schema.
+func (in *Schema) DeepCopy() *Schema {
+	if in == nil {
+		return nil
+	}
+	out := new(Schema)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SchemaStatus) DeepCopyInto(out *SchemaStatus) {
+	*out = *in
+	if in.RepairedDrift != nil {
+		in, out := &in.RepairedDrift, &out.RepairedDrift
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RepairedAt != nil {
+		in, out := &in.RepairedAt, &out.RepairedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SchemaStatus.
+func (in *SchemaStatus) DeepCopy() *SchemaStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SchemaStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecretRef) DeepCopyInto(out *SecretRef) {
 	*out = *in
@@ -338,6 +839,44 @@ func (in *SecretRef) DeepCopy() *SecretRef {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SettingsStatus) DeepCopyInto(out *SettingsStatus) {
+	*out = *in
+	if in.Database != nil {
+		in, out := &in.Database, &out.Database
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Role != nil {
+		in, out := &in.Role, &out.Role
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.CorrectedDrift != nil {
+		in, out := &in.CorrectedDrift, &out.CorrectedDrift
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CorrectedAt != nil {
+		in, out := &in.CorrectedAt, &out.CorrectedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SettingsStatus.
+func (in *SettingsStatus) DeepCopy() *SettingsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SettingsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SourceDataFrom) DeepCopyInto(out *SourceDataFrom) {
 	*out = *in
@@ -398,6 +937,44 @@ func (in *Status) DeepCopyInto(out *Status) {
 		in, out := &in.UserUpdatedAt, &out.UserUpdatedAt
 		*out = (*in).DeepCopy()
 	}
+	if in.Logins != nil {
+		in, out := &in.Logins, &out.Logins
+		*out = make([]LoginStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Extensions != nil {
+		in, out := &in.Extensions, &out.Extensions
+		*out = make([]ExtensionStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Schemas != nil {
+		in, out := &in.Schemas, &out.Schemas
+		*out = make([]SchemaStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Databases != nil {
+		in, out := &in.Databases, &out.Databases
+		*out = make([]DatabaseStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]UserStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Settings != nil {
+		in, out := &in.Settings, &out.Settings
+		*out = new(SettingsStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Status.
@@ -424,3 +1001,34 @@ func (in *Tag) DeepCopy() *Tag {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserStatus) DeepCopyInto(out *UserStatus) {
+	*out = *in
+	if in.ConnectionInfo != nil {
+		in, out := &in.ConnectionInfo, &out.ConnectionInfo
+		*out = new(DatabaseClaimConnectionInfo)
+		**out = **in
+	}
+	if in.UserUpdatedAt != nil {
+		in, out := &in.UserUpdatedAt, &out.UserUpdatedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Logins != nil {
+		in, out := &in.Logins, &out.Logins
+		*out = make([]LoginStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserStatus.
+func (in *UserStatus) DeepCopy() *UserStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(UserStatus)
+	in.DeepCopyInto(out)
+	return out
+}
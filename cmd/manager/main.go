@@ -21,6 +21,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -38,8 +39,11 @@ import (
 
 	persistancev1 "github.com/infobloxopen/db-controller/api/v1"
 	"github.com/infobloxopen/db-controller/controllers"
+	"github.com/infobloxopen/db-controller/pkg/audit"
 	"github.com/infobloxopen/db-controller/pkg/config"
+	"github.com/infobloxopen/db-controller/pkg/dbclient"
 	"github.com/infobloxopen/db-controller/pkg/rdsauth"
+	"github.com/infobloxopen/db-controller/pkg/redact"
 	dbwebhook "github.com/infobloxopen/db-controller/webhook"
 
 	//+kubebuilder:scaffold:imports
@@ -132,15 +136,41 @@ func main() {
 		os.Exit(1)
 	}
 
+	dbClients := dbclient.NewCache(ctrl.Log.WithName("dbclient"), dbclient.PoolConfig{
+		MaxOpenConns:    ctlConfig.GetInt("dbClientPool::maxOpenConns"),
+		MaxIdleConns:    ctlConfig.GetInt("dbClientPool::maxIdleConns"),
+		ConnMaxIdleTime: time.Duration(ctlConfig.GetInt("dbClientPool::connMaxIdleTime")) * time.Second,
+	}, time.Duration(ctlConfig.GetInt("dbClientPool::healthCheckInterval"))*time.Second,
+		time.Duration(ctlConfig.GetInt("dbClientPool::idleTimeout"))*time.Second)
+	defer dbClients.Close()
+
+	var auditSink audit.Sink
+	if ctlConfig.GetBool("auditLog::enabled") {
+		var sink *audit.JSONSink
+		if path := ctlConfig.GetString("auditLog::file"); path != "" {
+			if sink, err = audit.NewFileSink(path); err != nil {
+				setupLog.Error(err, "unable to open audit log")
+				os.Exit(1)
+			}
+		} else {
+			sink = audit.NewJSONSink(os.Stdout)
+		}
+		defer sink.Close()
+		auditSink = sink
+	}
+
 	if err = (&controllers.DatabaseClaimReconciler{
+		Audit:                 auditSink,
 		Class:                 class,
 		Client:                mgr.GetClient(),
 		Config:                ctlConfig,
+		DBClients:             dbClients,
 		DbIdentifierPrefix:    dbIdentifierPrefix,
-		Log:                   ctrl.Log.WithName("controllers").WithName("DatabaseClaim"),
+		Log:                   redact.Logger(ctrl.Log.WithName("controllers").WithName("DatabaseClaim")),
 		MasterAuth:            rdsauth.NewMasterAuth(),
 		MetricsDepYamlPath:    metricsDepYamlPath,
 		MetricsConfigYamlPath: metricsConfigYamlPath,
+		Recorder:              mgr.GetEventRecorderFor("databaseClaim-controller"),
 		Scheme:                mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "DatabaseClaim")
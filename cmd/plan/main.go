@@ -0,0 +1,88 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// plan prints the statements db-controller would run against a database host to
+// reconcile a DatabaseClaim, without running them.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"go.uber.org/zap/zapcore"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/yaml"
+
+	persistancev1 "github.com/infobloxopen/db-controller/api/v1"
+	"github.com/infobloxopen/db-controller/controllers"
+	"github.com/infobloxopen/db-controller/pkg/config"
+	"github.com/infobloxopen/db-controller/pkg/redact"
+)
+
+const dsnEnvVar = "DB_PLAN_DSN"
+
+func main() {
+	var configFile string
+	var claimFile string
+	var dsn string
+	var class string
+
+	flag.StringVar(&configFile, "config-file", "/etc/config/config.yaml", "The db-controller configuration.")
+	flag.StringVar(&claimFile, "claim", "", "The DatabaseClaim yaml to plan.")
+	flag.StringVar(&dsn, "dsn", os.Getenv(dsnEnvVar),
+		"Connection string of the database host with the master credentials, defaults to $"+dsnEnvVar+". "+
+			"For claims using an existing database, the connection string of that database.")
+	flag.StringVar(&class, "class", "default", "The class of claims the db-controller instance addresses.")
+	opts := zap.Options{
+		Development: false,
+		TimeEncoder: zapcore.RFC3339NanoTimeEncoder,
+	}
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	logger := zap.New(zap.UseFlagOptions(&opts))
+	if claimFile == "" || dsn == "" {
+		fmt.Fprintln(os.Stderr, "-claim and -dsn are required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	data, err := os.ReadFile(claimFile)
+	if err != nil {
+		logger.Error(err, "could not read claim", "file", claimFile)
+		os.Exit(1)
+	}
+	var dbClaim persistancev1.DatabaseClaim
+	if err := yaml.UnmarshalStrict(data, &dbClaim); err != nil {
+		logger.Error(err, "could not parse claim", "file", claimFile)
+		os.Exit(1)
+	}
+
+	r := &controllers.DatabaseClaimReconciler{
+		Class:  class,
+		Config: config.NewConfig(logger, configFile),
+		Log:    redact.Logger(logger.WithName("plan")),
+	}
+	statements, err := r.PlanClaim(&dbClaim, dsn)
+	if err != nil {
+		logger.Error(err, "could not plan claim", "claim", dbClaim.Name)
+		os.Exit(1)
+	}
+	for _, stmt := range statements {
+		fmt.Println(stmt)
+	}
+}
@@ -17,9 +17,13 @@ limitations under the License.
 package controllers
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"net/url"
 	"os"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -33,6 +37,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -42,6 +47,7 @@ import (
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 
 	persistancev1 "github.com/infobloxopen/db-controller/api/v1"
+	"github.com/infobloxopen/db-controller/pkg/audit"
 	"github.com/infobloxopen/db-controller/pkg/dbclient"
 	"github.com/infobloxopen/db-controller/pkg/dbuser"
 	"github.com/infobloxopen/db-controller/pkg/hostparams"
@@ -49,6 +55,7 @@ import (
 	"github.com/infobloxopen/db-controller/pkg/pgctl"
 	exporter "github.com/infobloxopen/db-controller/pkg/postgres-exporter"
 	"github.com/infobloxopen/db-controller/pkg/rdsauth"
+	"github.com/infobloxopen/db-controller/pkg/redact"
 )
 
 type Error string
@@ -70,12 +77,35 @@ const (
 	defaultRestoreFromSource = "Snapshot"
 	defaultBackupPolicyKey   = "backup"
 	tempTargetPassword       = "targetPassword"
+	planAnnotation           = "persistance.atlas.infoblox.com/plan"
 	tempSourceDsn            = "sourceDsn"
 	masterSecretSuffix       = "-master"
 	masterPasswordKey        = "password"
+	applyMethodImmediate     = "immediate"
+	applyMethodPendingReboot = "pending-reboot"
+	parameterApplyInSync     = "in-sync"
 	ErrMaxNameLen            = Error("dbclaim name is too long. max length is 44 characters")
 )
 
+const (
+	// the master secret keeps the replaced password while a rotation is verified
+	masterPreviousPasswordKey       = "previousPassword"
+	masterRotatedAtAnnotation       = "persistance.atlas.infoblox.com/master-password-rotated-at"
+	masterRotationStartedAnnotation = "persistance.atlas.infoblox.com/master-password-rotation-started-at"
+	defaultMasterVerifyTimeout      = 30
+	masterVerifyPingTimeout         = 10 * time.Second
+)
+
+// maxMigrationTables bounds the tables listed in the migration status
+const maxMigrationTables = 20
+
+// retryMigrationAnnotation resumes a failed migration from the state that failed
+const retryMigrationAnnotation = "persistance.atlas.infoblox.com/retry-migration"
+
+// restoreSourceAccessAnnotation grants back the write privileges frozen on the source
+// of the migration in progress or of the last completed one
+const restoreSourceAccessAnnotation = "persistance.atlas.infoblox.com/restore-source-access"
+
 type ModeEnum int
 
 type input struct {
@@ -91,6 +121,20 @@ type input struct {
 	EnableSuperUser            bool
 	EnablePerfInsight          bool
 	EnableCloudwatchLogsExport []*string
+	ParameterRebootRequired    bool
+}
+
+// MarshalLog logs the input without the master password and the temporary secret
+func (i input) MarshalLog() interface{} {
+	type plainInput input
+	masked := plainInput(i)
+	if masked.TempSecret != "" {
+		masked.TempSecret = redact.Mask
+	}
+	if masked.MasterConnInfo.Password != "" {
+		masked.MasterConnInfo.Password = redact.Mask
+	}
+	return masked
 }
 
 const (
@@ -116,6 +160,12 @@ type DatabaseClaimReconciler struct {
 	Class                 string
 	MetricsDepYamlPath    string
 	MetricsConfigYamlPath string
+	Recorder              record.EventRecorder
+	// DBClients shares database clients between reconciles, a new client is opened
+	// for every reconcile when it is nil
+	DBClients *dbclient.Cache
+	// Audit receives a record of every statement changing a database host, none when nil
+	Audit audit.Sink
 }
 
 func (r *DatabaseClaimReconciler) isClassPermitted(claimClass string) bool {
@@ -256,6 +306,24 @@ func (r *DatabaseClaimReconciler) setReqInfo(dbClaim *persistancev1.DatabaseClai
 	if err != nil {
 		return err
 	}
+	if err := r.validateParameters(dbClaim.Spec.Parameters); err != nil {
+		return err
+	}
+	if err := r.validateExtensions(dbClaim.Spec.Extensions); err != nil {
+		return err
+	}
+	if err := validateSchemas(dbClaim.Spec.Schemas); err != nil {
+		return err
+	}
+	if err := validateDatabases(dbClaim); err != nil {
+		return err
+	}
+	if err := validateAdditionalUsers(dbClaim); err != nil {
+		return err
+	}
+	if err := validateSettings(dbClaim); err != nil {
+		return err
+	}
 	r.Input = &input{ManageCloudDB: manageCloudDB, SharedDBHost: sharedDBHost,
 		MasterConnInfo: connInfo, FragmentKey: fragmentKey,
 		DbType: string(dbClaim.Spec.Type), HostParams: *hostParams,
@@ -280,7 +348,7 @@ func (r *DatabaseClaimReconciler) setReqInfo(dbClaim *persistancev1.DatabaseClai
 		r.Input.EnableReplicationRole = *dbClaim.Spec.EnableReplicationRole
 	}
 
-	logr.Info("setup values of ", "DatabaseClaimReconciler", r)
+	logr.Info("setup values of ", "input", r.Input)
 	return nil
 }
 
@@ -317,7 +385,12 @@ func (r *DatabaseClaimReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		// The object is being deleted
 		if controllerutil.ContainsFinalizer(&dbClaim, dbFinalizerName) {
 			// check if the claim is in the middle of rds migration, if so, wait for it to complete
-			if dbClaim.Status.MigrationState != "" && dbClaim.Status.MigrationState != pgctl.S_Completed.String() {
+			// unless it failed, then it is aborted before the resources are deleted
+			if dbClaim.Status.MigrationState == pgctl.S_Failed.String() {
+				if err := r.abortFailedMigration(ctx, &dbClaim); err != nil {
+					return r.manageError(ctx, &dbClaim, err)
+				}
+			} else if dbClaim.Status.MigrationState != "" && dbClaim.Status.MigrationState != pgctl.S_Completed.String() {
 				logr.Info("migration is in progress. object cannot be deleted")
 				dbClaim.Status.Error = "dbc cannot be deleted while migration is in progress"
 				err := r.Client.Status().Update(ctx, &dbClaim)
@@ -344,6 +417,16 @@ func (r *DatabaseClaimReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return ctrl.Result{}, nil
 	}
 
+	// while the claim is annotated for planning, the statements are recorded instead of run
+	if dbClaim.Annotations[planAnnotation] == "true" {
+		return r.reconcilePlan(ctx, &dbClaim)
+	}
+	dbClaim.Status.Plan = nil
+
+	if dbClaim.Annotations[restoreSourceAccessAnnotation] == "true" {
+		return r.reconcileSourceAccessRestore(ctx, &dbClaim)
+	}
+
 	// The object is not being deleted, so if it does not have our finalizer,
 	// then lets add the finalizer and update the object. This is equivalent
 	// registering our finalizer.
@@ -472,10 +555,32 @@ func (r *DatabaseClaimReconciler) reconcileUseExistingDB(ctx context.Context, db
 	dbName := existingDBConnInfo.DatabaseName
 	updateDBStatus(&dbClaim.Status.ActiveDB, dbName)
 
-	err = r.manageUser(dbClient, &dbClaim.Status.ActiveDB, dbName, dbClaim.Spec.Username)
+	err = r.manageExtensions(dbClient, &dbClaim.Status.ActiveDB, dbName, dbClaim)
+	if err != nil {
+		return err
+	}
+
+	err = r.manageUser(dbClient, dbClaim, &dbClaim.Status.ActiveDB, dbName, dbClaim.Spec.Username)
+	if err != nil {
+		return err
+	}
+	err = r.manageSettings(dbClient, &dbClaim.Status.ActiveDB, dbName, dbClaim)
+	if err != nil {
+		return err
+	}
+	err = r.manageSchemas(dbClient, &dbClaim.Status.ActiveDB, dbName, dbClaim)
 	if err != nil {
 		return err
 	}
+	// additional databases and users are created on the new host once the claim is migrated
+	if r.Mode == M_UseExistingDB {
+		if err := r.manageDatabases(ctx, dbClient, &dbClaim.Status.ActiveDB, dbClaim); err != nil {
+			return err
+		}
+		if err := r.manageAdditionalUsers(ctx, dbClient, &dbClaim.Status.ActiveDB, dbName, dbClaim); err != nil {
+			return err
+		}
+	}
 	if err := r.Status().Update(ctx, dbClaim); err != nil {
 		logr.Error(err, "could not update db claim")
 		return err
@@ -517,7 +622,9 @@ func (r *DatabaseClaimReconciler) reconcileNewDB(ctx context.Context,
 		r.Input.MasterConnInfo.Password = connInfo.Password
 		r.Input.MasterConnInfo.Port = connInfo.Port
 		r.Input.MasterConnInfo.Username = connInfo.Username
-
+		if err := r.manageMasterPasswordRotation(ctx, dbClaim); err != nil {
+			return ctrl.Result{}, err
+		}
 	} else {
 		password, err := r.readMasterPassword(ctx, dbClaim)
 		if err != nil {
@@ -543,18 +650,209 @@ func (r *DatabaseClaimReconciler) reconcileNewDB(ctx context.Context,
 		updateClusterStatus(&dbClaim.Status.NewDB, &r.Input.HostParams)
 	}
 
-	if err := r.manageDatabase(dbClient, &dbClaim.Status.NewDB); err != nil {
+	if err := r.manageDatabase(dbClient, &dbClaim.Status.NewDB, r.Input.MasterConnInfo.DatabaseName); err != nil {
 		return ctrl.Result{}, err
 
 	}
 
-	err = r.manageUser(dbClient, &dbClaim.Status.NewDB, GetDBName(dbClaim), dbClaim.Spec.Username)
+	if err := r.manageExtensions(dbClient, &dbClaim.Status.NewDB, GetDBName(dbClaim), dbClaim); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	err = r.manageUser(dbClient, dbClaim, &dbClaim.Status.NewDB, GetDBName(dbClaim), dbClaim.Spec.Username)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
+	if err := r.manageSettings(dbClient, &dbClaim.Status.NewDB, GetDBName(dbClaim), dbClaim); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.manageSchemas(dbClient, &dbClaim.Status.NewDB, GetDBName(dbClaim), dbClaim); err != nil {
+		return ctrl.Result{}, err
+	}
+	// the secret must keep pointing at the source until the migration completes
+	if r.Mode == M_UseNewDB {
+		if err := r.manageDatabases(ctx, dbClient, &dbClaim.Status.NewDB, dbClaim); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.manageAdditionalUsers(ctx, dbClient, &dbClaim.Status.NewDB, GetDBName(dbClaim), dbClaim); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
 	return ctrl.Result{}, nil
 }
 
+// reconcilePlan records in the status and in an event the statements a reconcile of
+// dbClaim would run against its database. The reconcile of the claim is paused, only
+// the password of its user keeps being rotated.
+func (r *DatabaseClaimReconciler) reconcilePlan(ctx context.Context, dbClaim *persistancev1.DatabaseClaim) (ctrl.Result, error) {
+	logr := r.Log.WithValues("databaseclaim", dbClaim.Namespace+"/"+dbClaim.Name, "func", "reconcilePlan")
+
+	if dbClaim.Status.Plan == nil {
+		r.Recorder.Event(dbClaim, corev1.EventTypeWarning, "ReconcilePaused",
+			"the claim is annotated for planning, only the password rotation runs until the annotation is removed")
+	}
+	dsn, err := r.planDsn(ctx, dbClaim.DeepCopy())
+	var statements []string
+	if err == nil {
+		statements, err = r.PlanClaim(dbClaim, dsn)
+	}
+	plannedAt := metav1.Now()
+	dbClaim.Status.Plan = &persistancev1.PlanStatus{Statements: statements, PlannedAt: &plannedAt, ReconcilePaused: true}
+	if dsn != "" {
+		if err := r.rotateWhilePlanned(ctx, dbClaim, dsn); err != nil {
+			logr.Error(err, "could not rotate password of planned claim")
+			dbClaim.Status.Plan.RotationError = err.Error()
+		}
+	}
+	if err != nil {
+		logr.Error(err, "could not plan claim")
+		dbClaim.Status.Plan.Error = err.Error()
+		r.Recorder.Event(dbClaim, corev1.EventTypeWarning, "PlanFailed", err.Error())
+	} else {
+		logr.Info("planned claim", "statements", statements)
+		msg := "no changes planned"
+		if len(statements) > 0 {
+			msg = fmt.Sprintf("%d statements planned:\n%s", len(statements), strings.Join(statements, "\n"))
+		}
+		r.Recorder.Event(dbClaim, corev1.EventTypeNormal, "Planned", msg)
+	}
+	if err := r.Status().Update(ctx, dbClaim); err != nil {
+		logr.Error(err, "could not update db claim")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: r.getPasswordRotationTime()}, nil
+}
+
+// planDsn resolves the dsn of the database host dbClaim is reconciled against. The
+// master connection info of the reconciler is left unchanged.
+func (r *DatabaseClaimReconciler) planDsn(ctx context.Context, dbClaim *persistancev1.DatabaseClaim) (string, error) {
+	if r.getMode(dbClaim.DeepCopy()) == M_UseExistingDB {
+		connInfo, err := persistancev1.ParseUri(dbClaim.Spec.SourceDataFrom.Database.DSN)
+		if err != nil {
+			return "", err
+		}
+		if err := r.getExistingDBConnInfo(ctx, dbClaim, connInfo); err != nil {
+			return "", err
+		}
+		return connInfo.Uri(), nil
+	}
+
+	masterConnInfo := r.Input.MasterConnInfo
+	if r.Input.ManageCloudDB {
+		connInfo, err := r.readResourceSecret(ctx, r.Input.DbHostIdentifier, dbClaim)
+		if err != nil {
+			return "", fmt.Errorf("database host %s is not provisioned yet", r.Input.DbHostIdentifier)
+		}
+		masterConnInfo.Host = connInfo.Host
+		masterConnInfo.Password = connInfo.Password
+		masterConnInfo.Port = connInfo.Port
+		masterConnInfo.Username = connInfo.Username
+	} else {
+		password, err := r.readMasterPassword(ctx, dbClaim)
+		if err != nil {
+			return "", err
+		}
+		masterConnInfo.Password = password
+	}
+	return masterDefaultDsn(masterConnInfo), nil
+}
+
+// rotateWhilePlanned rotates the password of the user of dbClaim on the host of dsn
+// while its reconcile is paused for planning. A renamed user is left to the reconcile.
+func (r *DatabaseClaimReconciler) rotateWhilePlanned(ctx context.Context, dbClaim *persistancev1.DatabaseClaim, dsn string) error {
+	status := &dbClaim.Status.ActiveDB
+	if status.ConnectionInfo == nil || status.ConnectionInfo.Username == "" {
+		return nil
+	}
+	if u, err := url.Parse(dsn); err != nil || u.Hostname() != status.ConnectionInfo.Host {
+		return nil
+	}
+	if r.newDBUser(dbClaim.Spec.Username).IsUserChanged(*status) {
+		return nil
+	}
+	dbClient, err := r.newDBClient(ctx, dsn, dbClaim)
+	if err != nil {
+		return err
+	}
+	defer dbClient.Close()
+
+	r.Input.TempSecret = ""
+	if err := r.rotateUser(dbClient, status, dbClaim.Spec.Username); err != nil {
+		return err
+	}
+	if r.Input.TempSecret == "" {
+		return nil
+	}
+	connInfo := status.ConnectionInfo.DeepCopy()
+	connInfo.Password = r.Input.TempSecret
+	return r.createOrUpdateSecret(ctx, dbClaim, connInfo)
+}
+
+// PlanClaim returns the statements a reconcile of dbClaim would run against the database
+// host of dsn, without running them. Additional databases and users are not planned.
+func (r *DatabaseClaimReconciler) PlanClaim(dbClaim *persistancev1.DatabaseClaim, dsn string) ([]string, error) {
+	// planning must not change the reconciler, the claim or report repairs that did not happen
+	planner := *r
+	planner.Recorder = &record.FakeRecorder{}
+	dbClaim = dbClaim.DeepCopy()
+
+	// claims read from a file miss the defaults of the CRD
+	if dbClaim.Spec.UseExistingSource == nil {
+		dbClaim.Spec.UseExistingSource = new(bool)
+	}
+	if dbClaim.Spec.EnableSuperUser == nil {
+		dbClaim.Spec.EnableSuperUser = new(bool)
+	}
+	if dbClaim.Spec.EnableReplicationRole == nil {
+		dbClaim.Spec.EnableReplicationRole = new(bool)
+	}
+	if dbClaim.Status.ActiveDB.ConnectionInfo == nil {
+		dbClaim.Status.ActiveDB.ConnectionInfo = new(persistancev1.DatabaseClaimConnectionInfo)
+	}
+	if err := planner.setReqInfo(dbClaim); err != nil {
+		return nil, err
+	}
+	planner.Mode = planner.getMode(dbClaim)
+	if planner.Mode == M_NotSupported {
+		return nil, fmt.Errorf("unsupported combination of useExistingSource and sourceDataFrom")
+	}
+
+	dbClient, err := dbclient.New(dbclient.Config{Log: r.Log, DBType: "postgres", DSN: dsn, Plan: true})
+	if err != nil {
+		return nil, err
+	}
+	defer dbClient.Close()
+
+	status := &persistancev1.Status{ConnectionInfo: new(persistancev1.DatabaseClaimConnectionInfo)}
+	if u, err := url.Parse(dsn); err == nil && u.Hostname() == dbClaim.Status.ActiveDB.ConnectionInfo.Host {
+		status = dbClaim.Status.ActiveDB.DeepCopy()
+	}
+	dbName := GetDBName(dbClaim)
+	if planner.Mode == M_UseExistingDB {
+		connInfo, err := persistancev1.ParseUri(dbClaim.Spec.SourceDataFrom.Database.DSN)
+		if err != nil {
+			return nil, err
+		}
+		dbName = connInfo.DatabaseName
+	} else if err := planner.manageDatabase(dbClient, status, dbName); err != nil {
+		return nil, err
+	}
+
+	if err := planner.manageExtensions(dbClient, status, dbName, dbClaim); err != nil {
+		return nil, err
+	}
+	if err := planner.manageUser(dbClient, dbClaim, status, dbName, dbClaim.Spec.Username); err != nil {
+		return nil, err
+	}
+	if err := planner.manageSettings(dbClient, status, dbName, dbClaim); err != nil {
+		return nil, err
+	}
+	if err := planner.manageSchemas(dbClient, status, dbName, dbClaim); err != nil {
+		return nil, err
+	}
+	return dbClient.(dbclient.Planner).Statements(), nil
+}
+
 func (r *DatabaseClaimReconciler) reconcileMigrateToNewDB(ctx context.Context,
 	dbClaim *persistancev1.DatabaseClaim) (ctrl.Result, error) {
 
@@ -578,6 +876,15 @@ func (r *DatabaseClaimReconciler) reconcileMigrationInProgress(ctx context.Conte
 	dbClaim *persistancev1.DatabaseClaim) (ctrl.Result, error) {
 	logr := r.Log.WithValues("databaseclaim", dbClaim.Namespace+"/"+dbClaim.Name, "func", "reconcileMigrationInProgress")
 
+	if dbClaim.Status.MigrationState == pgctl.S_Failed.String() {
+		if dbClaim.Annotations[retryMigrationAnnotation] != "true" {
+			return r.manageMigrationFailed(ctx, dbClaim)
+		}
+		if err := r.resumeMigration(ctx, dbClaim); err != nil {
+			return r.manageError(ctx, dbClaim, err)
+		}
+	}
+
 	migrationState := dbClaim.Status.MigrationState
 
 	logr.Info("Migration is progress", "state", migrationState)
@@ -604,7 +911,10 @@ func (r *DatabaseClaimReconciler) reconcileMigrationInProgress(ctx context.Conte
 	if err != nil {
 		return r.manageError(ctx, dbClaim, err)
 	}
-	var sourceMasterConn *persistancev1.DatabaseClaimConnectionInfo
+	var (
+		sourceMasterConn *persistancev1.DatabaseClaimConnectionInfo
+		source           *persistancev1.MigrationSourceStatus
+	)
 
 	if r.Mode == M_MigrationInProgress ||
 		r.Mode == M_MigrateExistingToNewDB {
@@ -616,6 +926,13 @@ func (r *DatabaseClaimReconciler) reconcileMigrationInProgress(ctx context.Conte
 		if err != nil {
 			return r.manageError(ctx, dbClaim, err)
 		}
+		source = &persistancev1.MigrationSourceStatus{
+			SecretNamespace: dbClaim.Spec.SourceDataFrom.Database.SecretRef.Namespace,
+			SecretName:      dbClaim.Spec.SourceDataFrom.Database.SecretRef.Name,
+		}
+		if source.SecretNamespace == "" {
+			source.SecretNamespace = "default"
+		}
 	} else if r.Mode == M_UpgradeDBInProgress ||
 		r.Mode == M_InitiateDBUpgrade {
 		activeHost, _, _ := strings.Cut(dbClaim.Status.ActiveDB.ConnectionInfo.Host, ".")
@@ -632,13 +949,28 @@ func (r *DatabaseClaimReconciler) reconcileMigrationInProgress(ctx context.Conte
 		}
 		sourceMasterConn.Username = activeConnInfo.Username
 		sourceMasterConn.Password = activeConnInfo.Password
+		serviceNS, _ := getServiceNamespace()
+		source = &persistancev1.MigrationSourceStatus{SecretNamespace: serviceNS, SecretName: activeHost}
 
 	} else {
 		err := fmt.Errorf("unsupported mode %v", r.Mode)
 		return r.manageError(ctx, dbClaim, err)
 	}
-	logr.Info("DSN", "sourceAppDsn", sourceAppDsn)
-	logr.Info("DSN", "sourceMasterConn", sourceMasterConn)
+	logr.Info("DSN", "sourceAppDsn", redact.String(sourceAppDsn))
+	logr.Info("DSN", "sourceMasterConn", sourceMasterConn.MarshalLog())
+
+	if setMigrationStatus(dbClaim) {
+		if err := r.Status().Update(ctx, dbClaim); err != nil {
+			logr.Error(err, "could not update db claim status")
+			return r.manageError(ctx, dbClaim, err)
+		}
+	}
+	// the source is kept to grant back the privileges frozen on it, without the password
+	if dbClaim.Status.Migration != nil {
+		source.ConnectionInfo = sourceMasterConn.DeepCopy()
+		source.ConnectionInfo.Password = ""
+		dbClaim.Status.Migration.Source = source
+	}
 
 	config := pgctl.Config{
 		Log:              r.Log,
@@ -646,10 +978,29 @@ func (r *DatabaseClaimReconciler) reconcileMigrationInProgress(ctx context.Conte
 		SourceDBUserDsn:  sourceAppDsn,
 		TargetDBUserDsn:  targetAppConn.Uri(),
 		TargetDBAdminDsn: targetMasterDsn,
-		ExportFilePath:   r.Config.GetString("pgTemp"),
+		Audit:            r.Audit,
+		Claim:            dbClaim.Namespace + "/" + dbClaim.Name,
+		// a failed check blocks the migration unless it is configured to warn
+		PreFlightWarnOnly: r.Config.GetString("migrationPreFlight") == "warn",
+		Verification: pgctl.Verification{
+			Checksums:     r.Config.GetBool("migrationVerification::checksums"),
+			ChunkSize:     r.Config.GetInt("migrationVerification::chunkSize"),
+			SampleRows:    r.Config.GetInt64("migrationVerification::sampleRows"),
+			SamplePercent: r.Config.GetInt("migrationVerification::samplePercent"),
+		},
+		RoleMap:        r.Config.GetStringMapString("migrationRoleMap"),
+		SequenceMargin: r.getMigrationSequenceMargin(),
+		Timeouts:       r.getMigrationTimeouts(),
+	}
+	if dbClaim.Status.Migration != nil {
+		config.Replication = pgctl.ReplicationNames{
+			Publication:  dbClaim.Status.Migration.Publication,
+			Subscription: dbClaim.Status.Migration.Subscription,
+			Slot:         dbClaim.Status.Migration.Slot,
+		}
 	}
 
-	logr.Info("DSN", "config", config)
+	logr.Info("DSN", "config", config.MarshalLog())
 
 	s, err := pgctl.GetReplicatorState(migrationState, config)
 	if err != nil {
@@ -658,9 +1009,15 @@ func (r *DatabaseClaimReconciler) reconcileMigrationInProgress(ctx context.Conte
 
 loop:
 	for {
-		next, err := s.Execute()
+		next, err := s.Execute(ctx)
+		// the report of failed checks is kept in the status with the failed attempt
+		progressed := r.recordMigrationReports(dbClaim, s)
 		if err != nil {
-			return r.manageError(ctx, dbClaim, err)
+			return r.manageMigrationAttempt(ctx, dbClaim, s, err)
+		}
+		if next.Id() != pgctl.S_Retry && dbClaim.Status.Migration != nil {
+			dbClaim.Status.Migration.Attempts = 0
+			dbClaim.Status.Migration.LastError = ""
 		}
 		switch next.Id() {
 		case pgctl.S_Completed:
@@ -669,7 +1026,11 @@ loop:
 
 		case pgctl.S_Retry:
 			logr.Info("retry called")
-			return ctrl.Result{RequeueAfter: 60 * time.Second, Requeue: true}, nil
+			// waiting on a migration that progresses is not a failed attempt
+			if progressed {
+				return r.manageMigrationWait(ctx, dbClaim, s)
+			}
+			return r.manageMigrationAttempt(ctx, dbClaim, s, fmt.Errorf("%s has to be retried, the migration made no progress", s))
 
 		case pgctl.S_WaitToDisableSource:
 			logr.Info("wait called")
@@ -702,6 +1063,9 @@ loop:
 		}
 	}
 	dbClaim.Status.MigrationState = pgctl.S_Completed.String()
+	dbClaim.Status.LastMigration = completedMigrationReport(dbClaim.Status.Migration)
+	dbClaim.Status.Migration = nil
+	deleteMigrationMetrics(dbClaim)
 
 	//done with migration- switch active server to newDB
 	dbClaim.Status.ActiveDB = *dbClaim.Status.NewDB.DeepCopy()
@@ -722,94 +1086,512 @@ loop:
 	return r.manageSuccess(ctx, dbClaim)
 }
 
-func (r *DatabaseClaimReconciler) getClientForExistingDB(ctx context.Context, logr logr.Logger,
-	dbClaim *persistancev1.DatabaseClaim, connInfo *persistancev1.DatabaseClaimConnectionInfo) (dbclient.Client, error) {
-
-	secretKey := "password"
-	gs := &corev1.Secret{}
-
-	if connInfo.Port == "" {
-		return nil, fmt.Errorf("cannot get master port")
+// setMigrationStatus names the replication objects of a migration that has not created
+// them yet after the claim, it returns whether the status changed. Migrations started
+// without a migration status keep the default names.
+func setMigrationStatus(dbClaim *persistancev1.DatabaseClaim) bool {
+	if dbClaim.Status.Migration != nil {
+		return false
 	}
-
-	if connInfo.Username == "" {
-		return nil, fmt.Errorf("invalid credentials (username)")
+	if state, err := pgctl.GetStateEnum(dbClaim.Status.MigrationState); err != nil || state > pgctl.S_PreFlightCheck {
+		return false
 	}
-
-	if connInfo.SSLMode == "" {
-		return nil, fmt.Errorf("invalid sslMode")
+	names := pgctl.NewReplicationNames(dbClaim.Namespace + "/" + dbClaim.Name)
+	dbClaim.Status.Migration = &persistancev1.MigrationStatus{
+		Publication:  names.Publication,
+		Subscription: names.Subscription,
+		Slot:         names.Slot,
 	}
+	return true
+}
 
-	r.Log.Info("using credentials from secret")
-
-	ns := dbClaim.Spec.SourceDataFrom.Database.SecretRef.Namespace
-	if ns == "" {
-		ns = "default"
+// recordMigrationReports copies the progress and the reports of the last execution of
+// migration state s into the migration status of the claim, it returns whether the
+// migration progressed
+func (r *DatabaseClaimReconciler) recordMigrationReports(dbClaim *persistancev1.DatabaseClaim, s pgctl.State) bool {
+	if dbClaim.Status.Migration == nil {
+		return false
 	}
-	err := r.Client.Get(ctx, client.ObjectKey{
-		Namespace: ns,
-		Name:      dbClaim.Spec.SourceDataFrom.Database.SecretRef.Name,
-	}, gs)
-	if err != nil {
-		return nil, err
+	var progressed bool
+	if reporter, ok := s.(pgctl.ProgressReporter); ok && reporter.Progress() != nil {
+		progressed = recordMigrationProgress(dbClaim, s.Id(), reporter.Progress())
 	}
-	connInfo.Password = string(gs.Data[secretKey])
-
-	if connInfo.Password == "" {
-		return nil, fmt.Errorf("invalid credentials (password)")
+	if reporter, ok := s.(pgctl.PreFlightReporter); ok && reporter.PreFlightReport() != nil {
+		r.recordPreFlightReport(dbClaim, reporter.PreFlightReport())
 	}
-	updateHostPortStatus(&dbClaim.Status.ActiveDB, connInfo.Host, connInfo.Port, connInfo.SSLMode)
-
-	return dbclient.New(dbclient.Config{Log: r.Log, DBType: "postgres", DSN: connInfo.Uri()})
+	if reporter, ok := s.(pgctl.PrivilegeReporter); ok && reporter.PrivilegeReport() != nil {
+		r.recordPrivilegeReport(dbClaim, reporter.PrivilegeReport())
+	}
+	if reporter, ok := s.(pgctl.SourceAccessReporter); ok && reporter.SourceAccessReport() != nil {
+		r.recordSourceAccessReport(dbClaim, reporter.SourceAccessReport())
+	}
+	return progressed
 }
 
-func (r *DatabaseClaimReconciler) getClientConn(dbClaim *persistancev1.DatabaseClaim) persistancev1.DatabaseClaimConnectionInfo {
-	connInfo := persistancev1.DatabaseClaimConnectionInfo{}
-
-	connInfo.Host = r.getMasterHost(dbClaim)
-	connInfo.Port = r.getMasterPort(dbClaim)
-	connInfo.Username = r.getMasterUser(dbClaim)
-	connInfo.SSLMode = r.getSSLMode(dbClaim)
-	connInfo.DatabaseName = GetDBName(dbClaim)
-	return connInfo
+// recordMigrationProgress copies the progress observed by state id into the migration
+// status and the migration metrics of the claim. It returns whether more tables were
+// synchronized or validated, or the replication lag shrank, since the last observation.
+func recordMigrationProgress(dbClaim *persistancev1.DatabaseClaim, id pgctl.StateEnum, progress *pgctl.Progress) bool {
+	status := dbClaim.Status.Migration
+	claim := dbClaim.Namespace + "/" + dbClaim.Name
+	status.Tables = nil
+	var progressed bool
+	switch id {
+	case pgctl.S_CutOverReadinessCheck:
+		progressed = progress.TablesTotal > status.TablesTotal || progress.TablesSynced > status.TablesSynced ||
+			(progress.LagBytes != nil && status.LagBytes != nil && *progress.LagBytes < *status.LagBytes)
+		status.TablesSynced = progress.TablesSynced
+		status.TablesTotal = progress.TablesTotal
+		status.LagBytes = progress.LagBytes
+		for _, t := range progress.Tables {
+			if !t.Synced() && len(status.Tables) < maxMigrationTables {
+				status.Tables = append(status.Tables, persistancev1.MigrationTableStatus{Name: t.Name, State: t.State})
+			}
+		}
+		metrics.MigrationTablesTotal.WithLabelValues(claim).Set(float64(progress.TablesTotal))
+		metrics.MigrationTablesSynced.WithLabelValues(claim).Set(float64(progress.TablesSynced))
+		if progress.LagBytes != nil {
+			metrics.MigrationReplicationLag.WithLabelValues(claim).Set(float64(*progress.LagBytes))
+		}
+	case pgctl.S_ValidateMigrationStatus:
+		progressed = progress.TablesValidated > status.TablesValidated || progress.RowsValidated > status.RowsValidated
+		status.TablesValidated = progress.TablesValidated
+		status.RowsValidated = progress.RowsValidated
+		for _, t := range progress.Tables {
+			if (t.TargetRows < t.SourceRows || t.ChunksMismatched > 0) && len(status.Tables) < maxMigrationTables {
+				sourceRows, targetRows := t.SourceRows, t.TargetRows
+				status.Tables = append(status.Tables, persistancev1.MigrationTableStatus{
+					Name:             t.Name,
+					SourceRows:       &sourceRows,
+					TargetRows:       &targetRows,
+					ChunksMismatched: t.ChunksMismatched,
+					ChunksVerified:   t.ChunksVerified,
+					Mismatch:         t.Mismatch,
+				})
+			}
+		}
+		metrics.MigrationRowsValidated.WithLabelValues(claim).Set(float64(progress.RowsValidated))
+	default:
+		return false
+	}
+	now := metav1.Now()
+	status.UpdatedAt = &now
+	return progressed
 }
 
-func (r *DatabaseClaimReconciler) getDBClient(dbClaim *persistancev1.DatabaseClaim) (dbclient.Client, error) {
-	logr := r.Log.WithValues("databaseclaim", dbClaim.Namespace+"/"+dbClaim.Name, "func", "getDBClient")
+// manageMigrationWait requeues the claim after the poll interval of the migration retry
+// policy when state s waits on a migration that progressed, the attempts of the state
+// start over
+func (r *DatabaseClaimReconciler) manageMigrationWait(ctx context.Context, dbClaim *persistancev1.DatabaseClaim,
+	s pgctl.State) (ctrl.Result, error) {
+	logr := r.Log.WithValues("databaseclaim", dbClaim.Namespace+"/"+dbClaim.Name, "func", "manageMigrationWait")
 
-	logr.Info("getting dbclient", "dsn", r.getMasterDefaultDsn())
-	updateHostPortStatus(&dbClaim.Status.NewDB, r.Input.MasterConnInfo.Host, r.Input.MasterConnInfo.Port, r.Input.MasterConnInfo.SSLMode)
-	return dbclient.New(dbclient.Config{Log: r.Log, DBType: "postgres", DSN: r.getMasterDefaultDsn()})
+	status := dbClaim.Status.Migration
+	status.Attempts = 0
+	status.LastError = ""
+	dbClaim.Status.Error = ""
+
+	interval := r.getMigrationRetryPolicy().PollInterval
+	logr.Info("migration progressed, waiting", "state", s.String(), "retryAfter", interval)
+	if err := r.Status().Update(ctx, dbClaim); err != nil {
+		logr.Error(err, "could not update db claim status")
+		return r.manageError(ctx, dbClaim, err)
+	}
+	return ctrl.Result{RequeueAfter: interval, Requeue: true}, nil
 }
 
-func (r *DatabaseClaimReconciler) getMasterDefaultDsn() string {
+// manageMigrationAttempt counts the failed or retried attempt of migration state s and
+// requeues the claim after the backoff of the attempt. The migration fails once the
+// attempts of the state are exhausted.
+func (r *DatabaseClaimReconciler) manageMigrationAttempt(ctx context.Context, dbClaim *persistancev1.DatabaseClaim,
+	s pgctl.State, attemptErr error) (ctrl.Result, error) {
+	logr := r.Log.WithValues("databaseclaim", dbClaim.Namespace+"/"+dbClaim.Name, "func", "manageMigrationAttempt")
+
+	// migrations started without a migration status keep the default replication names
+	if dbClaim.Status.Migration == nil {
+		dbClaim.Status.Migration = &persistancev1.MigrationStatus{}
+	}
+	status := dbClaim.Status.Migration
+	status.Attempts++
+	status.LastError = attemptErr.Error()
+
+	policy := r.getMigrationRetryPolicy()
+	if policy.Exhausted(status.Attempts) {
+		logr.Error(attemptErr, "migration failed", "state", s.String(), "attempts", status.Attempts)
+		status.FailedState = s.String()
+		dbClaim.Status.MigrationState = pgctl.S_Failed.String()
+		metrics.MigrationsFailed.Inc()
+		r.Recorder.Event(dbClaim, corev1.EventTypeWarning, "MigrationFailed",
+			fmt.Sprintf("migration failed in %s after %d attempts: %s", s, status.Attempts, attemptErr))
+		return r.manageMigrationFailed(ctx, dbClaim)
+	}
+
+	backoff := policy.Backoff(status.Attempts)
+	logr.Info("migration attempt failed", "state", s.String(), "attempts", status.Attempts, "retryAfter", backoff, "error", attemptErr.Error())
+	dbClaim.Status.Error = attemptErr.Error()
+	if err := r.Status().Update(ctx, dbClaim); err != nil {
+		logr.Error(err, "could not update db claim status")
+		return r.manageError(ctx, dbClaim, err)
+	}
+	return ctrl.Result{RequeueAfter: backoff, Requeue: true}, nil
+}
 
-	return fmt.Sprintf("%s://%s:%s@%s:%s/%s?sslmode=%s", "postgres",
-		r.Input.MasterConnInfo.Username, r.Input.MasterConnInfo.Password,
-		r.Input.MasterConnInfo.Host, r.Input.MasterConnInfo.Port,
-		"postgres", r.Input.MasterConnInfo.SSLMode)
+// manageMigrationFailed records the last error of a failed migration, the claim is not
+// requeued until it is annotated to retry the migration
+func (r *DatabaseClaimReconciler) manageMigrationFailed(ctx context.Context, dbClaim *persistancev1.DatabaseClaim) (ctrl.Result, error) {
+	dbClaim.Status.Error = fmt.Sprintf("%s: annotate the claim with %s=true to retry", pgctl.ErrMigrationFailed, retryMigrationAnnotation)
+	if status := dbClaim.Status.Migration; status != nil {
+		dbClaim.Status.Error = fmt.Sprintf("%s in %s: %s: annotate the claim with %s=true to retry",
+			pgctl.ErrMigrationFailed, status.FailedState, status.LastError, retryMigrationAnnotation)
+	}
+	if err := r.Status().Update(ctx, dbClaim); err != nil && !errors.IsConflict(err) {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
 }
 
-func (r *DatabaseClaimReconciler) getReclaimPolicy(fragmentKey string) string {
-	defaultReclaimPolicy := r.Config.GetString("defaultReclaimPolicy")
+// resumeMigration restarts a failed migration from the state that failed and removes
+// the retry annotation of the claim
+func (r *DatabaseClaimReconciler) resumeMigration(ctx context.Context, dbClaim *persistancev1.DatabaseClaim) error {
+	r.Log.Info("resuming failed migration", "databaseclaim", dbClaim.Namespace+"/"+dbClaim.Name)
+	dbClaim.Status.MigrationState = pgctl.S_Initial.String()
+	if status := dbClaim.Status.Migration; status != nil {
+		if status.FailedState != "" {
+			dbClaim.Status.MigrationState = status.FailedState
+		}
+		status.Attempts = 0
+		status.LastError = ""
+		status.FailedState = ""
+	}
+	if err := r.Status().Update(ctx, dbClaim); err != nil {
+		return err
+	}
+	delete(dbClaim.Annotations, retryMigrationAnnotation)
+	return r.Update(ctx, dbClaim)
+}
 
-	if fragmentKey == "" {
-		return defaultReclaimPolicy
+// recordPreFlightReport copies the report of the pre-flight checks into the migration
+// status and emits an event when a check failed
+func (r *DatabaseClaimReconciler) recordPreFlightReport(dbClaim *persistancev1.DatabaseClaim, report *pgctl.PreFlightReport) {
+	checks := make([]persistancev1.PreFlightCheckStatus, 0, len(report.Checks))
+	for _, c := range report.Checks {
+		checks = append(checks, persistancev1.PreFlightCheckStatus{Name: c.Name, Passed: c.Passed, Message: c.Message})
+	}
+	dbClaim.Status.Migration.PreFlight = checks
+	if !report.Passed() {
+		r.Recorder.Event(dbClaim, corev1.EventTypeWarning, "MigrationPreFlightFailed", report.String())
 	}
+}
 
-	reclaimPolicy := r.Config.GetString(fmt.Sprintf("%s::reclaimPolicy", fragmentKey))
+// recordPrivilegeReport copies the roles created and not mapped by the migration of the
+// privileges into the migration status and emits an event when a role was not mapped
+func (r *DatabaseClaimReconciler) recordPrivilegeReport(dbClaim *persistancev1.DatabaseClaim, report *pgctl.PrivilegeReport) {
+	// roles created by an earlier attempt exist on the target and are not reported again
+	for _, role := range report.Created {
+		if !containsString(dbClaim.Status.Migration.CreatedRoles, role) {
+			dbClaim.Status.Migration.CreatedRoles = append(dbClaim.Status.Migration.CreatedRoles, role)
+		}
+	}
+	dbClaim.Status.Migration.UnmappedRoles = report.Unmapped
+	if len(report.Unmapped) > 0 {
+		r.Recorder.Event(dbClaim, corev1.EventTypeWarning, "MigrationRolesUnmapped",
+			fmt.Sprintf("the grants and ownerships of roles %s were not migrated", strings.Join(report.Unmapped, ", ")))
+	}
+}
 
-	if reclaimPolicy == "retain" || (reclaimPolicy == "" && defaultReclaimPolicy == "retain") {
-		// Don't need to delete
-		return "retain"
-	} else {
-		// Assume reclaimPolicy == "delete"
-		return "delete"
+// recordSourceAccessReport adds the write privileges revoked on the source to the
+// migration status, the ones revoked by earlier attempts are kept
+func (r *DatabaseClaimReconciler) recordSourceAccessReport(dbClaim *persistancev1.DatabaseClaim, report *pgctl.SourceAccessReport) {
+	if len(report.Frozen) == 0 {
+		return
+	}
+	status := dbClaim.Status.Migration
+	roles := make(map[string]bool)
+	var frozenRoles []string
+	for _, g := range report.Frozen {
+		role := g.Role
+		if role == "" {
+			role = "PUBLIC"
+		}
+		if !roles[role] {
+			roles[role] = true
+			frozenRoles = append(frozenRoles, role)
+		}
+		i := 0
+		for ; i < len(status.FrozenGrants); i++ {
+			f := status.FrozenGrants[i]
+			if f.Role == g.Role && reflect.DeepEqual(f.Privileges, g.Privileges) {
+				break
+			}
+		}
+		if i == len(status.FrozenGrants) {
+			status.FrozenGrants = append(status.FrozenGrants,
+				persistancev1.FrozenGrantStatus{Role: g.Role, Privileges: g.Privileges})
+		}
+		frozen := &status.FrozenGrants[i]
+		if !containsString(frozen.Tables, g.Table) {
+			frozen.Tables = append(frozen.Tables, g.Table)
+		}
 	}
+	r.Recorder.Event(dbClaim, corev1.EventTypeNormal, "MigrationSourceFrozen",
+		fmt.Sprintf("writes of roles %s revoked on the source", strings.Join(frozenRoles, ", ")))
 }
 
-func (r *DatabaseClaimReconciler) deleteExternalResources(ctx context.Context, dbClaim *persistancev1.DatabaseClaim) error {
-	// delete any external resources associated with the dbClaim
+// completedMigrationReport returns the report of the migration of status kept once it
+// completed
+func completedMigrationReport(status *persistancev1.MigrationStatus) *persistancev1.MigrationReportStatus {
+	now := metav1.Now()
+	report := &persistancev1.MigrationReportStatus{CompletedAt: &now}
+	if status != nil {
+		report.CreatedRoles = status.CreatedRoles
+		report.UnmappedRoles = status.UnmappedRoles
+		report.FrozenGrants = status.FrozenGrants
+		report.Source = status.Source
+	}
+	return report
+}
+
+// reconcileSourceAccessRestore grants back the write privileges frozen on the source of
+// the migration in progress, or else of the last completed migration, and removes the
+// restore annotation of the claim
+func (r *DatabaseClaimReconciler) reconcileSourceAccessRestore(ctx context.Context, dbClaim *persistancev1.DatabaseClaim) (ctrl.Result, error) {
+	logr := r.Log.WithValues("databaseclaim", dbClaim.Namespace+"/"+dbClaim.Name, "func", "reconcileSourceAccessRestore")
+
+	var (
+		grants *[]persistancev1.FrozenGrantStatus
+		source *persistancev1.MigrationSourceStatus
+	)
+	if status := dbClaim.Status.Migration; status != nil {
+		grants, source = &status.FrozenGrants, status.Source
+	} else if report := dbClaim.Status.LastMigration; report != nil {
+		grants, source = &report.FrozenGrants, report.Source
+	}
+	if grants == nil || len(*grants) == 0 {
+		logr.Info("no write privileges frozen on the source of a migration, nothing to restore")
+	} else {
+		frozen := frozenGrants(*grants)
+		dsn, err := r.getMigrationSourceDsn(ctx, source)
+		if err != nil {
+			return r.manageError(ctx, dbClaim, err)
+		}
+		config := pgctl.Config{
+			Log:              r.Log,
+			SourceDBAdminDsn: dsn,
+			Audit:            r.Audit,
+			Claim:            dbClaim.Namespace + "/" + dbClaim.Name,
+		}
+		if err := pgctl.RestoreSourceAccess(ctx, config, frozen); err != nil {
+			return r.manageError(ctx, dbClaim, err)
+		}
+		*grants = nil
+		if dbClaim.Status.Migration == nil {
+			now := metav1.Now()
+			dbClaim.Status.LastMigration.SourceAccessRestoredAt = &now
+		}
+		r.Recorder.Event(dbClaim, corev1.EventTypeNormal, "MigrationSourceRestored",
+			fmt.Sprintf("%d write grants restored on the source", len(frozen)))
+		if err := r.Status().Update(ctx, dbClaim); err != nil {
+			logr.Error(err, "could not update db claim status")
+			return ctrl.Result{}, err
+		}
+	}
+	delete(dbClaim.Annotations, restoreSourceAccessAnnotation)
+	if err := r.Update(ctx, dbClaim); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{Requeue: true}, nil
+}
+
+// abortFailedMigration drops the replication objects of the failed migration of the
+// claim and grants back the write privileges it froze on the source
+func (r *DatabaseClaimReconciler) abortFailedMigration(ctx context.Context, dbClaim *persistancev1.DatabaseClaim) error {
+	logr := r.Log.WithValues("databaseclaim", dbClaim.Namespace+"/"+dbClaim.Name, "func", "abortFailedMigration")
+
+	status := dbClaim.Status.Migration
+	if status == nil || status.Source == nil {
+		logr.Info("the source of the failed migration is unknown, nothing to abort")
+		return nil
+	}
+	dsn, err := r.getMigrationSourceDsn(ctx, status.Source)
+	if err != nil {
+		return err
+	}
+	config := pgctl.Config{
+		Log:              r.Log,
+		SourceDBAdminDsn: dsn,
+		Audit:            r.Audit,
+		Claim:            dbClaim.Namespace + "/" + dbClaim.Name,
+		Replication: pgctl.ReplicationNames{
+			Publication:  status.Publication,
+			Subscription: status.Subscription,
+			Slot:         status.Slot,
+		},
+	}
+	// the subscription is gone with the target when it was not created
+	connInfo, err := r.readResourceSecret(ctx, r.Input.DbHostIdentifier, dbClaim)
+	if err != nil {
+		logr.Info("the target of the failed migration is not available, its subscription is not dropped", "error", err.Error())
+	} else {
+		target := r.Input.MasterConnInfo
+		target.Host = connInfo.Host
+		target.Password = connInfo.Password
+		target.Port = connInfo.Port
+		target.Username = connInfo.Username
+		config.TargetDBAdminDsn = target.Uri()
+	}
+	if err := pgctl.AbortMigration(ctx, config, frozenGrants(status.FrozenGrants)); err != nil {
+		return err
+	}
+	r.Recorder.Event(dbClaim, corev1.EventTypeNormal, "MigrationAborted", "the failed migration was aborted")
+	return nil
+}
+
+// frozenGrants returns the write grants of grants, one per table
+func frozenGrants(grants []persistancev1.FrozenGrantStatus) []pgctl.FrozenGrant {
+	var frozen []pgctl.FrozenGrant
+	for _, g := range grants {
+		for _, table := range g.Tables {
+			frozen = append(frozen, pgctl.FrozenGrant{Role: g.Role, Table: table, Privileges: g.Privileges})
+		}
+	}
+	return frozen
+}
+
+// getMigrationSourceDsn returns the dsn of the master user of the source of a migration
+func (r *DatabaseClaimReconciler) getMigrationSourceDsn(ctx context.Context, source *persistancev1.MigrationSourceStatus) (string, error) {
+	if source == nil || source.ConnectionInfo == nil {
+		return "", fmt.Errorf("the source of the migration is unknown")
+	}
+	gs := &corev1.Secret{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: source.SecretNamespace, Name: source.SecretName}, gs); err != nil {
+		return "", err
+	}
+	connInfo := source.ConnectionInfo.DeepCopy()
+	connInfo.Password = string(gs.Data[masterPasswordKey])
+	return connInfo.Uri(), nil
+}
+
+// deleteMigrationMetrics removes the migration metrics of the claim once it migrated
+func deleteMigrationMetrics(dbClaim *persistancev1.DatabaseClaim) {
+	claim := dbClaim.Namespace + "/" + dbClaim.Name
+	metrics.MigrationTablesTotal.DeleteLabelValues(claim)
+	metrics.MigrationTablesSynced.DeleteLabelValues(claim)
+	metrics.MigrationReplicationLag.DeleteLabelValues(claim)
+	metrics.MigrationRowsValidated.DeleteLabelValues(claim)
+}
+
+func (r *DatabaseClaimReconciler) getClientForExistingDB(ctx context.Context, logr logr.Logger,
+	dbClaim *persistancev1.DatabaseClaim, connInfo *persistancev1.DatabaseClaimConnectionInfo) (dbclient.Client, error) {
+
+	if err := r.getExistingDBConnInfo(ctx, dbClaim, connInfo); err != nil {
+		return nil, err
+	}
+	updateHostPortStatus(&dbClaim.Status.ActiveDB, connInfo.Host, connInfo.Port, connInfo.SSLMode)
+
+	return r.newDBClient(ctx, connInfo.Uri(), dbClaim)
+}
+
+// getExistingDBConnInfo validates connInfo of the source database and sets its password
+// from the secret of the claim
+func (r *DatabaseClaimReconciler) getExistingDBConnInfo(ctx context.Context, dbClaim *persistancev1.DatabaseClaim,
+	connInfo *persistancev1.DatabaseClaimConnectionInfo) error {
+
+	secretKey := "password"
+	gs := &corev1.Secret{}
+
+	if connInfo.Port == "" {
+		return fmt.Errorf("cannot get master port")
+	}
+
+	if connInfo.Username == "" {
+		return fmt.Errorf("invalid credentials (username)")
+	}
+
+	if connInfo.SSLMode == "" {
+		return fmt.Errorf("invalid sslMode")
+	}
+
+	r.Log.Info("using credentials from secret")
+
+	ns := dbClaim.Spec.SourceDataFrom.Database.SecretRef.Namespace
+	if ns == "" {
+		ns = "default"
+	}
+	err := r.Client.Get(ctx, client.ObjectKey{
+		Namespace: ns,
+		Name:      dbClaim.Spec.SourceDataFrom.Database.SecretRef.Name,
+	}, gs)
+	if err != nil {
+		return err
+	}
+	connInfo.Password = string(gs.Data[secretKey])
+
+	if connInfo.Password == "" {
+		return fmt.Errorf("invalid credentials (password)")
+	}
+	return nil
+}
+
+func (r *DatabaseClaimReconciler) getClientConn(dbClaim *persistancev1.DatabaseClaim) persistancev1.DatabaseClaimConnectionInfo {
+	connInfo := persistancev1.DatabaseClaimConnectionInfo{}
+
+	connInfo.Host = r.getMasterHost(dbClaim)
+	connInfo.Port = r.getMasterPort(dbClaim)
+	connInfo.Username = r.getMasterUser(dbClaim)
+	connInfo.SSLMode = r.getSSLMode(dbClaim)
+	connInfo.DatabaseName = GetDBName(dbClaim)
+	return connInfo
+}
+
+func (r *DatabaseClaimReconciler) getDBClient(dbClaim *persistancev1.DatabaseClaim) (dbclient.Client, error) {
+	logr := r.Log.WithValues("databaseclaim", dbClaim.Namespace+"/"+dbClaim.Name, "func", "getDBClient")
+
+	logr.Info("getting dbclient", "dsn", redact.String(r.getMasterDefaultDsn()))
+	updateHostPortStatus(&dbClaim.Status.NewDB, r.Input.MasterConnInfo.Host, r.Input.MasterConnInfo.Port, r.Input.MasterConnInfo.SSLMode)
+	return r.newDBClient(context.TODO(), r.getMasterDefaultDsn(), dbClaim)
+}
+
+func (r *DatabaseClaimReconciler) newDBClient(ctx context.Context, dsn string, dbClaim *persistancev1.DatabaseClaim) (dbclient.Client, error) {
+	cfg := dbclient.Config{Log: r.Log, DBType: "postgres", DSN: dsn,
+		Audit: r.Audit, Claim: dbClaim.Namespace + "/" + dbClaim.Name}
+	if r.DBClients == nil {
+		return dbclient.New(cfg)
+	}
+	return r.DBClients.Get(ctx, cfg)
+}
+
+func (r *DatabaseClaimReconciler) getMasterDefaultDsn() string {
+	return masterDefaultDsn(r.Input.MasterConnInfo)
+}
+
+// masterDefaultDsn returns the dsn of the postgres database of connInfo
+func masterDefaultDsn(connInfo persistancev1.DatabaseClaimConnectionInfo) string {
+	return fmt.Sprintf("%s://%s:%s@%s:%s/%s?sslmode=%s", "postgres",
+		connInfo.Username, connInfo.Password,
+		connInfo.Host, connInfo.Port,
+		"postgres", connInfo.SSLMode)
+}
+
+func (r *DatabaseClaimReconciler) getReclaimPolicy(fragmentKey string) string {
+	defaultReclaimPolicy := r.Config.GetString("defaultReclaimPolicy")
+
+	if fragmentKey == "" {
+		return defaultReclaimPolicy
+	}
+
+	reclaimPolicy := r.Config.GetString(fmt.Sprintf("%s::reclaimPolicy", fragmentKey))
+
+	if reclaimPolicy == "retain" || (reclaimPolicy == "" && defaultReclaimPolicy == "retain") {
+		// Don't need to delete
+		return "retain"
+	} else {
+		// Assume reclaimPolicy == "delete"
+		return "delete"
+	}
+}
+
+func (r *DatabaseClaimReconciler) deleteExternalResources(ctx context.Context, dbClaim *persistancev1.DatabaseClaim) error {
+	// delete any external resources associated with the dbClaim
 	// Only RDS Instance are managed for now
 
 	if r.Input.ManageCloudDB {
@@ -842,14 +1624,237 @@ func (r *DatabaseClaimReconciler) deleteExternalResources(ctx context.Context, d
 					return r.deleteParameterGroup(ctx, pgName)
 
 				}
+				// the host is kept for the other claims, only drop what this claim created
+				return r.dropDatabaseResources(ctx, dbClaim)
 			}
 		}
 		// else reclaimPolicy == "retain" nothing to do!
+		return nil
 	}
 
+	// shared host and existing database claims
+	if r.getReclaimPolicy(dbClaim.Spec.InstanceLabel) == "delete" {
+		return r.dropDatabaseResources(ctx, dbClaim)
+	}
 	return nil
 }
 
+// dropDatabaseResources drops the database, the users and the group role of a claim
+// from a host that outlives the claim. Databases and roles still used by other
+// claims on the same host are kept.
+func (r *DatabaseClaimReconciler) dropDatabaseResources(ctx context.Context, dbClaim *persistancev1.DatabaseClaim) error {
+	logr := r.Log.WithValues("databaseclaim", dbClaim.Namespace+"/"+dbClaim.Name, "func", "dropDatabaseResources")
+
+	if !r.Config.GetBool("dropDatabaseOnDelete") {
+		logr.Info("dropDatabaseOnDelete is disabled, database and users are left in place")
+		return nil
+	}
+	activeDB := dbClaim.Status.ActiveDB.ConnectionInfo
+	if activeDB == nil || activeDB.DatabaseName == "" {
+		logr.Info("no database was provisioned, nothing to drop")
+		return nil
+	}
+
+	otherDBs, otherRoles, err := r.getOtherClaimsResources(ctx, dbClaim)
+	if err != nil {
+		return err
+	}
+
+	var (
+		dbClient dbclient.Client
+		connInfo *persistancev1.DatabaseClaimConnectionInfo
+	)
+	if isExistingSource(dbClaim) {
+		connInfo, err = persistancev1.ParseUri(dbClaim.Spec.SourceDataFrom.Database.DSN)
+		if err != nil {
+			return err
+		}
+		dbClient, err = r.getClientForExistingDB(ctx, logr, dbClaim, connInfo)
+	} else {
+		if r.Input.ManageCloudDB {
+			hostInfo, err := r.readResourceSecret(ctx, r.Input.DbHostIdentifier, dbClaim)
+			if err != nil {
+				return err
+			}
+			r.Input.MasterConnInfo.Host = hostInfo.Host
+			r.Input.MasterConnInfo.Password = hostInfo.Password
+			r.Input.MasterConnInfo.Port = hostInfo.Port
+			r.Input.MasterConnInfo.Username = hostInfo.Username
+		} else {
+			password, err := r.readMasterPassword(ctx, dbClaim)
+			if err != nil {
+				return err
+			}
+			r.Input.MasterConnInfo.Password = password
+		}
+		connInfo = r.Input.MasterConnInfo.DeepCopy()
+		dbClient, err = r.getDBClient(dbClaim)
+	}
+	if err != nil {
+		logr.Error(err, "creating database client error")
+		return err
+	}
+	defer dbClient.Close()
+
+	return r.dropClaimDatabases(dbClient, dbClaim, connInfo, r.keptDatabase(dbClaim), otherDBs, otherRoles)
+}
+
+// keptDatabase returns the database of the claim kept when it is deleted, the database
+// of an existing source belongs to its owner unless dropExistingDatabaseOnDelete is enabled
+func (r *DatabaseClaimReconciler) keptDatabase(dbClaim *persistancev1.DatabaseClaim) string {
+	if isExistingSource(dbClaim) && !r.Config.GetBool("dropExistingDatabaseOnDelete") {
+		return dbClaim.Status.ActiveDB.ConnectionInfo.DatabaseName
+	}
+	return ""
+}
+
+// dropClaimDatabases drops the databases of the claim and their roles, the roles of
+// keptDB are dropped once their objects there are handed over
+func (r *DatabaseClaimReconciler) dropClaimDatabases(dbClient dbclient.Client, dbClaim *persistancev1.DatabaseClaim,
+	connInfo *persistancev1.DatabaseClaimConnectionInfo, keptDB string, otherDBs, otherRoles map[string]bool) error {
+	dbName := dbClaim.Status.ActiveDB.ConnectionInfo.DatabaseName
+	if dbName == keptDB {
+		r.Log.Info("the existing database is kept, only the roles of the claim are dropped", "databaseclaim", dbClaim.Namespace+"/"+dbClaim.Name, "database", dbName)
+		if err := r.dropRoles(dbClient, dbClaim, keptDB, dbClaim.Spec.Username, otherRoles); err != nil {
+			return err
+		}
+	} else if err := r.dropDatabaseAndRoles(dbClient, dbClaim, connInfo, dbName, dbClaim.Spec.Username, otherDBs, otherRoles); err != nil {
+		return err
+	}
+	// the grants of additional users are gone with the database, their roles are
+	// only dropped when it was dropped or is kept
+	provisionedUsers := map[string]bool{}
+	for _, u := range dbClaim.Status.ActiveDB.Users {
+		provisionedUsers[u.Name] = u.ConnectionInfo != nil && u.ConnectionInfo.Username != ""
+	}
+	for _, user := range dbClaim.Spec.AdditionalUsers {
+		if !provisionedUsers[user.Username] || (dbName != keptDB && otherDBs[dbName]) {
+			continue
+		}
+		if err := r.dropRoles(dbClient, dbClaim, keptDB, user.Username, otherRoles); err != nil {
+			return err
+		}
+	}
+	provisioned := map[string]bool{}
+	for _, db := range dbClaim.Status.ActiveDB.Databases {
+		provisioned[db.Name] = db.ConnectionInfo != nil && db.ConnectionInfo.DatabaseName != ""
+	}
+	for _, db := range dbClaim.Spec.Databases {
+		if !provisioned[db.Name] {
+			continue
+		}
+		if err := r.dropDatabaseAndRoles(dbClient, dbClaim, connInfo, db.Name, db.Username, otherDBs, otherRoles); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *DatabaseClaimReconciler) dropDatabaseAndRoles(dbClient dbclient.Client, dbClaim *persistancev1.DatabaseClaim,
+	connInfo *persistancev1.DatabaseClaimConnectionInfo, dbName, rolename string, otherDBs, otherRoles map[string]bool) error {
+	logr := r.Log.WithValues("databaseclaim", dbClaim.Namespace+"/"+dbClaim.Name, "func", "dropDatabaseAndRoles")
+
+	// the group role owns the objects of a database that is kept
+	if otherDBs[dbName] {
+		logr.Info("database is used by other claims, it is not dropped", "database", dbName, "role", rolename)
+		return nil
+	}
+	if err := r.archiveDatabase(dbClaim, connInfo, dbName); err != nil {
+		return err
+	}
+	if _, err := dbClient.DropDatabase(dbName); err != nil {
+		return err
+	}
+	return r.dropRoles(dbClient, dbClaim, "", rolename, otherRoles)
+}
+
+// dropRoles drops the logins and the group role of rolename unless other claims use it,
+// their objects in keptDB are handed over to the master user first
+func (r *DatabaseClaimReconciler) dropRoles(dbClient dbclient.Client, dbClaim *persistancev1.DatabaseClaim,
+	keptDB, rolename string, otherRoles map[string]bool) error {
+	if otherRoles[rolename] {
+		r.Log.Info("role is used by other claims, it is not dropped", "databaseclaim", dbClaim.Namespace+"/"+dbClaim.Name, "role", rolename)
+		return nil
+	}
+	// logins of a larger number of generations configured before are dropped too
+	dbu := dbuser.NewDBUserWithGenerations(rolename, dbuser.MaxGenerations)
+	if keptDB != "" {
+		for _, role := range append(dbu.Users(), rolename) {
+			if err := dbClient.DisownRole(keptDB, role); err != nil {
+				return err
+			}
+		}
+	}
+	for _, username := range dbu.Users() {
+		if _, err := dbClient.DropUser(username); err != nil {
+			return err
+		}
+	}
+	_, err := dbClient.DropGroup(rolename)
+	return err
+}
+
+// getOtherClaimsResources returns the databases and the roles used by the other
+// claims on the host and port of dbClaim, additional databases included
+func (r *DatabaseClaimReconciler) getOtherClaimsResources(ctx context.Context, dbClaim *persistancev1.DatabaseClaim) (map[string]bool, map[string]bool, error) {
+	var dbClaimList persistancev1.DatabaseClaimList
+	if err := r.List(ctx, &dbClaimList); err != nil {
+		return nil, nil, err
+	}
+
+	activeDB := dbClaim.Status.ActiveDB.ConnectionInfo
+	dbs, roles := map[string]bool{}, map[string]bool{}
+	for _, other := range dbClaimList.Items {
+		if other.Namespace == dbClaim.Namespace && other.Name == dbClaim.Name {
+			continue
+		}
+		otherDB := other.Status.ActiveDB.ConnectionInfo
+		if otherDB == nil || otherDB.Host != activeDB.Host || otherDB.Port != activeDB.Port {
+			continue
+		}
+		dbs[otherDB.DatabaseName] = true
+		roles[other.Spec.Username] = true
+		for _, db := range other.Spec.Databases {
+			dbs[db.Name] = true
+			roles[db.Username] = true
+		}
+		for _, user := range other.Spec.AdditionalUsers {
+			roles[user.Username] = true
+		}
+	}
+	return dbs, roles, nil
+}
+
+// archiveDatabase writes a final pg_dump archive of dbName to dropDatabaseArchivePath
+// when it is configured
+func (r *DatabaseClaimReconciler) archiveDatabase(dbClaim *persistancev1.DatabaseClaim,
+	connInfo *persistancev1.DatabaseClaimConnectionInfo, dbName string) error {
+
+	archivePath := r.Config.GetString("dropDatabaseArchivePath")
+	if archivePath == "" {
+		return nil
+	}
+	archiveConnInfo := connInfo.DeepCopy()
+	archiveConnInfo.DatabaseName = dbName
+
+	dump := pgctl.NewDump(archiveConnInfo.Uri())
+	dump.SetupFormat("c")
+	dump.SetPath(strings.TrimSuffix(archivePath, "/") + "/")
+	dump.SetFileName(fmt.Sprintf("%s_%s_%s_%d.dump", dbClaim.Namespace, dbClaim.Name, dbName, time.Now().Unix()))
+
+	r.Log.Info("archiving database before drop", "database", dbName, "file", dump.Path+dump.GetFileName())
+	result := dump.Exec(pgctl.ExecOptions{StreamPrint: false})
+	if result.Error != nil {
+		return fmt.Errorf("final archive of database %s failed: %s", dbName, result.Error.Err)
+	}
+	return nil
+}
+
+func isExistingSource(dbClaim *persistancev1.DatabaseClaim) bool {
+	return dbClaim.Spec.UseExistingSource != nil && *dbClaim.Spec.UseExistingSource &&
+		dbClaim.Spec.SourceDataFrom != nil && dbClaim.Spec.SourceDataFrom.Database != nil
+}
+
 func (r *DatabaseClaimReconciler) generatePassword() (string, error) {
 	var pass string
 	var err error
@@ -908,7 +1913,8 @@ func (r *DatabaseClaimReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		return err
 	}
 
-	pred := predicate.GenerationChangedPredicate{}
+	// the plan annotation does not change the generation of the claim
+	pred := predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{})
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&persistancev1.DatabaseClaim{}).WithEventFilter(pred).
 		Complete(r)
@@ -965,6 +1971,93 @@ func (r *DatabaseClaimReconciler) getPasswordRotationTime() time.Duration {
 	return time.Duration(prt) * time.Minute
 }
 
+// getRotationGenerations returns the number of logins alternated by password rotation
+func (r *DatabaseClaimReconciler) getRotationGenerations() int {
+	generations := r.Config.GetInt("passwordconfig::rotationGenerations")
+	if generations == 0 {
+		return dbuser.DefaultGenerations
+	}
+	if generations < dbuser.DefaultGenerations || generations > dbuser.MaxGenerations {
+		r.Log.Info("rotation generations are out of range, should be between 2 and 8, use the default")
+		return dbuser.DefaultGenerations
+	}
+	return generations
+}
+
+// getRotationGracePeriod returns how long a replaced login keeps access, 0 keeps
+// replaced logins enabled until they are rotated again
+func (r *DatabaseClaimReconciler) getRotationGracePeriod() time.Duration {
+	return time.Duration(r.Config.GetInt("passwordconfig::rotationGracePeriod")) * time.Minute
+}
+
+// getMasterPasswordRotationPeriod returns the age after which the master password of a
+// cloud host is rotated, 0 disables the rotation
+func (r *DatabaseClaimReconciler) getMasterPasswordRotationPeriod() time.Duration {
+	period := r.Config.GetInt("passwordconfig::masterPasswordRotationPeriod")
+	if period < 0 {
+		return 0
+	}
+	return time.Duration(period) * time.Minute
+}
+
+// getMasterPasswordVerifyTimeout returns how long a cloud host may take to accept a
+// rotated master password before the previous one is restored
+func (r *DatabaseClaimReconciler) getMasterPasswordVerifyTimeout() time.Duration {
+	timeout := r.Config.GetInt("passwordconfig::masterPasswordVerifyTimeout")
+	if timeout <= 0 {
+		return defaultMasterVerifyTimeout * time.Minute
+	}
+	return time.Duration(timeout) * time.Minute
+}
+
+// getMigrationRetryPolicy returns the backoff and attempts of the migration states,
+// migrationRetry::initialInterval, maxInterval and pollInterval are in seconds
+func (r *DatabaseClaimReconciler) getMigrationRetryPolicy() pgctl.RetryPolicy {
+	policy := pgctl.DefaultRetryPolicy
+	if interval := r.Config.GetInt("migrationRetry::initialInterval"); interval > 0 {
+		policy.InitialInterval = time.Duration(interval) * time.Second
+	}
+	if interval := r.Config.GetInt("migrationRetry::maxInterval"); interval > 0 {
+		policy.MaxInterval = time.Duration(interval) * time.Second
+	}
+	if r.Config.IsSet("migrationRetry::maxAttempts") {
+		policy.MaxAttempts = r.Config.GetInt("migrationRetry::maxAttempts")
+	}
+	if interval := r.Config.GetInt("migrationRetry::pollInterval"); interval > 0 {
+		policy.PollInterval = time.Duration(interval) * time.Second
+	}
+	return policy
+}
+
+// getMigrationSequenceMargin returns the margin the sequences of the target are set
+// ahead of the source by. The sequences are set before the source is frozen, a margin
+// that is not positive is replaced by the default one.
+func (r *DatabaseClaimReconciler) getMigrationSequenceMargin() int64 {
+	if r.Config.IsSet("migrationSequenceMargin") {
+		if margin := r.Config.GetInt64("migrationSequenceMargin"); margin > 0 {
+			return margin
+		}
+		r.Log.Info("ignoring a migration sequence margin that is not positive", "default", pgctl.DefaultSequenceMargin)
+	}
+	return pgctl.DefaultSequenceMargin
+}
+
+// getMigrationTimeouts returns the timeouts in seconds of migrationTimeouts, by state name
+func (r *DatabaseClaimReconciler) getMigrationTimeouts() map[pgctl.StateEnum]time.Duration {
+	timeouts := make(map[pgctl.StateEnum]time.Duration)
+	for name := range r.Config.GetStringMap("migrationTimeouts") {
+		state, err := pgctl.GetStateEnum(name)
+		if err != nil {
+			r.Log.Info("ignoring the timeout of an unknown migration state", "state", name)
+			continue
+		}
+		if timeout := r.Config.GetInt("migrationTimeouts::" + name); timeout > 0 {
+			timeouts[state] = time.Duration(timeout) * time.Second
+		}
+	}
+	return timeouts
+}
+
 func (r *DatabaseClaimReconciler) isPasswordComplexity() bool {
 	complEnabled := r.Config.GetString("passwordconfig::passwordComplexity")
 
@@ -1119,10 +2212,9 @@ func (r *DatabaseClaimReconciler) manageCloudHost(ctx context.Context, dbClaim *
 	}
 	return false, fmt.Errorf("unsupported db type requested - %s", dbClaim.Spec.Type)
 }
-func (r *DatabaseClaimReconciler) manageDatabase(dbClient dbclient.Client, status *persistancev1.Status) error {
+func (r *DatabaseClaimReconciler) manageDatabase(dbClient dbclient.Client, status *persistancev1.Status, dbName string) error {
 	logr := r.Log.WithValues("func", "manageDatabase")
 
-	dbName := r.Input.MasterConnInfo.DatabaseName
 	created, err := dbClient.CreateDatabase(dbName)
 	if err != nil {
 		msg := fmt.Sprintf("error creating database postgresURI %s using %s", dbName, r.Input.MasterConnInfo.Uri())
@@ -1142,46 +2234,689 @@ func (r *DatabaseClaimReconciler) manageDatabase(dbClient dbclient.Client, statu
 	if created || status.ConnectionInfo.DatabaseName == "" {
 		updateDBStatus(status, dbName)
 	}
-	return nil
+	return nil
+}
+
+// manageExtensions installs and updates the extensions of the claim in dbName and drops
+// the removed ones when requested
+func (r *DatabaseClaimReconciler) manageExtensions(dbClient dbclient.Client, status *persistancev1.Status,
+	dbName string, dbClaim *persistancev1.DatabaseClaim) error {
+	logr := r.Log.WithValues("func", "manageExtensions")
+
+	if len(dbClaim.Spec.Extensions) == 0 && len(status.Extensions) == 0 {
+		return nil
+	}
+
+	installed, err := dbClient.GetExtensions(dbName)
+	if err != nil {
+		return err
+	}
+	installedByName := map[string]dbclient.Extension{}
+	for _, e := range installed {
+		installedByName[e.Name] = e
+	}
+
+	requested := map[string]bool{}
+	extensions := make([]persistancev1.ExtensionStatus, 0, len(dbClaim.Spec.Extensions))
+	for _, ext := range dbClaim.Spec.Extensions {
+		requested[ext.Name] = true
+		desired := dbclient.Extension{Name: ext.Name, Version: ext.Version, Schema: ext.Schema}
+		current, ok := installedByName[ext.Name]
+
+		var err error
+		if !ok {
+			err = dbClient.CreateExtension(dbName, desired)
+		} else if (desired.Version != "" && desired.Version != current.Version) ||
+			(desired.Schema != "" && desired.Schema != current.Schema) {
+			if desired.Version == current.Version {
+				desired.Version = ""
+			}
+			if desired.Schema == current.Schema {
+				desired.Schema = ""
+			}
+			err = dbClient.UpdateExtension(dbName, desired)
+		}
+		extStatus := persistancev1.ExtensionStatus{Name: ext.Name}
+		if err != nil {
+			logr.Error(err, "extension reconcile failed", "database", dbName, "extension", ext.Name)
+			extStatus.Error = err.Error()
+		}
+		extensions = append(extensions, extStatus)
+	}
+
+	for _, prev := range status.Extensions {
+		if requested[prev.Name] {
+			continue
+		}
+		if _, ok := installedByName[prev.Name]; !ok {
+			continue
+		}
+		if !dbClaim.Spec.DropRemovedExtensions {
+			logr.Info("extension removed from claim, leaving it installed", "database", dbName, "extension", prev.Name)
+			// keep tracking the extension so that it is dropped once requested
+			extensions = append(extensions, persistancev1.ExtensionStatus{Name: prev.Name, Removed: true})
+			continue
+		}
+		if err := dbClient.DropExtension(dbName, prev.Name); err != nil {
+			logr.Error(err, "extension drop failed", "database", dbName, "extension", prev.Name)
+			// keep tracking the extension so that the drop is retried
+			extensions = append(extensions, persistancev1.ExtensionStatus{Name: prev.Name, Removed: true, Error: err.Error()})
+		}
+	}
+
+	installed, err = dbClient.GetExtensions(dbName)
+	if err != nil {
+		return err
+	}
+	installedByName = map[string]dbclient.Extension{}
+	for _, e := range installed {
+		installedByName[e.Name] = e
+	}
+	for i := range extensions {
+		if e, ok := installedByName[extensions[i].Name]; ok {
+			extensions[i].Version = e.Version
+			extensions[i].Schema = e.Schema
+		}
+	}
+	status.Extensions = extensions
+	return nil
+}
+
+// manageSchemas creates the schemas of the claim owned by the group role and
+// repairs drift of their ownership, grants and default privileges
+func (r *DatabaseClaimReconciler) manageSchemas(dbClient dbclient.Client, status *persistancev1.Status,
+	dbName string, dbClaim *persistancev1.DatabaseClaim) error {
+	logr := r.Log.WithValues("func", "manageSchemas")
+
+	if len(dbClaim.Spec.Schemas) == 0 && len(status.Schemas) == 0 {
+		return nil
+	}
+	rolename := dbClaim.Spec.Username
+
+	previous := map[string]persistancev1.SchemaStatus{}
+	for _, s := range status.Schemas {
+		previous[s.Name] = s
+	}
+
+	schemas := make([]persistancev1.SchemaStatus, 0, len(dbClaim.Spec.Schemas))
+	for _, schema := range dbClaim.Spec.Schemas {
+		schemaStatus := persistancev1.SchemaStatus{Name: schema.Name, RepairedAt: previous[schema.Name].RepairedAt}
+
+		repaired, err := r.manageSchema(dbClient, dbName, schema.Name, rolename)
+		if err != nil {
+			logr.Error(err, "schema reconcile failed", "database", dbName, "schema", schema.Name)
+			schemaStatus.Error = err.Error()
+		}
+		if len(repaired) > 0 {
+			timeNow := metav1.Now()
+			schemaStatus.RepairedDrift = repaired
+			schemaStatus.RepairedAt = &timeNow
+		}
+		schemas = append(schemas, schemaStatus)
+	}
+	status.Schemas = schemas
+	return nil
+}
+
+func (r *DatabaseClaimReconciler) manageSchema(dbClient dbclient.Client, dbName, schemaName, rolename string) ([]string, error) {
+	created, err := dbClient.CreateSchema(dbName, schemaName, rolename)
+	if err != nil {
+		return nil, err
+	}
+	repaired, err := dbClient.ManageSchemaPrivileges(dbName, schemaName, rolename)
+	if created {
+		// a new schema has no drift, its grants are set up for the first time
+		return nil, err
+	}
+	return repaired, err
+}
+
+// manageDatabases creates the additional databases of the claim and their users on the
+// host of status
+func (r *DatabaseClaimReconciler) manageDatabases(ctx context.Context, dbClient dbclient.Client,
+	status *persistancev1.Status, dbClaim *persistancev1.DatabaseClaim) error {
+	logr := r.Log.WithValues("func", "manageDatabases")
+
+	if len(dbClaim.Spec.Databases) == 0 && len(status.Databases) == 0 {
+		return nil
+	}
+	previous := map[string]persistancev1.DatabaseStatus{}
+	for _, d := range status.Databases {
+		previous[d.Name] = d
+	}
+
+	// manageUser reports new passwords in TempSecret, the one of the main user
+	// is written to the secret by the caller
+	mainUserPassword := r.Input.TempSecret
+	defer func() { r.Input.TempSecret = mainUserPassword }()
+
+	databases := make([]persistancev1.DatabaseStatus, 0, len(dbClaim.Spec.Databases))
+	for _, db := range dbClaim.Spec.Databases {
+		dbStatus := previous[db.Name]
+		dbStatus.Name = db.Name
+		dbStatus.Error = ""
+		if err := r.manageAdditionalDatabase(ctx, dbClient, status, &dbStatus, db, dbClaim); err != nil {
+			logr.Error(err, "database reconcile failed", "database", db.Name)
+			dbStatus.Error = err.Error()
+		}
+		databases = append(databases, dbStatus)
+	}
+	status.Databases = databases
+	return nil
+}
+
+func (r *DatabaseClaimReconciler) manageAdditionalDatabase(ctx context.Context, dbClient dbclient.Client,
+	status *persistancev1.Status, dbStatus *persistancev1.DatabaseStatus,
+	db persistancev1.AdditionalDatabase, dbClaim *persistancev1.DatabaseClaim) error {
+
+	hostInfo := status.ConnectionInfo
+	// users of a database provisioned on another host do not exist on this one
+	dbStatusOnHost := persistancev1.Status{ConnectionInfo: &persistancev1.DatabaseClaimConnectionInfo{}}
+	if dbStatus.ConnectionInfo != nil && dbStatus.ConnectionInfo.Host == hostInfo.Host {
+		dbStatusOnHost.ConnectionInfo = dbStatus.ConnectionInfo.DeepCopy()
+		dbStatusOnHost.DbCreatedAt = dbStatus.DbCreatedAt
+		dbStatusOnHost.UserUpdatedAt = dbStatus.UserUpdatedAt
+		dbStatusOnHost.Logins = dbStatus.Logins
+	}
+	updateHostPortStatus(&dbStatusOnHost, hostInfo.Host, hostInfo.Port, hostInfo.SSLMode)
+
+	if err := r.manageDatabase(dbClient, &dbStatusOnHost, db.Name); err != nil {
+		return err
+	}
+	prefix := getSecretKeyPrefix(db)
+	password := ""
+	if dbStatusOnHost.UserUpdatedAt != nil {
+		var err error
+		password, err = r.readSecretKey(ctx, dbClaim, dbClaim.Spec.SecretName, prefix+"password")
+		if err != nil {
+			return err
+		}
+		if password == "" {
+			// the password was lost with the secret, a new one is set by a rotation
+			dbStatusOnHost.UserUpdatedAt = nil
+		}
+	}
+	r.Input.TempSecret = ""
+	if err := r.manageUser(dbClient, dbClaim, &dbStatusOnHost, db.Name, db.Username); err != nil {
+		return err
+	}
+	if r.Input.TempSecret != "" {
+		password = r.Input.TempSecret
+	}
+	// the keys follow the current connection info, not only the rotations
+	connInfo := dbStatusOnHost.ConnectionInfo.DeepCopy()
+	connInfo.Password = password
+	if err := r.createOrUpdateSecretKeys(ctx, dbClaim, dbClaim.Spec.SecretName, prefix, connInfo); err != nil {
+		return err
+	}
+	dbStatus.ConnectionInfo = dbStatusOnHost.ConnectionInfo
+	dbStatus.DbCreatedAt = dbStatusOnHost.DbCreatedAt
+	dbStatus.UserUpdatedAt = dbStatusOnHost.UserUpdatedAt
+	dbStatus.Logins = dbStatusOnHost.Logins
+	return nil
+}
+
+func getSecretKeyPrefix(db persistancev1.AdditionalDatabase) string {
+	if db.SecretKeyPrefix != "" {
+		return db.SecretKeyPrefix
+	}
+	return db.Name + "_"
+}
+
+// manageSettings applies the connection limit and the role settings of the claim to the
+// logins of Username and the database settings to dbName. Settings removed from the
+// claim are reset and settings changed outside of the claim are corrected.
+// Failures of the settings are reported in their status and do not stop the reconcile.
+func (r *DatabaseClaimReconciler) manageSettings(dbClient dbclient.Client, status *persistancev1.Status,
+	dbName string, dbClaim *persistancev1.DatabaseClaim) error {
+	logr := r.Log.WithValues("func", "manageSettings")
+
+	limit := -1
+	if dbClaim.Spec.ConnectionLimit != nil {
+		limit = int(*dbClaim.Spec.ConnectionLimit)
+	}
+	logins := r.newDBUser(dbClaim.Spec.Username).Users()
+	for _, login := range logins {
+		if err := dbClient.SetConnectionLimit(login, limit); err != nil {
+			return err
+		}
+	}
+
+	if len(dbClaim.Spec.DatabaseSettings) == 0 && len(dbClaim.Spec.RoleSettings) == 0 && status.Settings == nil {
+		return nil
+	}
+	previous := status.Settings
+	if previous == nil {
+		previous = &persistancev1.SettingsStatus{}
+	}
+	// the last correction is kept until drift is corrected again
+	settingsStatus := &persistancev1.SettingsStatus{CorrectedDrift: previous.CorrectedDrift, CorrectedAt: previous.CorrectedAt}
+	databaseSettings, roleSettings := lowerSettingNames(dbClaim.Spec.DatabaseSettings), lowerSettingNames(dbClaim.Spec.RoleSettings)
+
+	changed, err := dbClient.ManageDatabaseSettings(dbName, databaseSettings, settingNames(previous.Database))
+	if err != nil {
+		logr.Error(err, "database settings reconcile failed", "database", dbName)
+		settingsStatus.Error = err.Error()
+		status.Settings = settingsStatus
+		return nil
+	}
+	drift := driftedSettings("database", changed, lowerSettingNames(previous.Database), databaseSettings)
+	for _, login := range logins {
+		changed, err := dbClient.ManageRoleSettings(login, roleSettings, settingNames(previous.Role))
+		if err != nil {
+			logr.Error(err, "role settings reconcile failed", "role", login)
+			settingsStatus.Error = err.Error()
+			status.Settings = settingsStatus
+			return nil
+		}
+		// a login created by this rotation gets its settings for the first time
+		if r.Input.TempSecret != "" && login == status.ConnectionInfo.Username {
+			continue
+		}
+		for _, d := range driftedSettings("role", changed, lowerSettingNames(previous.Role), roleSettings) {
+			if !containsFold(drift, d) {
+				drift = append(drift, d)
+			}
+		}
+	}
+	if len(drift) > 0 {
+		logr.Info("corrected settings drift", "drift", drift)
+		timeNow := metav1.Now()
+		settingsStatus.CorrectedDrift = drift
+		settingsStatus.CorrectedAt = &timeNow
+	}
+	settingsStatus.Database = databaseSettings
+	settingsStatus.Role = roleSettings
+	if len(settingsStatus.Database) == 0 && len(settingsStatus.Role) == 0 {
+		// every setting of the claim was reset
+		settingsStatus = nil
+	}
+	status.Settings = settingsStatus
+	return nil
+}
+
+// lowerSettingNames returns settings with their names in lowercase, the way postgres
+// stores them
+func lowerSettingNames(settings map[string]string) map[string]string {
+	if settings == nil {
+		return nil
+	}
+	lower := make(map[string]string, len(settings))
+	for name, value := range settings {
+		lower[strings.ToLower(name)] = value
+	}
+	return lower
+}
+
+func settingNames(settings map[string]string) []string {
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// driftedSettings returns the changed settings that were applied with the same value before
+func driftedSettings(kind string, changed []string, applied, desired map[string]string) []string {
+	var drift []string
+	for _, setting := range changed {
+		if v, ok := applied[setting]; ok && v == desired[setting] {
+			drift = append(drift, kind+" "+setting)
+		}
+	}
+	return drift
+}
+
+// manageAdditionalUsers provisions the additional users of the claim in dbName and
+// rotates their logins like the ones of Username
+func (r *DatabaseClaimReconciler) manageAdditionalUsers(ctx context.Context, dbClient dbclient.Client,
+	status *persistancev1.Status, dbName string, dbClaim *persistancev1.DatabaseClaim) error {
+	logr := r.Log.WithValues("func", "manageAdditionalUsers")
+
+	if len(dbClaim.Spec.AdditionalUsers) == 0 && len(status.Users) == 0 {
+		return nil
+	}
+	previous := map[string]persistancev1.UserStatus{}
+	for _, u := range status.Users {
+		previous[u.Name] = u
+	}
+
+	// the password of the main user is written to the secret by the caller
+	mainUserPassword := r.Input.TempSecret
+	defer func() { r.Input.TempSecret = mainUserPassword }()
+
+	users := make([]persistancev1.UserStatus, 0, len(dbClaim.Spec.AdditionalUsers))
+	for _, user := range dbClaim.Spec.AdditionalUsers {
+		userStatus := previous[user.Username]
+		userStatus.Name = user.Username
+		userStatus.Error = ""
+		if err := r.manageAdditionalUser(ctx, dbClient, status, &userStatus, dbName, user, dbClaim); err != nil {
+			logr.Error(err, "user reconcile failed", "user", user.Username)
+			userStatus.Error = err.Error()
+		}
+		users = append(users, userStatus)
+	}
+	status.Users = users
+	return nil
+}
+
+func (r *DatabaseClaimReconciler) manageAdditionalUser(ctx context.Context, dbClient dbclient.Client,
+	status *persistancev1.Status, userStatus *persistancev1.UserStatus, dbName string,
+	user persistancev1.AdditionalUser, dbClaim *persistancev1.DatabaseClaim) error {
+
+	hostInfo := status.ConnectionInfo
+	// logins rotated on another host do not exist on this one
+	userStatusOnHost := persistancev1.Status{ConnectionInfo: &persistancev1.DatabaseClaimConnectionInfo{}}
+	if userStatus.ConnectionInfo != nil && userStatus.ConnectionInfo.Host == hostInfo.Host {
+		userStatusOnHost.ConnectionInfo = userStatus.ConnectionInfo.DeepCopy()
+		userStatusOnHost.UserUpdatedAt = userStatus.UserUpdatedAt
+		userStatusOnHost.Logins = userStatus.Logins
+	}
+	updateHostPortStatus(&userStatusOnHost, hostInfo.Host, hostInfo.Port, hostInfo.SSLMode)
+	userStatusOnHost.ConnectionInfo.DatabaseName = dbName
+
+	if _, err := dbClient.CreateRole(user.Username); err != nil {
+		return err
+	}
+	if err := dbClient.ManageUserPrivileges(dbName, user.Username, getUserPrivileges(user, dbClaim)); err != nil {
+		return err
+	}
+	r.Input.TempSecret = ""
+	if err := r.rotateUser(dbClient, &userStatusOnHost, user.Username); err != nil {
+		return err
+	}
+	if r.Input.TempSecret != "" {
+		connInfo := userStatusOnHost.ConnectionInfo.DeepCopy()
+		connInfo.Password = r.Input.TempSecret
+		if err := r.createOrUpdateSecretKeys(ctx, dbClaim, user.SecretName, "", connInfo); err != nil {
+			return err
+		}
+	}
+	limit := -1
+	if user.ConnectionLimit != nil {
+		limit = int(*user.ConnectionLimit)
+	}
+	for _, login := range r.newDBUser(user.Username).Users() {
+		if err := dbClient.SetConnectionLimit(login, limit); err != nil {
+			return err
+		}
+		desired := dbclient.RoleState{MemberOf: []string{user.Username}, SetRole: user.Username}
+		if err := r.repairRoleDrift(dbClient, dbClaim, login, desired); err != nil {
+			return err
+		}
+	}
+	userStatus.ConnectionInfo = userStatusOnHost.ConnectionInfo
+	userStatus.UserUpdatedAt = userStatusOnHost.UserUpdatedAt
+	userStatus.Logins = userStatusOnHost.Logins
+	return nil
+}
+
+func getUserPrivileges(user persistancev1.AdditionalUser, dbClaim *persistancev1.DatabaseClaim) dbclient.UserPrivileges {
+	privileges := dbclient.UserPrivileges{
+		Profile:   user.Profile,
+		OwnerRole: dbClaim.Spec.Username,
+		Schemas:   []string{"public"},
+	}
+	if privileges.Profile == "" {
+		privileges.Profile = persistancev1.ReadWriteProfile
+	}
+	for _, schema := range dbClaim.Spec.Schemas {
+		if schema.Name != "public" {
+			privileges.Schemas = append(privileges.Schemas, schema.Name)
+		}
+	}
+	for _, grant := range user.Grants {
+		schema := grant.Schema
+		if schema == "" {
+			schema = "public"
+		}
+		privileges.Grants = append(privileges.Grants, dbclient.Grant{
+			Privileges: grant.Privileges,
+			Schema:     schema,
+			Tables:     grant.Tables,
+		})
+	}
+	return privileges
+}
+
+// validateExtensions checks the claim extensions against the allowedExtensions list
+// of the controller config. An empty list permits every extension. Extension names are
+// case sensitive in PostgreSQL, so they must match the list exactly.
+func (r *DatabaseClaimReconciler) validateExtensions(extensions []persistancev1.Extension) error {
+	allowed := r.Config.GetStringSlice("allowedExtensions")
+	seen := map[string]bool{}
+	for _, ext := range extensions {
+		if ext.Name == "" {
+			return fmt.Errorf("extension name is required")
+		}
+		if seen[ext.Name] {
+			return fmt.Errorf("extension %s is listed more than once", ext.Name)
+		}
+		seen[ext.Name] = true
+		if len(allowed) > 0 && !containsString(allowed, ext.Name) {
+			return fmt.Errorf("extension %s is not allowed by db-controller configuration", ext.Name)
+		}
+	}
+	return nil
+}
+
+func validateSchemas(schemas []persistancev1.Schema) error {
+	seen := map[string]bool{}
+	for _, schema := range schemas {
+		if schema.Name == "" {
+			return fmt.Errorf("schema name is required")
+		}
+		if strings.HasPrefix(schema.Name, "pg_") || schema.Name == "information_schema" {
+			return fmt.Errorf("schema name %s is reserved", schema.Name)
+		}
+		if seen[schema.Name] {
+			return fmt.Errorf("schema %s is listed more than once", schema.Name)
+		}
+		seen[schema.Name] = true
+	}
+	return nil
+}
+
+// validateDatabases rejects additional databases that collide with each other
+// or with the database and user of the claim
+func validateDatabases(dbClaim *persistancev1.DatabaseClaim) error {
+	dbNames := map[string]bool{GetDBName(dbClaim): true}
+	usernames := map[string]bool{dbClaim.Spec.Username: true}
+	prefixes := map[string]bool{}
+	for _, db := range dbClaim.Spec.Databases {
+		if db.Name == "" {
+			return fmt.Errorf("database name is required")
+		}
+		if strings.Contains(db.Name, " ") {
+			return fmt.Errorf("invalid database name %s (contains space)", db.Name)
+		}
+		if dbNames[db.Name] {
+			return fmt.Errorf("database %s is listed more than once", db.Name)
+		}
+		dbNames[db.Name] = true
+		if db.Username == "" {
+			return fmt.Errorf("userName of database %s is required", db.Name)
+		}
+		if usernames[db.Username] {
+			return fmt.Errorf("userName %s of database %s is already used by the claim", db.Username, db.Name)
+		}
+		usernames[db.Username] = true
+		prefix := getSecretKeyPrefix(db)
+		if prefixes[prefix] {
+			return fmt.Errorf("secretKeyPrefix %s of database %s is already used by the claim", prefix, db.Name)
+		}
+		prefixes[prefix] = true
+	}
+	return nil
+}
+
+// validateAdditionalUsers rejects additional users that collide with the other users
+// of the claim or request an invalid privilege profile
+func validateAdditionalUsers(dbClaim *persistancev1.DatabaseClaim) error {
+	usernames := map[string]bool{dbClaim.Spec.Username: true}
+	for _, db := range dbClaim.Spec.Databases {
+		usernames[db.Username] = true
+	}
+	secretNames := map[string]bool{dbClaim.Spec.SecretName: true}
+	for _, user := range dbClaim.Spec.AdditionalUsers {
+		if user.Username == "" {
+			return fmt.Errorf("additional user name is required")
+		}
+		if usernames[user.Username] {
+			return fmt.Errorf("additional user %s is already used by the claim", user.Username)
+		}
+		usernames[user.Username] = true
+		if user.SecretName == "" {
+			return fmt.Errorf("secretName of additional user %s is required", user.Username)
+		}
+		if secretNames[user.SecretName] {
+			return fmt.Errorf("secretName %s of additional user %s is already used by the claim", user.SecretName, user.Username)
+		}
+		secretNames[user.SecretName] = true
+		if user.ConnectionLimit != nil && *user.ConnectionLimit < -1 {
+			return fmt.Errorf("invalid connectionLimit %d of additional user %s", *user.ConnectionLimit, user.Username)
+		}
+		switch user.Profile {
+		case "", persistancev1.OwnerProfile, persistancev1.ReadWriteProfile, persistancev1.ReadOnlyProfile:
+			if len(user.Grants) > 0 {
+				return fmt.Errorf("grants of additional user %s require the custom profile", user.Username)
+			}
+		case persistancev1.CustomProfile:
+			if len(user.Grants) == 0 {
+				return fmt.Errorf("custom profile of additional user %s requires grants", user.Username)
+			}
+			for _, grant := range user.Grants {
+				if len(grant.Privileges) == 0 {
+					return fmt.Errorf("grant of additional user %s has no privileges", user.Username)
+				}
+				for _, privilege := range grant.Privileges {
+					if !containsFold(dbclient.TablePrivileges, privilege) {
+						return fmt.Errorf("privilege %s of additional user %s is not supported", privilege, user.Username)
+					}
+				}
+			}
+		default:
+			return fmt.Errorf("unknown privilege profile %s of additional user %s", user.Profile, user.Username)
+		}
+	}
+	return nil
+}
+
+// validateSettings rejects setting names that can not be applied with ALTER ... SET and
+// role settings that would change the identity of the logins
+func validateSettings(dbClaim *persistancev1.DatabaseClaim) error {
+	for name := range dbClaim.Spec.DatabaseSettings {
+		if !dbclient.IsValidSettingName(name) {
+			return fmt.Errorf("invalid database setting name %s", name)
+		}
+	}
+	if len(lowerSettingNames(dbClaim.Spec.DatabaseSettings)) != len(dbClaim.Spec.DatabaseSettings) {
+		return fmt.Errorf("database settings are set more than once with different cases")
+	}
+	for name := range dbClaim.Spec.RoleSettings {
+		if !dbclient.IsValidSettingName(name) {
+			return fmt.Errorf("invalid role setting name %s", name)
+		}
+		if strings.EqualFold(name, "role") || strings.EqualFold(name, "session_authorization") {
+			return fmt.Errorf("role setting %s is managed by db-controller", name)
+		}
+	}
+	if len(lowerSettingNames(dbClaim.Spec.RoleSettings)) != len(dbClaim.Spec.RoleSettings) {
+		return fmt.Errorf("role settings are set more than once with different cases")
+	}
+	if dbClaim.Spec.ConnectionLimit != nil && *dbClaim.Spec.ConnectionLimit < -1 {
+		return fmt.Errorf("invalid connectionLimit %d", *dbClaim.Spec.ConnectionLimit)
+	}
+	return nil
+}
+
+func (r *DatabaseClaimReconciler) manageUser(dbClient dbclient.Client, dbClaim *persistancev1.DatabaseClaim,
+	status *persistancev1.Status, dbName string, baseUsername string) error {
+	// baseUsername := dbClaim.Spec.Username
+	dbu := r.newDBUser(baseUsername)
+
+	// create role
+	_, err := dbClient.CreateGroup(dbName, baseUsername)
+	if err != nil {
+		return err
+	}
+
+	if err := r.rotateUser(dbClient, status, baseUsername); err != nil {
+		return err
+	}
+
+	// the group role holds the privileges, the logins only inherit them
+	err = r.repairRoleDrift(dbClient, dbClaim, baseUsername, dbclient.RoleState{
+		SuperUser: r.Input.EnableSuperUser,
+		Database:  dbName,
+	})
+	if err != nil {
+		return err
+	}
+	for _, login := range dbu.Users() {
+		err = r.repairRoleDrift(dbClient, dbClaim, login, dbclient.RoleState{
+			CreateRole:  r.Input.EnableSuperUser,
+			Replication: r.Input.EnableReplicationRole,
+			MemberOf:    []string{baseUsername},
+			SetRole:     baseUsername,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// repairRoleDrift brings the attributes, memberships and grants of rolename back to
+// desired and reports the repaired drift as an event of the claim
+func (r *DatabaseClaimReconciler) repairRoleDrift(dbClient dbclient.Client, dbClaim *persistancev1.DatabaseClaim,
+	rolename string, desired dbclient.RoleState) error {
+	repaired, err := dbClient.RepairRoleDrift(rolename, desired)
+	if len(repaired) > 0 {
+		r.Log.WithValues("func", "repairRoleDrift").Info("repaired role drift", "role", rolename, "drift", repaired)
+		r.Recorder.Event(dbClaim, corev1.EventTypeNormal, "RoleDriftRepaired",
+			fmt.Sprintf("role %s: %s", rolename, strings.Join(repaired, ", ")))
+	}
+	if err != nil {
+		r.Recorder.Event(dbClaim, corev1.EventTypeWarning, "RoleDriftRepairFailed", err.Error())
+	}
+	return err
 }
 
-func (r *DatabaseClaimReconciler) manageUser(dbClient dbclient.Client, status *persistancev1.Status, dbName string, baseUsername string) error {
-	logr := r.Log.WithValues("func", "manageUser")
+// rotateUser renames the logins of status when baseUsername changed and rotates the
+// password of the next login once the rotation time elapsed. The new password is
+// reported in TempSecret.
+func (r *DatabaseClaimReconciler) rotateUser(dbClient dbclient.Client, status *persistancev1.Status, baseUsername string) error {
+	logr := r.Log.WithValues("func", "rotateUser")
 
-	// baseUsername := dbClaim.Spec.Username
-	dbu := dbuser.NewDBUser(baseUsername)
+	dbu := r.newDBUser(baseUsername)
 	rotationTime := r.getPasswordRotationTime()
 
-	// create role
-	_, err := dbClient.CreateGroup(dbName, baseUsername)
-	if err != nil {
-		return err
-	}
-
 	if dbu.IsUserChanged(*status) {
 		oldUsername := dbu.TrimUserSuffix(status.ConnectionInfo.Username)
 		if err := dbClient.RenameUser(oldUsername, baseUsername); err != nil {
 			return err
 		}
-		// updating user a
-		userPassword, err := r.generatePassword()
-		if err != nil {
-			return err
-		}
-		if err := dbClient.UpdateUser(oldUsername+dbuser.SuffixA, dbu.GetUserA(), baseUsername, userPassword); err != nil {
-			return err
-		}
-		r.updateUserStatus(status, dbu.GetUserA(), userPassword)
-		// updating user b
-		userPassword, err = r.generatePassword()
-		if err != nil {
-			return err
-		}
-		if err := dbClient.UpdateUser(oldUsername+dbuser.SuffixB, dbu.GetUserB(), baseUsername, userPassword); err != nil {
-			return err
+		oldLogins := r.newDBUser(oldUsername).Users()
+		for i, username := range dbu.Users() {
+			userPassword, err := r.generatePassword()
+			if err != nil {
+				return err
+			}
+			if err := dbClient.UpdateUser(oldLogins[i], username, baseUsername, userPassword); err != nil {
+				return err
+			}
+			if i == 0 {
+				r.updateUserStatus(status, username, userPassword)
+			}
 		}
+		// the renamed logins start a new rotation history
+		status.Logins = nil
 	}
 
+	rotated := ""
 	if status.UserUpdatedAt == nil || time.Since(status.UserUpdatedAt.Time) > rotationTime {
 		logr.Info("rotating users")
 
@@ -1201,34 +2936,79 @@ func (r *DatabaseClaimReconciler) manageUser(dbClient dbclient.Client, status *p
 			if err := dbClient.UpdatePassword(nextUser, userPassword); err != nil {
 				return err
 			}
+			// the login was disabled when its grace period expired
+			if err := dbClient.SetLogin(nextUser, true); err != nil {
+				return err
+			}
 		}
 
 		r.updateUserStatus(status, nextUser, userPassword)
+		rotated = nextUser
 	}
-	err = dbClient.ManageSuperUserRole(baseUsername, r.Input.EnableSuperUser)
-	if err != nil {
-		return err
-	}
-	err = dbClient.ManageCreateRole(status.ConnectionInfo.Username, r.Input.EnableSuperUser)
-	if err != nil {
-		return err
-	}
-	err = dbClient.ManageCreateRole(dbu.NextUser(status.ConnectionInfo.Username), r.Input.EnableSuperUser)
-	if err != nil {
-		return err
-	}
-	err = dbClient.ManageReplicationRole(status.ConnectionInfo.Username, r.Input.EnableReplicationRole)
-	if err != nil {
-		return err
-	}
-	err = dbClient.ManageReplicationRole(dbu.NextUser(status.ConnectionInfo.Username), r.Input.EnableReplicationRole)
-	if err != nil {
-		return err
+	return r.manageLogins(dbClient, status, dbu, rotated)
+}
+
+// manageLogins reports the credential generation of every login of dbu in status and
+// denies login to the ones whose grace period expired. The grace period of a login
+// starts when the login of the following generation replaced it.
+func (r *DatabaseClaimReconciler) manageLogins(dbClient dbclient.Client, status *persistancev1.Status,
+	dbu dbuser.DBUser, rotated string) error {
+
+	current := status.ConnectionInfo.Username
+	if dbu.Generation(current, current) < 0 {
+		// the number of generations was reduced, the current login is replaced on the next rotation
+		return nil
+	}
+	previous := map[string]persistancev1.LoginStatus{}
+	for _, l := range status.Logins {
+		previous[l.Username] = l
+	}
+	if rotated != "" {
+		previous[rotated] = persistancev1.LoginStatus{RotatedAt: status.UserUpdatedAt}
+	}
+	if previous[current].RotatedAt == nil {
+		previous[current] = persistancev1.LoginStatus{RotatedAt: status.UserUpdatedAt}
+	}
+
+	// indexed by generation
+	byGeneration := make([]persistancev1.LoginStatus, len(dbu.Users()))
+	for _, username := range dbu.Users() {
+		g := dbu.Generation(current, username)
+		byGeneration[g] = persistancev1.LoginStatus{
+			Username:   username,
+			Generation: g,
+			RotatedAt:  previous[username].RotatedAt,
+			Disabled:   previous[username].Disabled,
+		}
 	}
 
+	gracePeriod := r.getRotationGracePeriod()
+	logins := make([]persistancev1.LoginStatus, 0, len(byGeneration))
+	for g, login := range byGeneration {
+		// the login was never created
+		if login.RotatedAt == nil {
+			continue
+		}
+		if g > 0 && gracePeriod > 0 && !login.Disabled {
+			replacedAt := byGeneration[g-1].RotatedAt
+			if replacedAt != nil && time.Since(replacedAt.Time) > gracePeriod {
+				r.Log.Info("grace period of login expired", "login", login.Username, "generation", g)
+				if err := dbClient.SetLogin(login.Username, false); err != nil {
+					return err
+				}
+				login.Disabled = true
+			}
+		}
+		logins = append(logins, login)
+	}
+	status.Logins = logins
 	return nil
 }
 
+func (r *DatabaseClaimReconciler) newDBUser(baseUsername string) dbuser.DBUser {
+	return dbuser.NewDBUserWithGenerations(baseUsername, r.getRotationGenerations())
+}
+
 func (r *DatabaseClaimReconciler) configureBackupPolicy(backupPolicy string, tags []persistancev1.Tag) []persistancev1.Tag {
 
 	for _, tag := range tags {
@@ -1271,6 +3051,9 @@ func (r *DatabaseClaimReconciler) manageMasterPassword(ctx context.Context, secr
 			ObjectMeta: metav1.ObjectMeta{
 				Namespace: secret.SecretReference.Namespace,
 				Name:      secret.SecretReference.Name,
+				Annotations: map[string]string{
+					masterRotatedAtAnnotation: time.Now().UTC().Format(time.RFC3339),
+				},
 			},
 			Data: map[string][]byte{
 				secret.Key: []byte(password),
@@ -1283,6 +3066,147 @@ func (r *DatabaseClaimReconciler) manageMasterPassword(ctx context.Context, secr
 	return nil
 }
 
+// manageMasterPasswordRotation rotates the master password of a cloud host managed by
+// the controller once it is older than masterPasswordRotationPeriod. The new password
+// is written to the master secret referenced by the crossplane resource, which applies
+// it to the host. When the host accepts it the connection secret is updated, when it
+// is not accepted within masterPasswordVerifyTimeout the previous password is restored.
+// r.Input.MasterConnInfo holds the password to use for the rest of the reconcile.
+func (r *DatabaseClaimReconciler) manageMasterPasswordRotation(ctx context.Context, dbClaim *persistancev1.DatabaseClaim) error {
+	period := r.getMasterPasswordRotationPeriod()
+	if period == 0 {
+		return nil
+	}
+	logr := r.Log.WithValues("databaseclaim", dbClaim.Namespace+"/"+dbClaim.Name, "func", "manageMasterPasswordRotation")
+
+	serviceNS, err := getServiceNamespace()
+	if err != nil {
+		return err
+	}
+	masterSecret := &corev1.Secret{}
+	err = r.Client.Get(ctx, client.ObjectKey{
+		Namespace: serviceNS,
+		Name:      r.Input.DbHostIdentifier + masterSecretSuffix,
+	}, masterSecret)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			logr.Info("master secret not found, master password is not rotated")
+			return nil
+		}
+		return err
+	}
+
+	if masterSecret.Data == nil {
+		masterSecret.Data = map[string][]byte{}
+	}
+	if masterSecret.Annotations == nil {
+		masterSecret.Annotations = map[string]string{}
+	}
+	if _, pending := masterSecret.Data[masterPreviousPasswordKey]; !pending {
+		if time.Since(masterRotatedAt(masterSecret)) < period {
+			return nil
+		}
+		return r.startMasterPasswordRotation(ctx, dbClaim, masterSecret)
+	}
+
+	password := string(masterSecret.Data[masterPasswordKey])
+	pingCtx, cancel := context.WithTimeout(ctx, masterVerifyPingTimeout)
+	defer cancel()
+	verifyErr := dbclient.Ping(pingCtx, dbclient.PostgresURI(r.Input.MasterConnInfo.Host, r.Input.MasterConnInfo.Port,
+		r.Input.MasterConnInfo.Username, password, "postgres", r.Input.MasterConnInfo.SSLMode))
+	if verifyErr == nil {
+		return r.completeMasterPasswordRotation(ctx, dbClaim, masterSecret)
+	}
+	started, _ := time.Parse(time.RFC3339, masterSecret.Annotations[masterRotationStartedAnnotation])
+	if time.Since(started) < r.getMasterPasswordVerifyTimeout() {
+		logr.Info("rotated master password is not accepted yet", "host", r.Input.MasterConnInfo.Host, "error", verifyErr.Error())
+		return nil
+	}
+	return r.rollbackMasterPasswordRotation(ctx, dbClaim, masterSecret, verifyErr)
+}
+
+// masterRotatedAt returns when the password of masterSecret was last set
+func masterRotatedAt(masterSecret *corev1.Secret) time.Time {
+	if rotatedAt, err := time.Parse(time.RFC3339, masterSecret.Annotations[masterRotatedAtAnnotation]); err == nil {
+		return rotatedAt
+	}
+	return masterSecret.CreationTimestamp.Time
+}
+
+func (r *DatabaseClaimReconciler) startMasterPasswordRotation(ctx context.Context, dbClaim *persistancev1.DatabaseClaim,
+	masterSecret *corev1.Secret) error {
+	password, err := generateMasterPassword()
+	if err != nil {
+		return err
+	}
+	masterSecret.Data[masterPreviousPasswordKey] = masterSecret.Data[masterPasswordKey]
+	masterSecret.Data[masterPasswordKey] = []byte(password)
+	masterSecret.Annotations[masterRotationStartedAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	if err := r.Client.Update(ctx, masterSecret); err != nil {
+		metrics.MasterPasswordRotateErrors.WithLabelValues("update error").Inc()
+		return err
+	}
+	r.Log.Info("master password rotation started", "secret", masterSecret.Name)
+	r.Recorder.Event(dbClaim, corev1.EventTypeNormal, "MasterPasswordRotationStarted",
+		fmt.Sprintf("new master password written to secret %s", masterSecret.Name))
+	return nil
+}
+
+func (r *DatabaseClaimReconciler) completeMasterPasswordRotation(ctx context.Context, dbClaim *persistancev1.DatabaseClaim,
+	masterSecret *corev1.Secret) error {
+	password := masterSecret.Data[masterPasswordKey]
+
+	connSecret := &corev1.Secret{}
+	if err := r.Client.Get(ctx, client.ObjectKey{
+		Namespace: masterSecret.Namespace,
+		Name:      r.Input.DbHostIdentifier,
+	}, connSecret); err != nil {
+		return err
+	}
+	if connSecret.Data == nil {
+		connSecret.Data = map[string][]byte{}
+	}
+	connSecret.Data["password"] = password
+	if err := r.Client.Update(ctx, connSecret); err != nil {
+		metrics.MasterPasswordRotateErrors.WithLabelValues("update error").Inc()
+		return err
+	}
+
+	delete(masterSecret.Data, masterPreviousPasswordKey)
+	delete(masterSecret.Annotations, masterRotationStartedAnnotation)
+	masterSecret.Annotations[masterRotatedAtAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	if err := r.Client.Update(ctx, masterSecret); err != nil {
+		metrics.MasterPasswordRotateErrors.WithLabelValues("update error").Inc()
+		return err
+	}
+	r.Input.MasterConnInfo.Password = string(password)
+	metrics.MasterPasswordRotated.Inc()
+	r.Log.Info("master password rotated", "host", r.Input.MasterConnInfo.Host)
+	r.Recorder.Event(dbClaim, corev1.EventTypeNormal, "MasterPasswordRotated",
+		fmt.Sprintf("host %s accepts the new master password", r.Input.MasterConnInfo.Host))
+	return nil
+}
+
+// rollbackMasterPasswordRotation restores the previous password in masterSecret, the
+// rotation is retried after a rotation period
+func (r *DatabaseClaimReconciler) rollbackMasterPasswordRotation(ctx context.Context, dbClaim *persistancev1.DatabaseClaim,
+	masterSecret *corev1.Secret, verifyErr error) error {
+	masterSecret.Data[masterPasswordKey] = masterSecret.Data[masterPreviousPasswordKey]
+	delete(masterSecret.Data, masterPreviousPasswordKey)
+	delete(masterSecret.Annotations, masterRotationStartedAnnotation)
+	masterSecret.Annotations[masterRotatedAtAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	if err := r.Client.Update(ctx, masterSecret); err != nil {
+		metrics.MasterPasswordRotateErrors.WithLabelValues("update error").Inc()
+		return err
+	}
+	metrics.MasterPasswordRotateErrors.WithLabelValues("rolled back").Inc()
+	r.Log.Error(verifyErr, "rotated master password was not accepted, previous password restored", "host", r.Input.MasterConnInfo.Host)
+	r.Recorder.Event(dbClaim, corev1.EventTypeWarning, "MasterPasswordRotationFailed",
+		fmt.Sprintf("host %s did not accept the new master password within %s, previous password restored: %s",
+			r.Input.MasterConnInfo.Host, r.getMasterPasswordVerifyTimeout(), verifyErr))
+	return nil
+}
+
 func (r *DatabaseClaimReconciler) manageDBCluster(ctx context.Context, dbHostName string,
 	dbClaim *persistancev1.DatabaseClaim) (bool, error) {
 
@@ -1398,6 +3322,8 @@ func (r *DatabaseClaimReconciler) manageDBCluster(ctx context.Context, dbHostNam
 		return false, err
 	}
 
+	r.observeClusterParameterApplyStatus(dbClaim, dbCluster)
+
 	return r.isResourceReady(dbCluster.Status.ResourceStatus), nil
 }
 
@@ -1530,6 +3456,7 @@ func (r *DatabaseClaimReconciler) managePostgresDBInstance(ctx context.Context,
 	if err != nil {
 		return false, err
 	}
+	r.observeParameterApplyStatus(dbClaim, dbInstance)
 	return r.isResourceReady(dbInstance.Status.ResourceStatus), nil
 }
 
@@ -1613,6 +3540,7 @@ func (r *DatabaseClaimReconciler) manageAuroraDBInstance(ctx context.Context, db
 	if err != nil {
 		return false, err
 	}
+	r.observeParameterApplyStatus(dbClaim, dbInstance)
 
 	return r.isResourceReady(dbInstance.Status.ResourceStatus), nil
 }
@@ -1621,8 +3549,8 @@ func (r *DatabaseClaimReconciler) managePostgresParamGroup(ctx context.Context,
 
 	logical := "rds.logical_replication"
 	one := "1"
-	immediate := "immediate"
-	reboot := "pending-reboot"
+	immediate := applyMethodImmediate
+	reboot := applyMethodPendingReboot
 	forceSsl := "rds.force_ssl"
 	transactionTimeout := "idle_in_transaction_session_timeout"
 	transactionTimeoutValue := "300000"
@@ -1638,6 +3566,29 @@ func (r *DatabaseClaimReconciler) managePostgresParamGroup(ctx context.Context,
 		Name: r.getProviderConfig(),
 	}
 
+	parameters := r.mergeParameters([]crossplanerds.CustomParameter{
+		{ParameterName: &logical,
+			ParameterValue: &one,
+			ApplyMethod:    &reboot,
+		},
+		{ParameterName: &forceSsl,
+			ParameterValue: &one,
+			ApplyMethod:    &immediate,
+		},
+		{ParameterName: &transactionTimeout,
+			ParameterValue: &transactionTimeoutValue,
+			ApplyMethod:    &immediate,
+		},
+		{ParameterName: &sharedLib,
+			ParameterValue: &sharedLibValue,
+			ApplyMethod:    &reboot,
+		},
+		{ParameterName: &cron,
+			ParameterValue: &cronValue,
+			ApplyMethod:    &reboot,
+		},
+	}, dbClaim.Spec.Parameters)
+
 	dbParamGroup := &crossplanerds.DBParameterGroup{}
 
 	err := r.Client.Get(ctx, client.ObjectKey{
@@ -1658,28 +3609,7 @@ func (r *DatabaseClaimReconciler) managePostgresParamGroup(ctx context.Context,
 								Engine:        params.Engine,
 								EngineVersion: &params.EngineVersion,
 							},
-							Parameters: []crossplanerds.CustomParameter{
-								{ParameterName: &logical,
-									ParameterValue: &one,
-									ApplyMethod:    &reboot,
-								},
-								{ParameterName: &forceSsl,
-									ParameterValue: &one,
-									ApplyMethod:    &immediate,
-								},
-								{ParameterName: &transactionTimeout,
-									ParameterValue: &transactionTimeoutValue,
-									ApplyMethod:    &immediate,
-								},
-								{ParameterName: &sharedLib,
-									ParameterValue: &sharedLibValue,
-									ApplyMethod:    &reboot,
-								},
-								{ParameterName: &cron,
-									ParameterValue: &cronValue,
-									ApplyMethod:    &reboot,
-								},
-							},
+							Parameters: parameters,
 						},
 					},
 					ResourceSpec: xpv1.ResourceSpec{
@@ -1694,18 +3624,26 @@ func (r *DatabaseClaimReconciler) managePostgresParamGroup(ctx context.Context,
 			if err != nil {
 				return pgName, err
 			}
-
+			r.updateParameterGroupStatus(&dbClaim.Status.ParameterGroup, pgName, dbClaim.Spec.Parameters, false)
+			return pgName, nil
 		} else {
 			//not errors.IsNotFound(err) {
 			return pgName, err
 		}
 	}
+
+	rebootRequired, err := r.updateParameterGroup(ctx, dbParamGroup, &dbParamGroup.Spec.ForProvider.Parameters, parameters)
+	if err != nil {
+		return pgName, err
+	}
+	r.updateParameterGroupStatus(&dbClaim.Status.ParameterGroup, pgName, dbClaim.Spec.Parameters, rebootRequired)
 	return pgName, nil
 }
+
 func (r *DatabaseClaimReconciler) manageAuroraPostgresParamGroup(ctx context.Context, dbClaim *persistancev1.DatabaseClaim) (string, error) {
 
-	immediate := "immediate"
-	reboot := "pending-reboot"
+	immediate := applyMethodImmediate
+	reboot := applyMethodPendingReboot
 	transactionTimeout := "idle_in_transaction_session_timeout"
 	transactionTimeoutValue := "300000"
 	params := &r.Input.HostParams
@@ -1720,6 +3658,22 @@ func (r *DatabaseClaimReconciler) manageAuroraPostgresParamGroup(ctx context.Con
 		Name: r.getProviderConfig(),
 	}
 
+	_, instanceParameters := r.splitParameters(dbClaim.Spec.Parameters)
+	parameters := r.mergeParameters([]crossplanerds.CustomParameter{
+		{ParameterName: &transactionTimeout,
+			ParameterValue: &transactionTimeoutValue,
+			ApplyMethod:    &immediate,
+		},
+		{ParameterName: &sharedLib,
+			ParameterValue: &sharedLibValue,
+			ApplyMethod:    &reboot,
+		},
+		{ParameterName: &cron,
+			ParameterValue: &cronValue,
+			ApplyMethod:    &reboot,
+		},
+	}, instanceParameters)
+
 	dbParamGroup := &crossplanerds.DBParameterGroup{}
 
 	err := r.Client.Get(ctx, client.ObjectKey{
@@ -1740,20 +3694,7 @@ func (r *DatabaseClaimReconciler) manageAuroraPostgresParamGroup(ctx context.Con
 								Engine:        params.Engine,
 								EngineVersion: &params.EngineVersion,
 							},
-							Parameters: []crossplanerds.CustomParameter{
-								{ParameterName: &transactionTimeout,
-									ParameterValue: &transactionTimeoutValue,
-									ApplyMethod:    &immediate,
-								},
-								{ParameterName: &sharedLib,
-									ParameterValue: &sharedLibValue,
-									ApplyMethod:    &reboot,
-								},
-								{ParameterName: &cron,
-									ParameterValue: &cronValue,
-									ApplyMethod:    &reboot,
-								},
-							},
+							Parameters: parameters,
 						},
 					},
 					ResourceSpec: xpv1.ResourceSpec{
@@ -1768,12 +3709,19 @@ func (r *DatabaseClaimReconciler) manageAuroraPostgresParamGroup(ctx context.Con
 			if err != nil {
 				return pgName, err
 			}
-
+			r.updateParameterGroupStatus(&dbClaim.Status.ParameterGroup, pgName, instanceParameters, false)
+			return pgName, nil
 		} else {
 			//not errors.IsNotFound(err) {
 			return pgName, err
 		}
 	}
+
+	rebootRequired, err := r.updateParameterGroup(ctx, dbParamGroup, &dbParamGroup.Spec.ForProvider.Parameters, parameters)
+	if err != nil {
+		return pgName, err
+	}
+	r.updateParameterGroupStatus(&dbClaim.Status.ParameterGroup, pgName, instanceParameters, rebootRequired)
 	return pgName, nil
 }
 
@@ -1781,8 +3729,8 @@ func (r *DatabaseClaimReconciler) manageClusterParamGroup(ctx context.Context, d
 
 	logical := "rds.logical_replication"
 	one := "1"
-	immediate := "immediate"
-	reboot := "pending-reboot"
+	immediate := applyMethodImmediate
+	reboot := applyMethodPendingReboot
 	forceSsl := "rds.force_ssl"
 	transactionTimeout := "idle_in_transaction_session_timeout"
 	transactionTimeoutValue := "300000"
@@ -1798,6 +3746,30 @@ func (r *DatabaseClaimReconciler) manageClusterParamGroup(ctx context.Context, d
 		Name: r.getProviderConfig(),
 	}
 
+	clusterParameters, _ := r.splitParameters(dbClaim.Spec.Parameters)
+	parameters := r.mergeParameters([]crossplanerds.CustomParameter{
+		{ParameterName: &logical,
+			ParameterValue: &one,
+			ApplyMethod:    &reboot,
+		},
+		{ParameterName: &forceSsl,
+			ParameterValue: &one,
+			ApplyMethod:    &immediate,
+		},
+		{ParameterName: &transactionTimeout,
+			ParameterValue: &transactionTimeoutValue,
+			ApplyMethod:    &immediate,
+		},
+		{ParameterName: &sharedLib,
+			ParameterValue: &sharedLibValue,
+			ApplyMethod:    &reboot,
+		},
+		{ParameterName: &cron,
+			ParameterValue: &cronValue,
+			ApplyMethod:    &reboot,
+		},
+	}, clusterParameters)
+
 	dbParamGroup := &crossplanerds.DBClusterParameterGroup{}
 
 	err := r.Client.Get(ctx, client.ObjectKey{
@@ -1818,28 +3790,7 @@ func (r *DatabaseClaimReconciler) manageClusterParamGroup(ctx context.Context, d
 								Engine:        params.Engine,
 								EngineVersion: &params.EngineVersion,
 							},
-							Parameters: []crossplanerds.CustomParameter{
-								{ParameterName: &logical,
-									ParameterValue: &one,
-									ApplyMethod:    &reboot,
-								},
-								{ParameterName: &forceSsl,
-									ParameterValue: &one,
-									ApplyMethod:    &immediate,
-								},
-								{ParameterName: &transactionTimeout,
-									ParameterValue: &transactionTimeoutValue,
-									ApplyMethod:    &immediate,
-								},
-								{ParameterName: &sharedLib,
-									ParameterValue: &sharedLibValue,
-									ApplyMethod:    &reboot,
-								},
-								{ParameterName: &cron,
-									ParameterValue: &cronValue,
-									ApplyMethod:    &reboot,
-								},
-							},
+							Parameters: parameters,
 						},
 					},
 					ResourceSpec: xpv1.ResourceSpec{
@@ -1854,15 +3805,174 @@ func (r *DatabaseClaimReconciler) manageClusterParamGroup(ctx context.Context, d
 			if err != nil {
 				return pgName, err
 			}
-
+			r.updateParameterGroupStatus(&dbClaim.Status.ClusterParameterGroup, pgName, clusterParameters, false)
+			return pgName, nil
 		} else {
 			//not errors.IsNotFound(err) {
 			return pgName, err
 		}
 	}
+
+	rebootRequired, err := r.updateParameterGroup(ctx, dbParamGroup, &dbParamGroup.Spec.ForProvider.Parameters, parameters)
+	if err != nil {
+		return pgName, err
+	}
+	r.updateParameterGroupStatus(&dbClaim.Status.ClusterParameterGroup, pgName, clusterParameters, rebootRequired)
 	return pgName, nil
 }
 
+// mergeParameters applies the claim parameters on top of the controller defaults.
+// Parameter names are matched ignoring case, as PostgreSQL does. Overridden defaults
+// keep their name and apply method, new parameters are applied immediately unless
+// they are listed in parameterGroup::pendingRebootParameters.
+func (r *DatabaseClaimReconciler) mergeParameters(defaults []crossplanerds.CustomParameter,
+	overrides map[string]string) []crossplanerds.CustomParameter {
+
+	merged := make([]crossplanerds.CustomParameter, 0, len(defaults)+len(overrides))
+	seen := map[string]bool{}
+	for _, p := range defaults {
+		for name, value := range overrides {
+			if strings.EqualFold(name, *p.ParameterName) {
+				value := value
+				p.ParameterValue = &value
+				seen[name] = true
+			}
+		}
+		merged = append(merged, p)
+	}
+
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		name := name
+		value := overrides[name]
+		applyMethod := applyMethodImmediate
+		if containsFold(r.Config.GetStringSlice("parameterGroup::pendingRebootParameters"), name) {
+			applyMethod = applyMethodPendingReboot
+		}
+		merged = append(merged, crossplanerds.CustomParameter{
+			ParameterName:  &name,
+			ParameterValue: &value,
+			ApplyMethod:    &applyMethod,
+		})
+	}
+	return merged
+}
+
+// splitParameters splits the claim parameters of an aurora-postgresql host between its
+// cluster parameter group, for the parameters listed in parameterGroup::clusterParameters,
+// and the parameter group of its instances, for the others
+func (r *DatabaseClaimReconciler) splitParameters(parameters map[string]string) (cluster, instance map[string]string) {
+	clusterNames := r.Config.GetStringSlice("parameterGroup::clusterParameters")
+	for name, value := range parameters {
+		if containsFold(clusterNames, name) {
+			if cluster == nil {
+				cluster = map[string]string{}
+			}
+			cluster[name] = value
+			continue
+		}
+		if instance == nil {
+			instance = map[string]string{}
+		}
+		instance[name] = value
+	}
+	return cluster, instance
+}
+
+// validateParameters checks the claim parameters against the allowed and denied
+// parameter lists of the controller config. An empty allowed list permits every
+// parameter that is not denied.
+func (r *DatabaseClaimReconciler) validateParameters(parameters map[string]string) error {
+	allowed := r.Config.GetStringSlice("parameterGroup::allowedParameters")
+	denied := r.Config.GetStringSlice("parameterGroup::deniedParameters")
+
+	names := make([]string, 0, len(parameters))
+	for name := range parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if containsFold(denied, name) {
+			return fmt.Errorf("parameter %s is denied by db-controller configuration", name)
+		}
+		if len(allowed) > 0 && !containsFold(allowed, name) {
+			return fmt.Errorf("parameter %s is not allowed by db-controller configuration", name)
+		}
+	}
+	return nil
+}
+
+// containsFold reports whether name is in list, ignoring case
+func containsFold(list []string, name string) bool {
+	for _, p := range list {
+		if strings.EqualFold(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString reports whether list has name
+func containsString(list []string, name string) bool {
+	for _, p := range list {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isRebootRequired reports whether a pending-reboot parameter in desired differs from current
+func isRebootRequired(current, desired []crossplanerds.CustomParameter) bool {
+	values := map[string]string{}
+	for _, p := range current {
+		if p.ParameterName != nil && p.ParameterValue != nil {
+			values[*p.ParameterName] = *p.ParameterValue
+		}
+	}
+	for _, p := range desired {
+		if *p.ApplyMethod != applyMethodPendingReboot {
+			continue
+		}
+		if value, ok := values[*p.ParameterName]; !ok || value != *p.ParameterValue {
+			return true
+		}
+	}
+	return false
+}
+
+// updateParameterGroupStatus records in group the claim parameters applied on the
+// parameter group pgName
+func (r *DatabaseClaimReconciler) updateParameterGroupStatus(group **persistancev1.ParameterGroupStatus, pgName string,
+	parameters map[string]string, rebootRequired bool) {
+	status := *group
+	if status == nil || status.Name != pgName {
+		status = &persistancev1.ParameterGroupStatus{Name: pgName}
+		*group = status
+	}
+	if rebootRequired {
+		status.PendingReboot = true
+		r.Input.ParameterRebootRequired = true
+	}
+	if !reflect.DeepEqual(status.Parameters, parameters) || status.UpdatedAt == nil {
+		timeNow := metav1.Now()
+		status.Parameters = nil
+		for name, value := range parameters {
+			if status.Parameters == nil {
+				status.Parameters = map[string]string{}
+			}
+			status.Parameters[name] = value
+		}
+		status.UpdatedAt = &timeNow
+	}
+}
+
 func (r *DatabaseClaimReconciler) deleteCloudDatabase(dbHostName string, ctx context.Context) error {
 
 	dbInstance := &crossplanerds.DBInstance{}
@@ -2037,6 +4147,74 @@ func (r *DatabaseClaimReconciler) updateDBCluster(ctx context.Context, dbClaim *
 
 	return true, nil
 }
+
+// updateParameterGroup sets the parameters of the DBParameterGroup or
+// DBClusterParameterGroup obj, current points to its parameters. It reports whether the
+// change requires a reboot.
+func (r *DatabaseClaimReconciler) updateParameterGroup(ctx context.Context, obj client.Object,
+	current *[]crossplanerds.CustomParameter, parameters []crossplanerds.CustomParameter) (bool, error) {
+
+	// Create a patch snapshot from current parameter group
+	patchDBParamGroup := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+
+	rebootRequired := isRebootRequired(*current, parameters)
+	*current = parameters
+
+	// Compute a json patch based on the changed parameter group
+	dbParamGroupPatchData, err := patchDBParamGroup.Data(obj)
+	if err != nil {
+		return false, err
+	}
+	// an empty json patch will be {}, we can assert that no update is required if len == 2
+	if len(dbParamGroupPatchData) <= 2 {
+		return false, nil
+	}
+	r.Log.Info("updating crossplane parameter group resource", "kind", fmt.Sprintf("%T", obj), "name", obj.GetName(), "rebootRequired", rebootRequired)
+	err = r.Client.Patch(ctx, obj, patchDBParamGroup)
+	if err != nil {
+		return false, err
+	}
+
+	return rebootRequired, nil
+}
+
+// observeParameterApplyStatus clears the pending reboot flag once the instance
+// reports its parameter group as in-sync. The observed status lags behind the
+// patch, so it is not trusted in the same reconcile the change was made.
+func (r *DatabaseClaimReconciler) observeParameterApplyStatus(dbClaim *persistancev1.DatabaseClaim, dbInstance *crossplanerds.DBInstance) {
+	status := dbClaim.Status.ParameterGroup
+	if status == nil || !status.PendingReboot || r.Input.ParameterRebootRequired {
+		return
+	}
+	for _, pg := range dbInstance.Status.AtProvider.DBParameterGroups {
+		if pg == nil || pg.DBParameterGroupName == nil || *pg.DBParameterGroupName != status.Name {
+			continue
+		}
+		if pg.ParameterApplyStatus != nil && *pg.ParameterApplyStatus == parameterApplyInSync {
+			status.PendingReboot = false
+		}
+	}
+}
+
+// observeClusterParameterApplyStatus clears the pending reboot flag of the cluster
+// parameter group once every member of the cluster reports it as in-sync
+func (r *DatabaseClaimReconciler) observeClusterParameterApplyStatus(dbClaim *persistancev1.DatabaseClaim, dbCluster *crossplanerds.DBCluster) {
+	status := dbClaim.Status.ClusterParameterGroup
+	if status == nil || !status.PendingReboot || r.Input.ParameterRebootRequired {
+		return
+	}
+	members := dbCluster.Status.AtProvider.DBClusterMembers
+	if len(members) == 0 {
+		return
+	}
+	for _, m := range members {
+		if m == nil || m.DBClusterParameterGroupStatus == nil || *m.DBClusterParameterGroupStatus != parameterApplyInSync {
+			return
+		}
+	}
+	status.PendingReboot = false
+}
+
 func (r *DatabaseClaimReconciler) rerouteTargetSecret(ctx context.Context, sourceDsn string,
 	targetAppConn *persistancev1.DatabaseClaimConnectionInfo, dbClaim *persistancev1.DatabaseClaim) error {
 
@@ -2096,9 +4274,25 @@ func (r *DatabaseClaimReconciler) createOrUpdateSecret(ctx context.Context, dbCl
 
 func (r *DatabaseClaimReconciler) createSecret(ctx context.Context, dbClaim *persistancev1.DatabaseClaim, dsn, dbURI string, connInfo *persistancev1.DatabaseClaimConnectionInfo) error {
 	secretName := dbClaim.Spec.SecretName
-	truePtr := true
 	dsnName := dbClaim.Spec.DSNName
-	secret := &corev1.Secret{
+	secret := newConnectionInfoSecret(dbClaim, secretName, map[string][]byte{
+		dsnName:          []byte(dsn),
+		"uri_" + dsnName: []byte(dbURI),
+		"hostname":       []byte(connInfo.Host),
+		"port":           []byte(connInfo.Port),
+		"database":       []byte(connInfo.DatabaseName),
+		"username":       []byte(connInfo.Username),
+		"password":       []byte(connInfo.Password),
+		"sslmode":        []byte(connInfo.SSLMode),
+	})
+	r.Log.Info("creating connection info secret", "secret", secret.Name, "namespace", secret.Namespace)
+
+	return r.Client.Create(ctx, secret)
+}
+
+func newConnectionInfoSecret(dbClaim *persistancev1.DatabaseClaim, secretName string, data map[string][]byte) *corev1.Secret {
+	truePtr := true
+	return &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: dbClaim.Namespace,
 			Name:      secretName,
@@ -2114,20 +4308,80 @@ func (r *DatabaseClaimReconciler) createSecret(ctx context.Context, dbClaim *per
 				},
 			},
 		},
-		Data: map[string][]byte{
-			dsnName:          []byte(dsn),
-			"uri_" + dsnName: []byte(dbURI),
-			"hostname":       []byte(connInfo.Host),
-			"port":           []byte(connInfo.Port),
-			"database":       []byte(connInfo.DatabaseName),
-			"username":       []byte(connInfo.Username),
-			"password":       []byte(connInfo.Password),
-			"sslmode":        []byte(connInfo.SSLMode),
-		},
+		Data: data,
 	}
-	r.Log.Info("creating connection info secret", "secret", secret.Name, "namespace", secret.Namespace)
+}
 
-	return r.Client.Create(ctx, secret)
+// createOrUpdateSecretKeys writes the connection info of an additional database or user
+// to secretName, every key is prefixed with prefix. Other keys of the secret are kept.
+func (r *DatabaseClaimReconciler) createOrUpdateSecretKeys(ctx context.Context, dbClaim *persistancev1.DatabaseClaim,
+	secretName, prefix string, connInfo *persistancev1.DatabaseClaimConnectionInfo) error {
+
+	switch dbClaim.Spec.Type {
+	case defaultPostgresStr, defaultAuroraPostgresStr:
+	default:
+		return fmt.Errorf("unknown DB type")
+	}
+	dsnName := dbClaim.Spec.DSNName
+	data := map[string][]byte{
+		prefix + dsnName: []byte(dbclient.PostgresConnectionString(connInfo.Host, connInfo.Port, connInfo.Username,
+			connInfo.Password, connInfo.DatabaseName, connInfo.SSLMode)),
+		prefix + "uri_" + dsnName: []byte(dbclient.PostgresURI(connInfo.Host, connInfo.Port, connInfo.Username,
+			connInfo.Password, connInfo.DatabaseName, connInfo.SSLMode)),
+		prefix + "hostname": []byte(connInfo.Host),
+		prefix + "port":     []byte(connInfo.Port),
+		prefix + "database": []byte(connInfo.DatabaseName),
+		prefix + "username": []byte(connInfo.Username),
+		prefix + "password": []byte(connInfo.Password),
+		prefix + "sslmode":  []byte(connInfo.SSLMode),
+	}
+
+	gs := &corev1.Secret{}
+	err := r.Client.Get(ctx, client.ObjectKey{
+		Namespace: dbClaim.Namespace,
+		Name:      secretName,
+	}, gs)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		secret := newConnectionInfoSecret(dbClaim, secretName, data)
+		r.Log.Info("creating connection info secret", "secret", secret.Name, "namespace", secret.Namespace)
+		return r.Client.Create(ctx, secret)
+	}
+	if gs.Data == nil {
+		gs.Data = map[string][]byte{}
+	}
+	changed := false
+	for k, v := range data {
+		if !bytes.Equal(gs.Data[k], v) {
+			gs.Data[k] = v
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	r.Log.Info("updating connection info secret", "secret", gs.Name, "namespace", gs.Namespace, "prefix", prefix)
+	return r.Client.Update(ctx, gs)
+}
+
+// readSecretKey returns the value of key in secretName, empty when the secret or the
+// key does not exist
+func (r *DatabaseClaimReconciler) readSecretKey(ctx context.Context, dbClaim *persistancev1.DatabaseClaim,
+	secretName, key string) (string, error) {
+	gs := &corev1.Secret{}
+	err := r.Client.Get(ctx, client.ObjectKey{
+		Namespace: dbClaim.Namespace,
+		Name:      secretName,
+	}, gs)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return string(gs.Data[key]), nil
 }
 
 func (r *DatabaseClaimReconciler) updateSecret(ctx context.Context, dsnName, dsn, dbURI string, connInfo *persistancev1.DatabaseClaimConnectionInfo, exSecret *corev1.Secret) error {
@@ -2135,6 +4389,7 @@ func (r *DatabaseClaimReconciler) updateSecret(ctx context.Context, dsnName, dsn
 	exSecret.Data["uri_"+dsnName] = []byte(dbURI)
 	exSecret.Data["hostname"] = []byte(connInfo.Host)
 	exSecret.Data["port"] = []byte(connInfo.Port)
+	exSecret.Data["database"] = []byte(connInfo.DatabaseName)
 	exSecret.Data["username"] = []byte(connInfo.Username)
 	exSecret.Data["password"] = []byte(connInfo.Password)
 	exSecret.Data["sslmode"] = []byte(connInfo.SSLMode)
@@ -127,10 +127,11 @@ var _ = BeforeSuite(func() {
 	Expect(err).ToNot(HaveOccurred())
 
 	err = (&DatabaseClaimReconciler{
-		Client: k8sClient,
-		Scheme: k8sManager.GetScheme(),
-		Log:    ctrl.Log.WithName("controllers").WithName("DB-controller"),
-		Config: NewConfig(controllerConfig),
+		Client:   k8sClient,
+		Scheme:   k8sManager.GetScheme(),
+		Log:      ctrl.Log.WithName("controllers").WithName("DB-controller"),
+		Config:   NewConfig(controllerConfig),
+		Recorder: k8sManager.GetEventRecorderFor("databaseClaim-controller"),
 	}).SetupWithManager(k8sManager)
 	Expect(err).ToNot(HaveOccurred())
 
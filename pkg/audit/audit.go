@@ -0,0 +1,167 @@
+// Package audit records the privileged statements the controller runs against
+// database hosts. Records are written to a Sink, statements have their secrets redacted.
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/infobloxopen/db-controller/pkg/metrics"
+	"github.com/infobloxopen/db-controller/pkg/redact"
+)
+
+const (
+	ResultSucceeded = "succeeded"
+	ResultFailed    = "failed"
+)
+
+// Record describes a statement run against a database host
+type Record struct {
+	Time time.Time `json:"time"`
+	// Component running the statement, dbclient or pgctl
+	Component string `json:"component"`
+	// Claim on whose behalf the statement runs as namespace/name, empty when unknown
+	Claim     string `json:"claim,omitempty"`
+	Host      string `json:"host"`
+	Database  string `json:"database"`
+	User      string `json:"user,omitempty"`
+	Statement string `json:"statement"`
+	Result    string `json:"result"`
+	Error     string `json:"error,omitempty"`
+	// Duration of the statement in milliseconds
+	Duration float64 `json:"durationMs"`
+}
+
+// Sink receives audit records, implementations must be safe for concurrent use
+type Sink interface {
+	Write(rec Record) error
+}
+
+// Discard drops every record
+var Discard Sink = discard{}
+
+type discard struct{}
+
+func (discard) Write(Record) error { return nil }
+
+// JSONSink writes records as one JSON object per line
+type JSONSink struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+}
+
+// NewJSONSink returns a sink writing to w, os.Stdout for a log stream
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{w: w, enc: json.NewEncoder(w)}
+}
+
+// NewFileSink returns a sink appending to the file at path, the file is created when missing
+func NewFileSink(path string) (*JSONSink, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("could not open audit log %s: %s", path, err)
+	}
+	return NewJSONSink(f), nil
+}
+
+func (s *JSONSink) Write(rec Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(rec)
+}
+
+// Close closes the underlying writer when it is a closer other than the standard streams
+func (s *JSONSink) Close() error {
+	if s.w == os.Stdout || s.w == os.Stderr {
+		return nil
+	}
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// NewRecord returns the record of a statement run by component for claim on the
+// host and database of dsn, which is either a URL or a list of key=value pairs
+func NewRecord(component, claim, dsn string) Record {
+	rec := Record{Component: component, Claim: claim}
+	if u, err := url.Parse(dsn); err == nil && u.Host != "" {
+		rec.Host = u.Hostname()
+		rec.Database = strings.TrimPrefix(u.Path, "/")
+		if u.User != nil {
+			rec.User = u.User.Username()
+		}
+		return rec
+	}
+	for _, kv := range strings.Fields(dsn) {
+		k, v, _ := strings.Cut(kv, "=")
+		v = strings.Trim(v, "'")
+		switch k {
+		case "host":
+			rec.Host = v
+		case "dbname":
+			rec.Database = v
+		case "user":
+			rec.User = v
+		}
+	}
+	return rec
+}
+
+// Execer runs statements, like a *sql.DB or a *sql.Conn
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Exec runs query on db and writes its record to sink. rec identifies the target,
+// the statement, result and duration are filled in.
+func Exec(sink Sink, rec Record, db *sql.DB, query string, args ...interface{}) (sql.Result, error) {
+	return ExecContext(context.Background(), sink, rec, db, query, args...)
+}
+
+// ExecContext is Exec with a context bounding the statement
+func ExecContext(ctx context.Context, sink Sink, rec Record, db Execer, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	res, err := db.ExecContext(ctx, query, args...)
+	rec.Statement = Statement(query, args)
+	Write(sink, rec, start, err)
+	return res, err
+}
+
+// Write completes rec with the result err of a statement started at start and writes it to sink
+func Write(sink Sink, rec Record, start time.Time, err error) {
+	if sink == nil {
+		return
+	}
+	rec.Time = start.UTC()
+	rec.Duration = float64(time.Since(start).Microseconds()) / 1000
+	rec.Result = ResultSucceeded
+	if err != nil {
+		rec.Result = ResultFailed
+		rec.Error = redact.String(err.Error())
+	}
+	rec.Statement = redact.String(rec.Statement)
+	if err := sink.Write(rec); err != nil {
+		metrics.AuditRecordErrors.WithLabelValues(rec.Component).Inc()
+	}
+}
+
+// Statement renders query with its placeholders replaced by the quoted args and
+// whitespace collapsed, secrets are redacted
+func Statement(query string, args []interface{}) string {
+	for i := len(args); i > 0; i-- {
+		query = strings.ReplaceAll(query, fmt.Sprintf("$%d", i), pq.QuoteLiteral(fmt.Sprint(args[i-1])))
+	}
+	return redact.String(strings.Join(strings.Fields(query), " "))
+}
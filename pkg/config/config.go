@@ -16,6 +16,12 @@ const (
 func NewConfig(logger logr.Logger, configFile string) *viper.Viper {
 	c := viper.NewWithOptions(viper.KeyDelimiter("::"))
 	c.SetDefault("config", "config.yaml")
+	c.SetDefault("dbClientPool::maxOpenConns", 5)
+	c.SetDefault("dbClientPool::maxIdleConns", 2)
+	c.SetDefault("dbClientPool::connMaxIdleTime", 300)
+	c.SetDefault("dbClientPool::healthCheckInterval", 60)
+	c.SetDefault("dbClientPool::idleTimeout", 600)
+	c.SetDefault("auditLog::enabled", false)
 	c.SetConfigFile(configFile)
 	logger.Info("loading config")
 	err := c.ReadInConfig() // Find and read the config file
@@ -2,24 +2,17 @@ package dbclient
 
 import (
 	"context"
+	"database/sql/driver"
 	"fmt"
 	"net/url"
+	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/feature/rds/auth"
+	"github.com/lib/pq"
 )
 
-func awsAuthBuilder(ctx context.Context, cliCfg Config) (string, error) {
-
-	cfg, err := config.LoadDefaultConfig(ctx)
-	if err != nil {
-		return "", err
-	}
-
-	return awsAuthBuilderWithConfig(ctx, cliCfg, cfg)
-}
-
 func awsAuthBuilderWithConfig(ctx context.Context, cliCfg Config, awsCfg aws.Config) (string, error) {
 
 	parsed, err := url.Parse(cliCfg.DSN)
@@ -38,3 +31,35 @@ func awsAuthBuilderWithConfig(ctx context.Context, cliCfg Config, awsCfg aws.Con
 	return parsed.String(), err
 
 }
+
+// iamConnector builds a new IAM auth token for every connection it opens,
+// tokens expire after 15 minutes while pooled connections live longer
+type iamConnector struct {
+	dsn string
+
+	once   sync.Once
+	awsCfg aws.Config
+	err    error
+}
+
+func (c *iamConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	c.once.Do(func() {
+		c.awsCfg, c.err = config.LoadDefaultConfig(ctx)
+	})
+	if c.err != nil {
+		return nil, c.err
+	}
+	dsn, err := awsAuthBuilderWithConfig(ctx, Config{DSN: c.dsn}, c.awsCfg)
+	if err != nil {
+		return nil, err
+	}
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return connector.Connect(ctx)
+}
+
+func (c *iamConnector) Driver() driver.Driver {
+	return &pq.Driver{}
+}
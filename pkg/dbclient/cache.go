@@ -0,0 +1,228 @@
+package dbclient
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/infobloxopen/db-controller/pkg/redact"
+)
+
+// Cache shares clients between reconciles. Clients are keyed by their DSN without
+// the password and replaced when the password changes, which happens when the
+// master credentials are rotated, or when they fail their health check. Clients
+// and the connection pools of their databases are closed once unused for idleTimeout.
+type Cache struct {
+	log                 logr.Logger
+	pool                PoolConfig
+	healthCheckInterval time.Duration
+	idleTimeout         time.Duration
+
+	mu      sync.Mutex
+	clients map[string]*cachedClient
+	// pending are closed once the client of their key is checked or created, the
+	// health check and the creation run without holding mu
+	pending map[string]chan struct{}
+}
+
+type cachedClient struct {
+	*client
+	dsn       string
+	refs      int
+	evicted   bool
+	checkedAt time.Time
+	usedAt    time.Time
+}
+
+// sharedClient is handed out by the cache, it shares the connection pools of the
+// cached client and audits its statements for the claim it was requested for.
+// Closing it releases the cached client.
+type sharedClient struct {
+	*client
+	cache *Cache
+	entry *cachedClient
+	once  sync.Once
+}
+
+func (sc *sharedClient) Close() error {
+	sc.once.Do(func() {
+		sc.cache.release(sc.entry)
+	})
+	return nil
+}
+
+// NewCache returns a cache of clients with the given pool bounds. Cached clients are
+// pinged before they are reused once healthCheckInterval elapsed since their last check.
+// Released clients are closed after idleTimeout, they are kept when it is 0.
+func NewCache(log logr.Logger, pool PoolConfig, healthCheckInterval, idleTimeout time.Duration) *Cache {
+	return &Cache{
+		log:                 redact.Logger(log),
+		pool:                pool,
+		healthCheckInterval: healthCheckInterval,
+		idleTimeout:         idleTimeout,
+		clients:             map[string]*cachedClient{},
+		pending:             map[string]chan struct{}{},
+	}
+}
+
+// Get returns the cached client of cfg.DSN, it is created when missing. The returned
+// client audits to cfg.Audit for cfg.Claim and must be closed when it is not used anymore.
+func (c *Cache) Get(ctx context.Context, cfg Config) (Client, error) {
+	key, err := cacheKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// wait for the check or the creation of the client of key by another Get
+	for {
+		c.mu.Lock()
+		c.evictIdle()
+		done, ok := c.pending[key]
+		if !ok {
+			break
+		}
+		c.mu.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	entry := c.clients[key]
+	if entry != nil && entry.dsn != cfg.DSN {
+		c.log.Info("credentials changed, evicting client", "key", key)
+		c.evict(key, entry)
+		entry = nil
+	}
+	if entry != nil && time.Since(entry.checkedAt) < c.healthCheckInterval {
+		defer c.mu.Unlock()
+		return c.acquire(entry, cfg), nil
+	}
+	done := make(chan struct{})
+	c.pending[key] = done
+	c.mu.Unlock()
+
+	var pingErr error
+	if entry != nil {
+		pingCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		pingErr = entry.DB.PingContext(pingCtx)
+		cancel()
+	}
+	var pc *client
+	if entry == nil || pingErr != nil {
+		cfg.Pool = c.pool
+		pc, err = newPostgresClient(ctx, cfg)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, key)
+	close(done)
+	if entry != nil && !entry.evicted {
+		if pingErr == nil {
+			entry.checkedAt = time.Now()
+			return c.acquire(entry, cfg), nil
+		}
+		c.log.Error(pingErr, "health check failed, evicting client", "key", key)
+		c.evict(key, entry)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if pc == nil {
+		// the client was evicted while it was checked
+		cfg.Pool = c.pool
+		if pc, err = newPostgresClient(ctx, cfg); err != nil {
+			return nil, err
+		}
+	}
+	entry = &cachedClient{client: pc, dsn: cfg.DSN, checkedAt: time.Now()}
+	c.clients[key] = entry
+	return c.acquire(entry, cfg), nil
+}
+
+// acquire returns a client of entry for cfg, mu must be held
+func (c *Cache) acquire(entry *cachedClient, cfg Config) Client {
+	entry.refs++
+	entry.usedAt = time.Now()
+	return &sharedClient{client: entry.client.withAudit(cfg.Audit, cfg.Claim), cache: c, entry: entry}
+}
+
+// evictIdle closes the released clients unused for idleTimeout and the connection
+// pools of the databases of the other released clients unused for idleTimeout, mu
+// must be held
+func (c *Cache) evictIdle() {
+	if c.idleTimeout <= 0 {
+		return
+	}
+	for key, entry := range c.clients {
+		if entry.refs > 0 {
+			continue
+		}
+		if _, ok := c.pending[key]; ok {
+			continue
+		}
+		if time.Since(entry.usedAt) >= c.idleTimeout {
+			c.log.Info("client unused, evicting it", "key", key)
+			c.evict(key, entry)
+			continue
+		}
+		entry.client.closeIdleDBs(c.idleTimeout)
+	}
+}
+
+// Evict closes the cached client of dsn once it is released
+func (c *Cache) Evict(dsn string) {
+	key, err := cacheKey(Config{DSN: dsn})
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.clients[key]; ok {
+		c.evict(key, entry)
+	}
+}
+
+// Close closes every cached client once it is released
+func (c *Cache) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, entry := range c.clients {
+		c.evict(key, entry)
+	}
+}
+
+func (c *Cache) evict(key string, entry *cachedClient) {
+	delete(c.clients, key)
+	entry.evicted = true
+	if entry.refs == 0 {
+		entry.client.Close()
+	}
+}
+
+func (c *Cache) release(entry *cachedClient) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry.refs--
+	entry.usedAt = time.Now()
+	if entry.evicted && entry.refs == 0 {
+		entry.client.Close()
+	}
+}
+
+// cacheKey returns the DSN of cfg without password
+func cacheKey(cfg Config) (string, error) {
+	u, err := url.Parse(cfg.DSN)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid dsn, a postgres:// URL is required")
+	}
+	if u.User != nil {
+		u.User = url.User(u.User.Username())
+	}
+	return fmt.Sprintf("%s iam=%t", u.String(), cfg.UseIAM), nil
+}
@@ -7,12 +7,15 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/lib/pq"
 
+	"github.com/infobloxopen/db-controller/pkg/audit"
 	"github.com/infobloxopen/db-controller/pkg/metrics"
+	"github.com/infobloxopen/db-controller/pkg/redact"
 )
 
 const (
@@ -33,15 +36,157 @@ type client struct {
 	dbURL  string
 	DB     *sql.DB
 	log    logr.Logger
+	useIAM bool
+	pool   PoolConfig
+
+	mu sync.Mutex
+	// dbs are the connection pools of the databases of the host by name, dbUsed
+	// the time they were last requested
+	dbs    map[string]*sql.DB
+	dbUsed map[string]time.Time
+	// plan records the statements instead of executing them, when set
+	plan *plan
+	// audit receives the statements executed on behalf of claim
+	audit audit.Sink
+	claim string
+	// parent owns the connection pools of a client handed out by the cache
+	parent *client
 }
 
+// root returns the client owning the connection pools
+func (p *client) root() *client {
+	if p.parent != nil {
+		return p.parent
+	}
+	return p
+}
+
+// withAudit returns a client sharing the connection pools of p that audits the
+// statements it executes to sink on behalf of claim
+func (p *client) withAudit(sink audit.Sink, claim string) *client {
+	return &client{
+		dbType: p.dbType,
+		dbURL:  p.dbURL,
+		DB:     p.DB,
+		log:    p.log,
+		useIAM: p.useIAM,
+		pool:   p.pool,
+		plan:   p.plan,
+		audit:  sink,
+		claim:  claim,
+		parent: p.root(),
+	}
+}
+
+// getDB returns the connection pool of dbname, the pool is closed with the client
 func (p *client) getDB(dbname string) (*sql.DB, error) {
 	u, err := url.Parse(p.dbURL)
 	if err != nil {
 		return nil, err
 	}
+	root := p.root()
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	if root.dbUsed == nil {
+		root.dbUsed = map[string]time.Time{}
+	}
+	root.dbUsed[dbname] = time.Now()
+	if db, ok := root.dbs[dbname]; ok {
+		return db, nil
+	}
 	u.Path = "/" + dbname
-	return sql.Open("postgres", u.String())
+	if p.isPlanned("database", dbname) {
+		u.Path = "/" + templateDB
+	}
+	db, err := p.openDB(u.String())
+	if err != nil {
+		return nil, err
+	}
+	if root.dbs == nil {
+		root.dbs = map[string]*sql.DB{}
+	}
+	root.dbs[dbname] = db
+	return db, nil
+}
+
+// closeDB closes the connection pool of dbname, if any
+func (p *client) closeDB(dbname string) {
+	root := p.root()
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	if db, ok := root.dbs[dbname]; ok {
+		db.Close()
+		delete(root.dbs, dbname)
+		delete(root.dbUsed, dbname)
+	}
+}
+
+// closeIdleDBs closes the connection pools of the databases not requested for idle
+func (p *client) closeIdleDBs(idle time.Duration) {
+	root := p.root()
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	for name, db := range root.dbs {
+		if time.Since(root.dbUsed[name]) >= idle {
+			db.Close()
+			delete(root.dbs, name)
+			delete(root.dbUsed, name)
+		}
+	}
+}
+
+func (pc *client) dbNameOf(db *sql.DB) string {
+	if db == pc.DB {
+		if u, err := url.Parse(pc.dbURL); err == nil {
+			return strings.TrimPrefix(u.Path, "/")
+		}
+	}
+	root := pc.root()
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	for name, d := range root.dbs {
+		if d == db {
+			return name
+		}
+	}
+	return ""
+}
+
+func (p *client) openDB(dsn string) (*sql.DB, error) {
+	var db *sql.DB
+	if p.useIAM {
+		db = sql.OpenDB(&iamConnector{dsn: dsn})
+	} else {
+		var err error
+		if db, err = sql.Open(PostgresType, dsn); err != nil {
+			return nil, err
+		}
+	}
+	p.pool.apply(db)
+	return db, nil
+}
+
+// PoolConfig bounds the connection pools of a client, zero values keep the database/sql defaults
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxIdleTime time.Duration
+	ConnMaxLifetime time.Duration
+}
+
+func (pc PoolConfig) apply(db *sql.DB) {
+	if pc.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(pc.MaxOpenConns)
+	}
+	if pc.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(pc.MaxIdleConns)
+	}
+	if pc.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(pc.ConnMaxIdleTime)
+	}
+	if pc.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(pc.ConnMaxLifetime)
+	}
 }
 
 type Config struct {
@@ -52,6 +197,15 @@ type Config struct {
 	DSN string
 	// UseIAM bool
 	UseIAM bool // attempt to connect with IAM user provided in DSN
+	// Pool bounds the connection pools of the client
+	Pool PoolConfig
+	// Plan records the statements changing the host instead of executing them,
+	// they are returned by the Statements method of Planner
+	Plan bool
+	// Audit receives a record of every statement changing the host, none when nil
+	Audit audit.Sink
+	// Claim the statements are executed for, as namespace/name
+	Claim string
 }
 
 func New(cfg Config) (Client, error) {
@@ -72,29 +226,37 @@ func DBClientFactory(log logr.Logger, dbType, host, port, user, password, sslmod
 	})
 }
 
-// creates postgres client
+// creates postgres client, with IAM a new auth token is built for every connection
 func newPostgresClient(ctx context.Context, cfg Config) (*client, error) {
-
-	authedDSN := cfg.DSN
-
-	if cfg.UseIAM {
-		var err error
-		authedDSN, err = awsAuthBuilder(ctx, cfg)
-		if err != nil {
-			return nil, err
-		}
+	pc := &client{
+		dbType: PostgresType,
+		log:    redact.Logger(cfg.Log),
+		dbURL:  cfg.DSN,
+		useIAM: cfg.UseIAM,
+		pool:   cfg.Pool,
+		audit:  cfg.Audit,
+		claim:  cfg.Claim,
 	}
-
-	db, err := sql.Open(PostgresType, authedDSN)
+	if cfg.Plan {
+		pc.plan = &plan{created: map[string]bool{}}
+	}
+	db, err := pc.openDB(cfg.DSN)
 	if err != nil {
 		return nil, err
 	}
-	return &client{
-		dbType: PostgresType,
-		DB:     db,
-		log:    cfg.Log,
-		dbURL:  cfg.DSN,
-	}, nil
+	pc.DB = db
+	return pc, nil
+}
+
+// Ping opens a connection to dsn outside of any shared client, it verifies the
+// credentials of dsn are accepted by the host
+func Ping(ctx context.Context, dsn string) error {
+	db, err := sql.Open(PostgresType, dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.PingContext(ctx)
 }
 
 func PostgresConnectionString(host, port, user, password, dbname, sslmode string) string {
@@ -131,11 +293,12 @@ func (pc *client) CreateDatabase(dbName string) (bool, error) {
 	if !exists {
 		pc.log.Info("creating DB:", "database name", dbName)
 		// create the database
-		if _, err := db.Exec(fmt.Sprintf("create database %s", pq.QuoteIdentifier(dbName))); err != nil {
+		if _, err := pc.exec(db, fmt.Sprintf("create database %s", pq.QuoteIdentifier(dbName))); err != nil {
 			pc.log.Error(err, "could not create database")
 			metrics.DBProvisioningErrors.WithLabelValues("create error")
 			return created, err
 		}
+		pc.markPlanned("database", dbName)
 		created = true
 		pc.log.Info("database has been created", "DB", dbName)
 		metrics.DBCreated.Inc()
@@ -152,9 +315,8 @@ func (pc *client) CreateDefaultExtentions(dbName string) error {
 		return err
 	}
 	pc.log.Info("connected to " + dbName)
-	defer db.Close()
 	for _, s := range getDefaulExtensions() {
-		if _, err = db.Exec(fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s", pq.QuoteIdentifier(s))); err != nil {
+		if _, err = pc.exec(db, fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s", pq.QuoteIdentifier(s))); err != nil {
 			pc.log.Error(err, "could not create extension", "database_name", dbName)
 			return fmt.Errorf("could not create extension %s: %s", s, err)
 		}
@@ -185,14 +347,14 @@ func (pc *client) CreateGroup(dbName, rolename string) (bool, error) {
 		grantSchemaPrivileges := `
 			GRANT ALL ON SCHEMA public TO %s;
 		`
-		_, err = pc.DB.Exec(fmt.Sprintf("CREATE ROLE %s WITH NOLOGIN", pq.QuoteIdentifier(rolename)))
+		_, err = pc.exec(pc.DB, fmt.Sprintf("CREATE ROLE %s WITH NOLOGIN", pq.QuoteIdentifier(rolename)))
 		if err != nil {
 			pc.log.Error(err, "could not create role "+rolename)
 			metrics.UsersCreatedErrors.WithLabelValues("create error").Inc()
 			return created, err
 		}
 
-		_, err := pc.DB.Exec(fmt.Sprintf(grantDB, pq.QuoteIdentifier(dbName),
+		_, err := pc.exec(pc.DB, fmt.Sprintf(grantDB, pq.QuoteIdentifier(dbName),
 			pq.QuoteIdentifier(rolename), pq.QuoteIdentifier(rolename),
 			pq.QuoteIdentifier(rolename)))
 		if err != nil {
@@ -206,15 +368,15 @@ func (pc *client) CreateGroup(dbName, rolename string) (bool, error) {
 			pc.log.Error(err, "could not connect to db", "database", dbName)
 			return created, err
 		}
-		defer db.Close()
 		//grant schema privileges
-		_, err = db.Exec(fmt.Sprintf(grantSchemaPrivileges, pq.QuoteIdentifier(rolename)))
+		_, err = pc.exec(db, fmt.Sprintf(grantSchemaPrivileges, pq.QuoteIdentifier(rolename)))
 		if err != nil {
 			pc.log.Error(err, "could not set schema privileges to role "+rolename)
 			metrics.UsersCreatedErrors.WithLabelValues("grant error").Inc()
 			return created, err
 		}
 
+		pc.markPlanned("role", rolename)
 		created = true
 		pc.log.Info("role has been created", "role", rolename)
 		metrics.UsersCreated.Inc()
@@ -227,7 +389,7 @@ func (pc *client) CreateGroup(dbName, rolename string) (bool, error) {
 
 func (pc *client) setGroup(username, rolename string) error {
 	db := pc.DB
-	if _, err := db.Exec(fmt.Sprintf("ALTER ROLE %s SET ROLE TO %s", pq.QuoteIdentifier(username), pq.QuoteIdentifier(rolename))); err != nil {
+	if _, err := pc.exec(db, fmt.Sprintf("ALTER ROLE %s SET ROLE TO %s", pq.QuoteIdentifier(username), pq.QuoteIdentifier(rolename))); err != nil {
 		return err
 	}
 
@@ -250,8 +412,14 @@ func (pc *client) CreateUser(username, rolename, userPassword string) (bool, err
 	if !exists {
 		pc.log.Info("creating a user", "user", username)
 
-		s := fmt.Sprintf("CREATE ROLE %s with encrypted password %s LOGIN IN ROLE %s", pq.QuoteIdentifier(username), pq.QuoteLiteral(userPassword), pq.QuoteIdentifier(rolename))
-		_, err = pc.DB.Exec(s)
+		verifier, err := scramSHA256Verifier(userPassword)
+		if err != nil {
+			pc.log.Error(err, "could not compute password verifier")
+			metrics.UsersCreatedErrors.WithLabelValues("create error").Inc()
+			return created, err
+		}
+		s := fmt.Sprintf("CREATE ROLE %s with encrypted password %s LOGIN IN ROLE %s", pq.QuoteIdentifier(username), pq.QuoteLiteral(verifier), pq.QuoteIdentifier(rolename))
+		_, err = pc.exec(pc.DB, s)
 		if err != nil {
 			pc.log.Error(err, "could not create user "+username)
 			metrics.UsersCreatedErrors.WithLabelValues("create error").Inc()
@@ -265,6 +433,7 @@ func (pc *client) CreateUser(username, rolename, userPassword string) (bool, err
 			return created, err
 		}
 
+		pc.markPlanned("role", username)
 		created = true
 		pc.log.Info("user has been created", "user", username)
 		metrics.UsersCreated.Inc()
@@ -289,7 +458,7 @@ func (pc *client) RenameUser(oldUsername string, newUsername string) error {
 	if exists {
 		pc.log.Info(fmt.Sprintf("renaming user %v to %v", oldUsername, newUsername))
 
-		_, err = db.Exec(fmt.Sprintf("ALTER USER %s RENAME TO %s", pq.QuoteIdentifier(oldUsername), pq.QuoteIdentifier(newUsername)))
+		_, err = pc.exec(db, fmt.Sprintf("ALTER USER %s RENAME TO %s", pq.QuoteIdentifier(oldUsername), pq.QuoteIdentifier(newUsername)))
 		if err != nil {
 			pc.log.Error(err, "could not rename user "+oldUsername)
 			return err
@@ -348,7 +517,13 @@ func (pc *client) UpdatePassword(username string, userPassword string) error {
 	}
 
 	pc.log.Info("update user password", "user:", username)
-	_, err := db.Exec(fmt.Sprintf("ALTER ROLE %s with encrypted password %s", pq.QuoteIdentifier(username), pq.QuoteLiteral(userPassword)))
+	verifier, err := scramSHA256Verifier(userPassword)
+	if err != nil {
+		pc.log.Error(err, "could not compute password verifier")
+		metrics.PasswordRotatedErrors.WithLabelValues("alter error").Inc()
+		return err
+	}
+	_, err = pc.exec(db, fmt.Sprintf("ALTER ROLE %s with encrypted password %s", pq.QuoteIdentifier(username), pq.QuoteLiteral(verifier)))
 	if err != nil {
 		if !strings.Contains(err.Error(), "already exists") {
 			pc.log.Error(err, "could not alter user "+username)
@@ -381,7 +556,7 @@ func (pc *client) ManageSuperUserRole(username string, enableSuperUser bool) err
 	if hasSuperUser {
 		if !enableSuperUser {
 			// remove superuser role
-			_, err = pc.DB.Exec(fmt.Sprintf("REVOKE %s FROM  %s", RDSSuperUserRole, pq.QuoteIdentifier(username)))
+			_, err = pc.exec(pc.DB, fmt.Sprintf("REVOKE %s FROM  %s", RDSSuperUserRole, pq.QuoteIdentifier(username)))
 			if err != nil {
 				pc.log.Error(err, "could not revoke superuser role")
 				return err
@@ -391,7 +566,7 @@ func (pc *client) ManageSuperUserRole(username string, enableSuperUser bool) err
 		if enableSuperUser {
 			// add superuser role
 			// _, err = pc.DB.Exec(`GRANT $1 TO $2`, pq.QuoteIdentifier(RDSSuperUserRole), pq.QuoteIdentifier(username))
-			_, err = pc.DB.Exec(fmt.Sprintf("GRANT %s TO  %s", RDSSuperUserRole, pq.QuoteIdentifier(username)))
+			_, err = pc.exec(pc.DB, fmt.Sprintf("GRANT %s TO  %s", RDSSuperUserRole, pq.QuoteIdentifier(username)))
 			// _, err = pc.DB.Exec(`GRANT rds_superuser TO "new-test-user"`)
 			if err != nil {
 				pc.log.Error(err, "could not grant superuser role")
@@ -437,7 +612,7 @@ func (pc *client) ManageCreateRole(username string, enableCreateRole bool) error
 	if hasCreateRole {
 		if !enableCreateRole {
 			// remove create role
-			_, err = pc.DB.Exec(fmt.Sprintf("ALTER ROLE  %s NOCREATEROLE", pq.QuoteIdentifier(username)))
+			_, err = pc.exec(pc.DB, fmt.Sprintf("ALTER ROLE  %s NOCREATEROLE", pq.QuoteIdentifier(username)))
 			if err != nil {
 				pc.log.Error(err, "could not remove create role")
 				return err
@@ -446,7 +621,7 @@ func (pc *client) ManageCreateRole(username string, enableCreateRole bool) error
 	} else {
 		if enableCreateRole {
 			// add create role
-			_, err = pc.DB.Exec(fmt.Sprintf("ALTER ROLE  %s CREATEROLE", pq.QuoteIdentifier(username)))
+			_, err = pc.exec(pc.DB, fmt.Sprintf("ALTER ROLE  %s CREATEROLE", pq.QuoteIdentifier(username)))
 			if err != nil {
 				pc.log.Error(err, "could not add create role")
 				return err
@@ -498,7 +673,7 @@ func (pc *client) ManageReplicationRole(username string, enableReplicationRole b
 		if hasReplicationRole {
 			if !enableReplicationRole {
 				// remove replication role
-				_, err = pc.DB.Exec(fmt.Sprintf("REVOKE rds_replication FROM %s", pq.QuoteIdentifier(username)))
+				_, err = pc.exec(pc.DB, fmt.Sprintf("REVOKE rds_replication FROM %s", pq.QuoteIdentifier(username)))
 				if err != nil {
 					return err
 				}
@@ -506,7 +681,7 @@ func (pc *client) ManageReplicationRole(username string, enableReplicationRole b
 		} else {
 			if enableReplicationRole {
 				// add replication role
-				_, err = pc.DB.Exec(fmt.Sprintf("GRANT rds_replication TO %s", pq.QuoteIdentifier(username)))
+				_, err = pc.exec(pc.DB, fmt.Sprintf("GRANT rds_replication TO %s", pq.QuoteIdentifier(username)))
 				if err != nil {
 					return err
 				}
@@ -529,7 +704,7 @@ func (pc *client) ManageReplicationRole(username string, enableReplicationRole b
 		if hasReplicationRole {
 			if !enableReplicationRole {
 				// remove replication role
-				_, err = pc.DB.Exec(fmt.Sprintf("ALTER ROLE %s NOREPLICATION", pq.QuoteIdentifier(username)))
+				_, err = pc.exec(pc.DB, fmt.Sprintf("ALTER ROLE %s NOREPLICATION", pq.QuoteIdentifier(username)))
 				if err != nil {
 					return err
 				}
@@ -537,7 +712,7 @@ func (pc *client) ManageReplicationRole(username string, enableReplicationRole b
 		} else {
 			if enableReplicationRole {
 				// add replication role
-				_, err = pc.DB.Exec(fmt.Sprintf("ALTER ROLE %s REPLICATION", pq.QuoteIdentifier(username)))
+				_, err = pc.exec(pc.DB, fmt.Sprintf("ALTER ROLE %s REPLICATION", pq.QuoteIdentifier(username)))
 				if err != nil {
 					return err
 				}
@@ -548,6 +723,12 @@ func (pc *client) ManageReplicationRole(username string, enableReplicationRole b
 }
 
 func (pc *client) Close() error {
+	pc.mu.Lock()
+	for name, db := range pc.dbs {
+		db.Close()
+		delete(pc.dbs, name)
+	}
+	pc.mu.Unlock()
 	if pc.DB != nil {
 		return pc.DB.Close()
 	}
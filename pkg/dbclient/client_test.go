@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"net/url"
+	"os"
 	"strconv"
 	"testing"
 
@@ -144,6 +145,42 @@ func setupSqlDB(t *testing.T) *testDB {
 	}
 }
 
+// sharedDB is the PostgreSQL container of the client tests, it is started by the first
+// test that needs it and purged by TestMain
+var sharedDB *testDB
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if sharedDB != nil {
+		if err := sharedDB.pool.Purge(sharedDB.resource); err != nil {
+			fmt.Printf("Could not purge resource: %s\n", err)
+		}
+	}
+	os.Exit(code)
+}
+
+// setupClient returns a client of the shared PostgreSQL container, the objects created
+// by a test are named after it so that tests do not interfere
+func setupClient(t *testing.T) (*testDB, *client) {
+	if sharedDB == nil {
+		sharedDB = setupSqlDB(t)
+	}
+	testDB := *sharedDB
+	testDB.t = t
+	db, err := sql.Open("postgres", testDB.URL())
+	if err != nil {
+		t.Fatalf("Could not connect to DB: %s", err)
+	}
+	pc := &client{
+		dbType: "postgres",
+		dbURL:  testDB.URL(),
+		DB:     db,
+		log:    logr.Discard(),
+	}
+	t.Cleanup(func() { pc.Close() })
+	return &testDB, pc
+}
+
 func TestPostgresClientOperations(t *testing.T) {
 	testDB := setupSqlDB(t)
 	defer testDB.Close()
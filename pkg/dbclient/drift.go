@@ -0,0 +1,253 @@
+package dbclient
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/infobloxopen/db-controller/pkg/metrics"
+)
+
+// RoleState is the desired state of a role of a claim
+type RoleState struct {
+	// CreateRole and Replication are attributes of the role, replication is
+	// granted through membership of rds_replication on RDS
+	CreateRole  bool
+	Replication bool
+	// SuperUser grants membership of rds_superuser on RDS, the role is never a superuser
+	SuperUser bool
+	// MemberOf are the roles the role is granted. Memberships of other roles are left
+	// in place, except for the rds_superuser and rds_replication roles managed above.
+	MemberOf []string
+	// SetRole is the role assumed by the sessions of the role, none when empty
+	SetRole string
+	// Database the role is granted all privileges on, together with its public schema.
+	// Grants are not checked when empty.
+	Database string
+}
+
+// RepairRoleDrift compares the attributes, memberships and grants of rolename managed
+// by the controller against desired and repairs the differences. It returns the drift
+// that was repaired. Missing roles are skipped.
+func (pc *client) RepairRoleDrift(rolename string, desired RoleState) ([]string, error) {
+	var super, createRole, replication bool
+	err := pc.DB.QueryRow(`SELECT rolsuper, rolcreaterole, rolreplication
+		FROM pg_catalog.pg_roles WHERE rolname = $1`, rolename).Scan(&super, &createRole, &replication)
+	plannedRole := err == sql.ErrNoRows && pc.isPlanned("role", rolename)
+	if err == sql.ErrNoRows && !plannedRole {
+		return nil, nil
+	}
+	if err != nil && !plannedRole {
+		pc.log.Error(err, "could not query for role", "role", rolename)
+		metrics.UsersUpdatedErrors.WithLabelValues("read error").Inc()
+		return nil, err
+	}
+	rdsRoles, err := pc.existingRoles(RDSSuperUserRole, RDSReplicationRole)
+	if err != nil {
+		return nil, err
+	}
+
+	role := pq.QuoteIdentifier(rolename)
+	type repair struct {
+		drift string
+		stmt  string
+	}
+	var repairs []repair
+	attribute := func(drift string, actual, want bool, attr string) {
+		if actual == want {
+			return
+		}
+		if !want {
+			attr = "NO" + attr
+		}
+		repairs = append(repairs, repair{drift, fmt.Sprintf("ALTER ROLE %s %s", role, attr)})
+	}
+	attribute("superuser", super, false, "SUPERUSER")
+	attribute("createrole", createRole, desired.CreateRole, "CREATEROLE")
+	if !rdsRoles[RDSReplicationRole] {
+		attribute("replication", replication, desired.Replication, "REPLICATION")
+	}
+
+	memberOf := map[string]bool{}
+	for _, g := range desired.MemberOf {
+		memberOf[g] = true
+	}
+	// the memberships that are revoked when they are not desired
+	managed := map[string]bool{}
+	if rdsRoles[RDSSuperUserRole] {
+		managed[RDSSuperUserRole] = true
+		memberOf[RDSSuperUserRole] = desired.SuperUser
+	}
+	if rdsRoles[RDSReplicationRole] {
+		managed[RDSReplicationRole] = true
+		memberOf[RDSReplicationRole] = desired.Replication
+	}
+	current, err := pc.memberships(rolename)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range sortedKeys(memberOf) {
+		if memberOf[g] && !current[g] {
+			repairs = append(repairs, repair{"membership of " + g,
+				fmt.Sprintf("GRANT %s TO %s", pq.QuoteIdentifier(g), role)})
+		}
+	}
+	var unmanaged []string
+	for _, g := range sortedKeys(current) {
+		switch {
+		case memberOf[g]:
+		case managed[g]:
+			repairs = append(repairs, repair{"membership of " + g,
+				fmt.Sprintf("REVOKE %s FROM %s", pq.QuoteIdentifier(g), role)})
+		default:
+			unmanaged = append(unmanaged, g)
+		}
+	}
+	if len(unmanaged) > 0 {
+		pc.log.Info("role is a member of roles not managed by the controller, memberships are left in place",
+			"role", rolename, "memberOf", unmanaged)
+	}
+
+	var setRole string
+	err = pc.DB.QueryRow(`SELECT COALESCE((SELECT substr(c, 6) FROM unnest(s.setconfig) c WHERE c LIKE 'role=%'), '')
+		FROM pg_catalog.pg_db_role_setting s JOIN pg_catalog.pg_roles r ON r.oid = s.setrole
+		WHERE r.rolname = $1 AND s.setdatabase = 0`, rolename).Scan(&setRole)
+	if err != nil && err != sql.ErrNoRows {
+		pc.log.Error(err, "could not query role settings", "role", rolename)
+		metrics.UsersUpdatedErrors.WithLabelValues("read error").Inc()
+		return nil, err
+	}
+	if strings.Trim(setRole, `"`) != desired.SetRole {
+		stmt := fmt.Sprintf("ALTER ROLE %s RESET ROLE", role)
+		if desired.SetRole != "" {
+			stmt = fmt.Sprintf("ALTER ROLE %s SET ROLE TO %s", role, pq.QuoteIdentifier(desired.SetRole))
+		}
+		repairs = append(repairs, repair{"set role", stmt})
+	}
+
+	if desired.Database != "" && (plannedRole || pc.isPlanned("database", desired.Database)) {
+		repairs = append(repairs, repair{"database privileges",
+			fmt.Sprintf("GRANT ALL PRIVILEGES ON DATABASE %s TO %s", pq.QuoteIdentifier(desired.Database), role)})
+	} else if desired.Database != "" {
+		var missing bool
+		err = pc.DB.QueryRow(`SELECT NOT (has_database_privilege($1::name, $2::text, 'CONNECT')
+			AND has_database_privilege($1::name, $2::text, 'CREATE')
+			AND has_database_privilege($1::name, $2::text, 'TEMPORARY'))`, rolename, desired.Database).Scan(&missing)
+		if err != nil {
+			pc.log.Error(err, "could not query database privileges", "role", rolename)
+			metrics.UsersUpdatedErrors.WithLabelValues("read error").Inc()
+			return nil, err
+		}
+		if missing {
+			repairs = append(repairs, repair{"database privileges",
+				fmt.Sprintf("GRANT ALL PRIVILEGES ON DATABASE %s TO %s", pq.QuoteIdentifier(desired.Database), role)})
+		}
+	}
+
+	var repaired []string
+	for _, r := range repairs {
+		pc.log.Info("repairing role drift", "role", rolename, "drift", r.drift)
+		if _, err := pc.exec(pc.DB, r.stmt); err != nil {
+			pc.log.Error(err, "could not repair "+r.drift, "role", rolename)
+			metrics.UsersUpdatedErrors.WithLabelValues("alter error").Inc()
+			return repaired, fmt.Errorf("could not repair %s of role %s: %s", r.drift, rolename, err)
+		}
+		metrics.RoleDriftRepaired.WithLabelValues(strings.SplitN(r.drift, " of ", 2)[0]).Inc()
+		repaired = append(repaired, r.drift)
+	}
+
+	if desired.Database != "" {
+		schemaRepaired, err := pc.repairPublicSchemaPrivileges(desired.Database, rolename, plannedRole)
+		if err != nil {
+			return repaired, err
+		}
+		repaired = append(repaired, schemaRepaired...)
+	}
+	return repaired, nil
+}
+
+func (pc *client) repairPublicSchemaPrivileges(dbName, rolename string, plannedRole bool) ([]string, error) {
+	db, err := pc.getDB(dbName)
+	if err != nil {
+		pc.log.Error(err, "could not connect to db", "database", dbName)
+		return nil, err
+	}
+
+	missing := plannedRole
+	if !plannedRole {
+		err = db.QueryRow(`SELECT NOT (has_schema_privilege($1::name, 'public', 'USAGE')
+			AND has_schema_privilege($1::name, 'public', 'CREATE'))`, rolename).Scan(&missing)
+	}
+	if err != nil {
+		pc.log.Error(err, "could not query schema privileges", "role", rolename)
+		metrics.UsersUpdatedErrors.WithLabelValues("read error").Inc()
+		return nil, err
+	}
+	if !missing {
+		return nil, nil
+	}
+	drift := "schema privileges"
+	pc.log.Info("repairing role drift", "role", rolename, "drift", drift)
+	if _, err := pc.exec(db, fmt.Sprintf("GRANT ALL ON SCHEMA public TO %s", pq.QuoteIdentifier(rolename))); err != nil {
+		pc.log.Error(err, "could not repair "+drift, "role", rolename)
+		metrics.UsersUpdatedErrors.WithLabelValues("grant error").Inc()
+		return nil, fmt.Errorf("could not repair %s of role %s: %s", drift, rolename, err)
+	}
+	metrics.RoleDriftRepaired.WithLabelValues(drift).Inc()
+	return []string{drift}, nil
+}
+
+// memberships returns the roles rolename is directly a member of
+func (pc *client) memberships(rolename string) (map[string]bool, error) {
+	rows, err := pc.DB.Query(`SELECT g.rolname FROM pg_catalog.pg_auth_members m
+		JOIN pg_catalog.pg_roles g ON g.oid = m.roleid
+		JOIN pg_catalog.pg_roles u ON u.oid = m.member
+		WHERE u.rolname = $1`, rolename)
+	if err != nil {
+		pc.log.Error(err, "could not query role memberships", "role", rolename)
+		metrics.UsersUpdatedErrors.WithLabelValues("read error").Inc()
+		return nil, err
+	}
+	defer rows.Close()
+	groups := map[string]bool{}
+	for rows.Next() {
+		var g string
+		if err := rows.Scan(&g); err != nil {
+			return nil, err
+		}
+		groups[g] = true
+	}
+	return groups, rows.Err()
+}
+
+// existingRoles reports which of the roles exist
+func (pc *client) existingRoles(rolenames ...string) (map[string]bool, error) {
+	rows, err := pc.DB.Query("SELECT rolname FROM pg_catalog.pg_roles WHERE rolname = ANY($1)", pq.Array(rolenames))
+	if err != nil {
+		pc.log.Error(err, "could not query for roles")
+		metrics.UsersUpdatedErrors.WithLabelValues("read error").Inc()
+		return nil, err
+	}
+	defer rows.Close()
+	exists := map[string]bool{}
+	for rows.Next() {
+		var r string
+		if err := rows.Scan(&r); err != nil {
+			return nil, err
+		}
+		exists[r] = true
+	}
+	return exists, rows.Err()
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,101 @@
+package dbclient
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPostgresClientRoleDrift(t *testing.T) {
+	_, pc := setupClient(t)
+
+	dbName := "drift_db"
+	group := "drift_group"
+	user := "drift_group_a"
+	if _, err := pc.CreateDatabase(dbName); err != nil {
+		t.Fatalf("\t%s CreateDatabase() error = %v", failed, err)
+	}
+	if _, err := pc.CreateGroup(dbName, group); err != nil {
+		t.Fatalf("\t%s CreateGroup() error = %v", failed, err)
+	}
+	if _, err := pc.CreateUser(user, group, "password"); err != nil {
+		t.Fatalf("\t%s CreateUser() error = %v", failed, err)
+	}
+	groupState := RoleState{Database: dbName}
+	userState := RoleState{Replication: true, MemberOf: []string{group}, SetRole: group}
+
+	// the cases run in order against the same roles
+	tests := []struct {
+		name         string
+		drift        []string
+		role         string
+		state        RoleState
+		wantRepaired []string
+	}{
+		{
+			name:  "group without drift",
+			role:  group,
+			state: groupState,
+		},
+		{
+			// replication is not set up by CreateUser
+			name:         "user without drift",
+			role:         user,
+			state:        userState,
+			wantRepaired: []string{"replication"},
+		},
+		{
+			// memberships of roles not managed by the controller are left in place
+			name: "user with drift",
+			drift: []string{
+				"ALTER ROLE drift_group_a CREATEROLE NOREPLICATION",
+				"GRANT pg_read_all_data TO drift_group_a",
+				"REVOKE drift_group FROM drift_group_a",
+				"ALTER ROLE drift_group_a RESET ROLE",
+				"REVOKE ALL ON DATABASE drift_db FROM drift_group",
+			},
+			role:         user,
+			state:        userState,
+			wantRepaired: []string{"createrole", "replication", "membership of drift_group", "set role"},
+		},
+		{
+			name:         "group with drift",
+			role:         group,
+			state:        groupState,
+			wantRepaired: []string{"database privileges"},
+		},
+		{
+			name:  "user after repair",
+			role:  user,
+			state: userState,
+		},
+		{
+			name:  "missing role",
+			role:  "missing_role",
+			state: userState,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, stmt := range tt.drift {
+				if _, err := pc.DB.Exec(stmt); err != nil {
+					t.Fatalf("\t%s %s error = %v", failed, stmt, err)
+				}
+			}
+			repaired, err := pc.RepairRoleDrift(tt.role, tt.state)
+			if err != nil {
+				t.Fatalf("\t%s RepairRoleDrift() error = %v", failed, err)
+			}
+			if len(repaired) != 0 || len(tt.wantRepaired) != 0 {
+				if !reflect.DeepEqual(repaired, tt.wantRepaired) {
+					t.Errorf("\t%s RepairRoleDrift() repaired = %v, want %v", failed, repaired, tt.wantRepaired)
+				}
+			}
+			t.Logf("\t%s %s is passed", succeed, tt.name)
+		})
+	}
+
+	var member bool
+	if err := pc.DB.QueryRow("SELECT pg_has_role('drift_group_a', 'pg_read_all_data', 'MEMBER')").Scan(&member); err != nil || !member {
+		t.Errorf("\t%s unmanaged membership was revoked, member = %v, error = %v", failed, member, err)
+	}
+}
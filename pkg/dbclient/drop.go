@@ -0,0 +1,149 @@
+package dbclient
+
+import (
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"github.com/infobloxopen/db-controller/pkg/metrics"
+)
+
+// maintenanceDB is used to drop databases and roles, the client DSN can point to the database being dropped
+const maintenanceDB = "postgres"
+
+// DropDatabase blocks new connections to dbName, terminates the open sessions and drops it
+func (pc *client) DropDatabase(dbName string) (bool, error) {
+	var exists bool
+	dropped := false
+
+	// idle connections of the client would block the drop
+	pc.closeDB(dbName)
+	db, err := pc.getDB(maintenanceDB)
+	if err != nil {
+		pc.log.Error(err, "could not connect to db", "database", maintenanceDB)
+		return dropped, err
+	}
+
+	err = db.QueryRow("SELECT EXISTS(SELECT datname FROM pg_catalog.pg_database WHERE datname = $1)", dbName).Scan(&exists)
+	if err != nil {
+		pc.log.Error(err, "could not query for database name")
+		metrics.DBProvisioningErrors.WithLabelValues("read error").Inc()
+		return dropped, err
+	}
+	if !exists {
+		return dropped, nil
+	}
+
+	pc.log.Info("dropping DB:", "database name", dbName)
+	// the connection limit does not apply to superusers, which is why sessions are terminated after
+	if _, err := pc.exec(db, fmt.Sprintf("ALTER DATABASE %s CONNECTION LIMIT 0", pq.QuoteIdentifier(dbName))); err != nil {
+		pc.log.Error(err, "could not block connections to database")
+		metrics.DBProvisioningErrors.WithLabelValues("drop error").Inc()
+		return dropped, err
+	}
+	if _, err := pc.exec(db, `SELECT pg_terminate_backend(pid) FROM pg_catalog.pg_stat_activity
+		WHERE datname = $1 AND pid <> pg_backend_pid()`, dbName); err != nil {
+		pc.log.Error(err, "could not terminate sessions of database")
+		metrics.DBProvisioningErrors.WithLabelValues("drop error").Inc()
+		return dropped, err
+	}
+	if _, err := pc.exec(db, fmt.Sprintf("DROP DATABASE IF EXISTS %s", pq.QuoteIdentifier(dbName))); err != nil {
+		pc.log.Error(err, "could not drop database")
+		metrics.DBProvisioningErrors.WithLabelValues("drop error").Inc()
+		return dropped, err
+	}
+	dropped = true
+	pc.log.Info("database has been dropped", "DB", dbName)
+	metrics.DBDeleted.Inc()
+
+	return dropped, nil
+}
+
+// DropUser terminates the sessions of username and drops the login role
+func (pc *client) DropUser(username string) (bool, error) {
+	return pc.dropRole(username)
+}
+
+// DropGroup drops the group role, memberships of the group are revoked with it
+func (pc *client) DropGroup(rolename string) (bool, error) {
+	return pc.dropRole(rolename)
+}
+
+// dropRole drops rolename from the maintenance database, the client DSN can point to
+// a database dropped before its roles
+func (pc *client) dropRole(rolename string) (bool, error) {
+	var exists bool
+	dropped := false
+
+	db, err := pc.getDB(maintenanceDB)
+	if err != nil {
+		pc.log.Error(err, "could not connect to db", "database", maintenanceDB)
+		return dropped, err
+	}
+	err = db.QueryRow("SELECT EXISTS(SELECT pg_roles.rolname FROM pg_catalog.pg_roles where pg_roles.rolname = $1)", rolename).Scan(&exists)
+	if err != nil {
+		pc.log.Error(err, "could not query for role")
+		metrics.UsersDeletedErrors.WithLabelValues("read error").Inc()
+		return dropped, err
+	}
+	if !exists {
+		return dropped, nil
+	}
+
+	pc.log.Info("dropping ROLE", "role", rolename)
+	if _, err := pc.exec(db, fmt.Sprintf("ALTER ROLE %s NOLOGIN", pq.QuoteIdentifier(rolename))); err != nil {
+		pc.log.Error(err, "could not disable login of role "+rolename)
+		metrics.UsersDeletedErrors.WithLabelValues("drop error").Inc()
+		return dropped, err
+	}
+	if _, err := pc.exec(db, `SELECT pg_terminate_backend(pid) FROM pg_catalog.pg_stat_activity
+		WHERE usename = $1 AND pid <> pg_backend_pid()`, rolename); err != nil {
+		pc.log.Error(err, "could not terminate sessions of role "+rolename)
+		metrics.UsersDeletedErrors.WithLabelValues("drop error").Inc()
+		return dropped, err
+	}
+	if _, err := pc.exec(db, fmt.Sprintf("DROP ROLE IF EXISTS %s", pq.QuoteIdentifier(rolename))); err != nil {
+		pc.log.Error(err, "could not drop role "+rolename)
+		metrics.UsersDeletedErrors.WithLabelValues("drop error").Inc()
+		return dropped, err
+	}
+	dropped = true
+	pc.log.Info("role has been dropped", "role", rolename)
+	metrics.UsersDeleted.Inc()
+
+	return dropped, nil
+}
+
+// DisownRole hands the objects of rolename in dbName over to the current user and
+// revokes its privileges there, so that it can be dropped while dbName is kept
+func (pc *client) DisownRole(dbName, rolename string) error {
+	var exists bool
+
+	db, err := pc.getDB(dbName)
+	if err != nil {
+		pc.log.Error(err, "could not connect to db", "database", dbName)
+		return err
+	}
+	err = db.QueryRow("SELECT EXISTS(SELECT pg_roles.rolname FROM pg_catalog.pg_roles where pg_roles.rolname = $1)", rolename).Scan(&exists)
+	if err != nil {
+		pc.log.Error(err, "could not query for role")
+		metrics.UsersDeletedErrors.WithLabelValues("read error").Inc()
+		return err
+	}
+	if !exists {
+		return nil
+	}
+
+	pc.log.Info("disowning ROLE", "role", rolename, "database", dbName)
+	if _, err := pc.exec(db, fmt.Sprintf("REASSIGN OWNED BY %s TO CURRENT_USER", pq.QuoteIdentifier(rolename))); err != nil {
+		pc.log.Error(err, "could not reassign the objects of role "+rolename)
+		metrics.UsersDeletedErrors.WithLabelValues("drop error").Inc()
+		return err
+	}
+	if _, err := pc.exec(db, fmt.Sprintf("DROP OWNED BY %s", pq.QuoteIdentifier(rolename))); err != nil {
+		pc.log.Error(err, "could not revoke the privileges of role "+rolename)
+		metrics.UsersDeletedErrors.WithLabelValues("drop error").Inc()
+		return err
+	}
+	return nil
+}
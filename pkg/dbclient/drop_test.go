@@ -0,0 +1,155 @@
+package dbclient
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+func TestPostgresClientDrop(t *testing.T) {
+	testDB, pc := setupClient(t)
+
+	dbName := "drop_db"
+	keptDB := "drop_kept_db"
+	group := "drop_group"
+	user := "drop_group_a"
+	keptGroup := "drop_kept_group"
+	password := "drop_password"
+	for _, db := range []string{dbName, keptDB} {
+		if _, err := pc.CreateDatabase(db); err != nil {
+			t.Fatalf("\t%s CreateDatabase() error = %v", failed, err)
+		}
+	}
+	if _, err := pc.CreateGroup(dbName, group); err != nil {
+		t.Fatalf("\t%s CreateGroup() error = %v", failed, err)
+	}
+	if _, err := pc.CreateUser(user, group, password); err != nil {
+		t.Fatalf("\t%s CreateUser() error = %v", failed, err)
+	}
+	if _, err := pc.CreateGroup(keptDB, keptGroup); err != nil {
+		t.Fatalf("\t%s CreateGroup() error = %v", failed, err)
+	}
+	kept, err := pc.getDB(keptDB)
+	if err != nil {
+		t.Fatalf("\t%s getDB() error = %v", failed, err)
+	}
+	if _, err := kept.Exec(fmt.Sprintf("CREATE TABLE kept (id int); ALTER TABLE kept OWNER TO %s", keptGroup)); err != nil {
+		t.Fatalf("\t%s could not create table owned by %s: %v", failed, keptGroup, err)
+	}
+
+	// keep a session open, it has to be terminated by the drop
+	session, err := testDB.OpenUser(dbName, user, password)
+	if err != nil {
+		t.Fatalf("\t%s OpenUser() error = %v", failed, err)
+	}
+	defer session.Close()
+	if err := session.Ping(); err != nil {
+		t.Fatalf("\t%s could not login as %s: %v", failed, user, err)
+	}
+
+	// the client of an existing database claim is connected to the database it drops
+	existingDB, err := testDB.OpenUser(dbName, testDB.username, testDB.password)
+	if err != nil {
+		t.Fatalf("\t%s OpenUser() error = %v", failed, err)
+	}
+	if err := existingDB.Ping(); err != nil {
+		t.Fatalf("\t%s could not connect to %s: %v", failed, dbName, err)
+	}
+	existing := &client{
+		dbType: "postgres",
+		dbURL:  strings.Replace(testDB.URL(), "/postgres?", "/"+dbName+"?", 1),
+		DB:     existingDB,
+		log:    logr.Discard(),
+	}
+	defer existing.Close()
+
+	databaseExists := `SELECT EXISTS(SELECT datname FROM pg_catalog.pg_database WHERE datname = $1)`
+	roleExists := `SELECT EXISTS(SELECT rolname FROM pg_catalog.pg_roles WHERE rolname = $1)`
+	keptTableExists := `SELECT EXISTS(SELECT 1 FROM pg_catalog.pg_tables WHERE tablename = $1)`
+
+	// the cases run in order, query reports whether object exists after the drop
+	tests := []struct {
+		name        string
+		drop        func() (bool, error)
+		wantDropped bool
+		wantErr     bool
+		query       string
+		object      string
+		wantExists  bool
+	}{
+		{
+			name:        "DropDatabase() of the database of the client",
+			drop:        func() (bool, error) { return existing.DropDatabase(dbName) },
+			wantDropped: true,
+			query:       databaseExists,
+			object:      dbName,
+		},
+		{
+			name:   "DropDatabase() of missing database",
+			drop:   func() (bool, error) { return pc.DropDatabase(dbName) },
+			query:  databaseExists,
+			object: dbName,
+		},
+		{
+			name:        "DropUser()",
+			drop:        func() (bool, error) { return existing.DropUser(user) },
+			wantDropped: true,
+			query:       roleExists,
+			object:      user,
+		},
+		{
+			name:        "DropGroup()",
+			drop:        func() (bool, error) { return existing.DropGroup(group) },
+			wantDropped: true,
+			query:       roleExists,
+			object:      group,
+		},
+		{
+			name:       "DropGroup() of a role owning objects",
+			drop:       func() (bool, error) { return pc.DropGroup(keptGroup) },
+			wantErr:    true,
+			query:      roleExists,
+			object:     keptGroup,
+			wantExists: true,
+		},
+		{
+			name:       "DisownRole()",
+			drop:       func() (bool, error) { return false, pc.DisownRole(keptDB, keptGroup) },
+			query:      keptTableExists,
+			object:     "kept",
+			wantExists: true,
+		},
+		{
+			name:        "DropGroup() of a disowned role",
+			drop:        func() (bool, error) { return pc.DropGroup(keptGroup) },
+			wantDropped: true,
+			query:       roleExists,
+			object:      keptGroup,
+		},
+		{
+			name:   "DisownRole() of missing role",
+			drop:   func() (bool, error) { return false, pc.DisownRole(keptDB, keptGroup) },
+			query:  roleExists,
+			object: keptGroup,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dropped, err := tt.drop()
+			if (err != nil) != tt.wantErr || dropped != tt.wantDropped {
+				t.Fatalf("\t%s dropped = %v, error = %v, want dropped %v, wantErr %v", failed, dropped, err, tt.wantDropped, tt.wantErr)
+			}
+			db := pc.DB
+			if tt.query == keptTableExists {
+				db = kept
+			}
+			var exists bool
+			if err := db.QueryRow(tt.query, tt.object).Scan(&exists); err != nil || exists != tt.wantExists {
+				t.Errorf("\t%s %s exists = %v, error = %v", failed, tt.object, exists, err)
+			}
+			t.Logf("\t%s %s is passed", succeed, tt.name)
+		})
+	}
+}
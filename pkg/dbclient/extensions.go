@@ -0,0 +1,127 @@
+package dbclient
+
+import (
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"github.com/infobloxopen/db-controller/pkg/metrics"
+)
+
+// Extension describes a postgres extension in a database
+type Extension struct {
+	Name    string
+	Version string
+	Schema  string
+}
+
+// GetExtensions returns the extensions installed in dbName
+func (pc *client) GetExtensions(dbName string) ([]Extension, error) {
+	db, err := pc.getDB(dbName)
+	if err != nil {
+		pc.log.Error(err, "could not connect to db", "database", dbName)
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT e.extname, e.extversion, n.nspname
+		FROM pg_catalog.pg_extension e
+		JOIN pg_catalog.pg_namespace n ON n.oid = e.extnamespace
+		ORDER BY e.extname`)
+	if err != nil {
+		pc.log.Error(err, "could not query for extensions", "database", dbName)
+		metrics.ExtensionErrors.WithLabelValues("read error").Inc()
+		return nil, err
+	}
+	defer rows.Close()
+
+	var extensions []Extension
+	for rows.Next() {
+		var e Extension
+		if err := rows.Scan(&e.Name, &e.Version, &e.Schema); err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, e)
+	}
+	return extensions, rows.Err()
+}
+
+// CreateExtension installs the extension in dbName, creating its schema when required
+func (pc *client) CreateExtension(dbName string, extension Extension) error {
+	db, err := pc.getDB(dbName)
+	if err != nil {
+		pc.log.Error(err, "could not connect to db", "database", dbName)
+		return err
+	}
+
+	stmt := fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s", pq.QuoteIdentifier(extension.Name))
+	if extension.Schema != "" {
+		if _, err := pc.exec(db, fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", pq.QuoteIdentifier(extension.Schema))); err != nil {
+			pc.log.Error(err, "could not create schema", "database", dbName, "schema", extension.Schema)
+			metrics.ExtensionErrors.WithLabelValues("create error").Inc()
+			return fmt.Errorf("could not create schema %s: %s", extension.Schema, err)
+		}
+		stmt += fmt.Sprintf(" WITH SCHEMA %s", pq.QuoteIdentifier(extension.Schema))
+	}
+	if extension.Version != "" {
+		stmt += fmt.Sprintf(" VERSION %s", pq.QuoteLiteral(extension.Version))
+	}
+	if _, err := pc.exec(db, stmt); err != nil {
+		pc.log.Error(err, "could not create extension", "database", dbName, "extension", extension.Name)
+		metrics.ExtensionErrors.WithLabelValues("create error").Inc()
+		return fmt.Errorf("could not create extension %s: %s", extension.Name, err)
+	}
+	pc.log.Info("created extension", "database", dbName, "extension", extension.Name, "version", extension.Version)
+	return nil
+}
+
+// UpdateExtension updates the extension to the requested version and moves it to
+// the requested schema. Empty fields are left unchanged.
+func (pc *client) UpdateExtension(dbName string, extension Extension) error {
+	db, err := pc.getDB(dbName)
+	if err != nil {
+		pc.log.Error(err, "could not connect to db", "database", dbName)
+		return err
+	}
+
+	if extension.Version != "" {
+		if _, err := pc.exec(db, fmt.Sprintf("ALTER EXTENSION %s UPDATE TO %s",
+			pq.QuoteIdentifier(extension.Name), pq.QuoteLiteral(extension.Version))); err != nil {
+			pc.log.Error(err, "could not update extension", "database", dbName, "extension", extension.Name)
+			metrics.ExtensionErrors.WithLabelValues("update error").Inc()
+			return fmt.Errorf("could not update extension %s to version %s: %s", extension.Name, extension.Version, err)
+		}
+	}
+	if extension.Schema != "" {
+		if _, err := pc.exec(db, fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", pq.QuoteIdentifier(extension.Schema))); err != nil {
+			pc.log.Error(err, "could not create schema", "database", dbName, "schema", extension.Schema)
+			metrics.ExtensionErrors.WithLabelValues("update error").Inc()
+			return fmt.Errorf("could not create schema %s: %s", extension.Schema, err)
+		}
+		if _, err := pc.exec(db, fmt.Sprintf("ALTER EXTENSION %s SET SCHEMA %s",
+			pq.QuoteIdentifier(extension.Name), pq.QuoteIdentifier(extension.Schema))); err != nil {
+			pc.log.Error(err, "could not move extension", "database", dbName, "extension", extension.Name)
+			metrics.ExtensionErrors.WithLabelValues("update error").Inc()
+			return fmt.Errorf("could not move extension %s to schema %s: %s", extension.Name, extension.Schema, err)
+		}
+	}
+	pc.log.Info("updated extension", "database", dbName, "extension", extension.Name, "version", extension.Version)
+	return nil
+}
+
+// DropExtension removes the extension from dbName. Objects depending on the
+// extension are not dropped, the statement fails instead.
+func (pc *client) DropExtension(dbName string, name string) error {
+	db, err := pc.getDB(dbName)
+	if err != nil {
+		pc.log.Error(err, "could not connect to db", "database", dbName)
+		return err
+	}
+
+	if _, err := pc.exec(db, fmt.Sprintf("DROP EXTENSION IF EXISTS %s", pq.QuoteIdentifier(name))); err != nil {
+		pc.log.Error(err, "could not drop extension", "database", dbName, "extension", name)
+		metrics.ExtensionErrors.WithLabelValues("drop error").Inc()
+		return fmt.Errorf("could not drop extension %s: %s", name, err)
+	}
+	pc.log.Info("dropped extension", "database", dbName, "extension", name)
+	return nil
+}
@@ -0,0 +1,82 @@
+package dbclient
+
+import "testing"
+
+func TestPostgresClientExtensions(t *testing.T) {
+	_, pc := setupClient(t)
+
+	dbName := "ext_db"
+	if _, err := pc.CreateDatabase(dbName); err != nil {
+		t.Fatalf("\t%s CreateDatabase() error = %v", failed, err)
+	}
+
+	getExtension := func(t *testing.T, name string) (Extension, bool) {
+		extensions, err := pc.GetExtensions(dbName)
+		if err != nil {
+			t.Fatalf("\t%s GetExtensions() error = %v", failed, err)
+		}
+		for _, e := range extensions {
+			if e.Name == name {
+				return e, true
+			}
+		}
+		return Extension{}, false
+	}
+
+	// the cases run in order against the same database, a nil want expects the
+	// extension to be dropped
+	tests := []struct {
+		name      string
+		apply     func() error
+		extension string
+		want      *Extension
+		wantErr   bool
+	}{
+		{
+			name:  "create at version",
+			apply: func() error { return pc.CreateExtension(dbName, Extension{Name: "citext", Version: "1.4"}) },
+			want:  &Extension{Name: "citext", Version: "1.4", Schema: "public"},
+		},
+		{
+			name:  "create in schema",
+			apply: func() error { return pc.CreateExtension(dbName, Extension{Name: "hstore", Schema: "ext"}) },
+			want:  &Extension{Name: "hstore", Schema: "ext"},
+		},
+		{
+			name: "update version and schema",
+			apply: func() error {
+				return pc.UpdateExtension(dbName, Extension{Name: "citext", Version: "1.6", Schema: "ext"})
+			},
+			want: &Extension{Name: "citext", Version: "1.6", Schema: "ext"},
+		},
+		{
+			name:    "update to unknown version",
+			apply:   func() error { return pc.UpdateExtension(dbName, Extension{Name: "citext", Version: "99.0"}) },
+			want:    &Extension{Name: "citext", Version: "1.6", Schema: "ext"},
+			wantErr: true,
+		},
+		{
+			name:      "drop",
+			apply:     func() error { return pc.DropExtension(dbName, "hstore") },
+			extension: "hstore",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.apply(); (err != nil) != tt.wantErr {
+				t.Fatalf("\t%s error = %v, wantErr %v", failed, err, tt.wantErr)
+			}
+			if tt.want == nil {
+				if _, ok := getExtension(t, tt.extension); ok {
+					t.Errorf("\t%s %s is still installed", failed, tt.extension)
+				}
+				return
+			}
+			e, ok := getExtension(t, tt.want.Name)
+			if !ok || (tt.want.Version != "" && e.Version != tt.want.Version) || e.Schema != tt.want.Schema {
+				t.Errorf("\t%s got = %v, want %v", failed, e, *tt.want)
+			}
+			t.Logf("\t%s %s is passed", succeed, tt.name)
+		})
+	}
+}
@@ -7,12 +7,29 @@ type Client interface {
 	CreateUser(username, role, userPassword string) (bool, error)
 	CreateGroup(dbName, username string) (bool, error)
 	CreateDefaultExtentions(dbName string) error
+	GetExtensions(dbName string) ([]Extension, error)
+	CreateExtension(dbName string, extension Extension) error
+	UpdateExtension(dbName string, extension Extension) error
+	DropExtension(dbName string, name string) error
+	CreateSchema(dbName, schemaName, rolename string) (bool, error)
+	ManageSchemaPrivileges(dbName, schemaName, rolename string) ([]string, error)
+	CreateRole(rolename string) (bool, error)
+	ManageUserPrivileges(dbName, rolename string, privileges UserPrivileges) error
+	SetConnectionLimit(username string, limit int) error
+	SetLogin(username string, login bool) error
+	ManageDatabaseSettings(dbName string, settings map[string]string, reset []string) ([]string, error)
+	ManageRoleSettings(rolename string, settings map[string]string, reset []string) ([]string, error)
 	RenameUser(oldUsername string, newUsername string) error
 	UpdateUser(oldUsername, newUsername, rolename, password string) error
 	UpdatePassword(username string, userPassword string) error
 	ManageReplicationRole(username string, enableReplicationRole bool) error
 	ManageSuperUserRole(username string, enableSuperUser bool) error
 	ManageCreateRole(username string, enableCreateRole bool) error
+	RepairRoleDrift(rolename string, desired RoleState) ([]string, error)
+	DropDatabase(dbName string) (bool, error)
+	DropUser(username string) (bool, error)
+	DropGroup(rolename string) (bool, error)
+	DisownRole(dbName, rolename string) error
 
 	DBCloser
 }
@@ -0,0 +1,71 @@
+package dbclient
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+
+	"github.com/infobloxopen/db-controller/pkg/audit"
+)
+
+// Planner is implemented by clients created with Config.Plan
+type Planner interface {
+	// Statements returns the recorded statements prefixed by the database they run in
+	Statements() []string
+}
+
+// plan records the statements of a planning client instead of executing them,
+// together with the objects they would create
+type plan struct {
+	mu         sync.Mutex
+	statements []string
+	created    map[string]bool
+}
+
+// templateDB is read in place of databases created by the plan, new databases are copies of it
+const templateDB = "template1"
+
+func (pc *client) Statements() []string {
+	if pc.plan == nil {
+		return nil
+	}
+	pc.plan.mu.Lock()
+	defer pc.plan.mu.Unlock()
+	return append([]string(nil), pc.plan.statements...)
+}
+
+// exec runs query on db and records it in the audit log, a planning client records it
+// in the plan instead. Queries reading the state of the host always run.
+func (pc *client) exec(db *sql.DB, query string, args ...interface{}) (sql.Result, error) {
+	if pc.plan == nil {
+		rec := audit.NewRecord("dbclient", pc.claim, pc.dbURL)
+		rec.Database = pc.dbNameOf(db)
+		return audit.Exec(pc.audit, rec, db, query, args...)
+	}
+	stmt := audit.Statement(query, args)
+	pc.log.Info("planned statement", "database", pc.dbNameOf(db), "statement", stmt)
+	pc.plan.mu.Lock()
+	defer pc.plan.mu.Unlock()
+	pc.plan.statements = append(pc.plan.statements, pc.dbNameOf(db)+": "+stmt)
+	return driver.RowsAffected(0), nil
+}
+
+// markPlanned records that the plan creates the object kind name
+func (pc *client) markPlanned(kind, name string) {
+	if pc.plan == nil {
+		return
+	}
+	pc.plan.mu.Lock()
+	defer pc.plan.mu.Unlock()
+	pc.plan.created[kind+" "+name] = true
+}
+
+// isPlanned reports whether the object kind name only exists in the plan
+func (pc *client) isPlanned(kind, name string) bool {
+	if pc.plan == nil {
+		return false
+	}
+	pc.plan.mu.Lock()
+	defer pc.plan.mu.Unlock()
+	return pc.plan.created[kind+" "+name]
+}
@@ -0,0 +1,131 @@
+package dbclient
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	"github.com/infobloxopen/db-controller/pkg/audit"
+)
+
+func TestPlanExecRedactsPasswords(t *testing.T) {
+	pc := &client{
+		dbURL: "postgres://postgres@localhost:5432/postgres",
+		log:   logr.Discard(),
+		plan:  &plan{created: map[string]bool{}},
+	}
+	if _, err := pc.exec(pc.DB, `CREATE ROLE "u" with encrypted password 'SCRAM-SHA-256$4096:x''y$z' LOGIN`); err != nil {
+		t.Fatalf("exec() error = %v", err)
+	}
+	want := []string{`postgres: CREATE ROLE "u" with encrypted password '***' LOGIN`}
+	if got := pc.Statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Statements() = %v, want %v", got, want)
+	}
+}
+
+func TestPostgresClientPlan(t *testing.T) {
+	_, pc := setupClient(t)
+	pc.plan = &plan{created: map[string]bool{}}
+
+	dbName := "plan_db"
+	role := "plan_group"
+	user := "plan_user"
+	defer pc.closeDB(dbName)
+
+	t.Logf("plan new database")
+	if _, err := pc.CreateDatabase(dbName); err != nil {
+		t.Fatalf("\t%s CreateDatabase() error = %v", failed, err)
+	}
+	if _, err := pc.CreateGroup(dbName, role); err != nil {
+		t.Fatalf("\t%s CreateGroup() error = %v", failed, err)
+	}
+	if _, err := pc.CreateUser(user, role, "secret"); err != nil {
+		t.Fatalf("\t%s CreateUser() error = %v", failed, err)
+	}
+	if _, err := pc.CreateSchema(dbName, "app", role); err != nil {
+		t.Fatalf("\t%s CreateSchema() error = %v", failed, err)
+	}
+	if _, err := pc.ManageSchemaPrivileges(dbName, "app", role); err != nil {
+		t.Fatalf("\t%s ManageSchemaPrivileges() error = %v", failed, err)
+	}
+	if _, err := pc.ManageRoleSettings(role, map[string]string{"statement_timeout": "30s"}, nil); err != nil {
+		t.Fatalf("\t%s ManageRoleSettings() error = %v", failed, err)
+	}
+	if _, err := pc.RepairRoleDrift(role, RoleState{Database: dbName}); err != nil {
+		t.Fatalf("\t%s RepairRoleDrift() error = %v", failed, err)
+	}
+
+	got := strings.Join(pc.Statements(), "\n")
+	for _, want := range []string{
+		`create database "plan_db"`,
+		`CREATE ROLE "plan_group"`,
+		`CREATE ROLE "plan_user" with encrypted password '***'`,
+		`plan_db: CREATE SCHEMA IF NOT EXISTS "app"`,
+		`ALTER ROLE "plan_group" SET statement_timeout TO '30s'`,
+		`GRANT ALL PRIVILEGES ON DATABASE "plan_db" TO "plan_group"`,
+		`plan_db: GRANT ALL ON SCHEMA public TO "plan_group"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("\t%s plan does not contain %s:\n%s", failed, want, got)
+		}
+	}
+	if strings.Contains(got, "secret") {
+		t.Errorf("\t%s plan contains the password:\n%s", failed, got)
+	}
+
+	var exists bool
+	if err := pc.DB.QueryRow(`SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)
+		OR EXISTS (SELECT 1 FROM pg_roles WHERE rolname = ANY(ARRAY[$2, $3]))`, dbName, role, user).Scan(&exists); err != nil {
+		t.Fatalf("\t%s query error = %v", failed, err)
+	}
+	if exists {
+		t.Fatalf("\t%s planned objects were created", failed)
+	}
+	t.Logf("\t%s plan is passed", succeed)
+}
+
+type recordingSink struct {
+	records []audit.Record
+}
+
+func (s *recordingSink) Write(rec audit.Record) error {
+	s.records = append(s.records, rec)
+	return nil
+}
+
+func TestPostgresClientAudit(t *testing.T) {
+	testDB, pc := setupClient(t)
+	sink := &recordingSink{}
+	pc.audit = sink
+	pc.claim = "default/audit"
+
+	dbName := "audit_db"
+	defer pc.closeDB(dbName)
+	if _, err := pc.CreateDatabase(dbName); err != nil {
+		t.Fatalf("\t%s CreateDatabase() error = %v", failed, err)
+	}
+	if _, err := pc.CreateSchema(dbName, "app", testDB.username); err != nil {
+		t.Fatalf("\t%s CreateSchema() error = %v", failed, err)
+	}
+	if err := pc.UpdatePassword("missing_user", "secret"); err == nil {
+		t.Fatalf("\t%s UpdatePassword() of a missing user succeeded", failed)
+	}
+
+	if len(sink.records) != 3 {
+		t.Fatalf("\t%s audit records = %+v", failed, sink.records)
+	}
+	for i, want := range []struct{ database, statement, result string }{
+		{"postgres", `create database "audit_db"`, audit.ResultSucceeded},
+		{dbName, `CREATE SCHEMA IF NOT EXISTS "app" AUTHORIZATION "` + testDB.username + `"`, audit.ResultSucceeded},
+		{"postgres", `ALTER ROLE "missing_user" with encrypted password '***'`, audit.ResultFailed},
+	} {
+		rec := sink.records[i]
+		if rec.Claim != "default/audit" || rec.Component != "dbclient" || rec.Host == "" ||
+			rec.Database != want.database || rec.Statement != want.statement || rec.Result != want.result {
+			t.Errorf("\t%s audit record %d = %+v", failed, i, rec)
+		}
+	}
+	t.Logf("\t%s audit is passed", succeed)
+}
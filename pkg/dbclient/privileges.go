@@ -0,0 +1,240 @@
+package dbclient
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	persistancev1 "github.com/infobloxopen/db-controller/api/v1"
+	"github.com/infobloxopen/db-controller/pkg/metrics"
+)
+
+// TablePrivileges are the privileges accepted in custom grants
+var TablePrivileges = []string{"SELECT", "INSERT", "UPDATE", "DELETE", "TRUNCATE", "REFERENCES", "TRIGGER"}
+
+// Grant defines privileges on tables of a schema, every table of the schema when Tables is empty
+type Grant struct {
+	Privileges []string
+	Schema     string
+	Tables     []string
+}
+
+// UserPrivileges defines the privileges granted to the group role of an additional user
+type UserPrivileges struct {
+	Profile persistancev1.PrivilegeProfile
+	// OwnerRole is the group role owning the database objects, the owner profile is a member of it
+	OwnerRole string
+	// Schemas the read-write and read-only profiles apply to
+	Schemas []string
+	// Grants of the custom profile
+	Grants []Grant
+}
+
+// CreateRole creates a NOLOGIN role without any privileges
+func (pc *client) CreateRole(rolename string) (bool, error) {
+	var exists bool
+	created := false
+
+	err := pc.DB.QueryRow("SELECT EXISTS(SELECT pg_roles.rolname FROM pg_catalog.pg_roles where pg_roles.rolname = $1)", rolename).Scan(&exists)
+	if err != nil {
+		pc.log.Error(err, "could not query for role")
+		metrics.UsersCreatedErrors.WithLabelValues("read error").Inc()
+		return created, err
+	}
+	if exists {
+		return created, nil
+	}
+
+	pc.log.Info("creating a ROLE", "role", rolename)
+	if _, err := pc.exec(pc.DB, fmt.Sprintf("CREATE ROLE %s WITH NOLOGIN", pq.QuoteIdentifier(rolename))); err != nil {
+		pc.log.Error(err, "could not create role "+rolename)
+		metrics.UsersCreatedErrors.WithLabelValues("create error").Inc()
+		return created, err
+	}
+	pc.markPlanned("role", rolename)
+	created = true
+	metrics.UsersCreated.Inc()
+	return created, nil
+}
+
+// ManageUserPrivileges grants rolename the privileges of its profile in dbName.
+// Membership in the owner role and write privileges granted by a previous
+// profile are revoked. Grants apply to the objects existing at the time of the call,
+// the read-write and read-only profiles also get default privileges on the objects
+// created later by the owner role.
+func (pc *client) ManageUserPrivileges(dbName, rolename string, privileges UserPrivileges) error {
+	db, err := pc.getDB(dbName)
+	if err != nil {
+		pc.log.Error(err, "could not connect to db", "database", dbName)
+		return err
+	}
+
+	role := pq.QuoteIdentifier(rolename)
+	stmts := []string{fmt.Sprintf("GRANT CONNECT ON DATABASE %s TO %s", pq.QuoteIdentifier(dbName), role)}
+
+	var isMember bool
+	err = db.QueryRow(`SELECT EXISTS(SELECT 1 FROM pg_catalog.pg_auth_members m
+		JOIN pg_catalog.pg_roles g ON g.oid = m.roleid
+		JOIN pg_catalog.pg_roles u ON u.oid = m.member
+		WHERE g.rolname = $1 AND u.rolname = $2)`, privileges.OwnerRole, rolename).Scan(&isMember)
+	if err != nil {
+		pc.log.Error(err, "could not query role membership", "role", rolename)
+		metrics.UsersCreatedErrors.WithLabelValues("read error").Inc()
+		return err
+	}
+	owner := pq.QuoteIdentifier(privileges.OwnerRole)
+	if privileges.Profile == persistancev1.OwnerProfile && !isMember {
+		stmts = append(stmts, fmt.Sprintf("GRANT %s TO %s", owner, role))
+	}
+	if privileges.Profile != persistancev1.OwnerProfile && isMember {
+		stmts = append(stmts, fmt.Sprintf("REVOKE %s FROM %s", owner, role))
+	}
+
+	// default privileges of the owner role, only set by the read-write and read-only profiles
+	defaults := "ALTER DEFAULT PRIVILEGES FOR ROLE " + owner + " IN SCHEMA "
+	switch privileges.Profile {
+	case persistancev1.OwnerProfile:
+		for _, s := range privileges.Schemas {
+			schema := pq.QuoteIdentifier(s)
+			stmts = append(stmts,
+				fmt.Sprintf("%s%s REVOKE ALL ON TABLES FROM %s", defaults, schema, role),
+				fmt.Sprintf("%s%s REVOKE ALL ON SEQUENCES FROM %s", defaults, schema, role),
+			)
+		}
+	case persistancev1.ReadWriteProfile:
+		for _, s := range privileges.Schemas {
+			schema := pq.QuoteIdentifier(s)
+			stmts = append(stmts,
+				fmt.Sprintf("GRANT USAGE ON SCHEMA %s TO %s", schema, role),
+				fmt.Sprintf("GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA %s TO %s", schema, role),
+				fmt.Sprintf("GRANT USAGE, SELECT, UPDATE ON ALL SEQUENCES IN SCHEMA %s TO %s", schema, role),
+				fmt.Sprintf("%s%s GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO %s", defaults, schema, role),
+				fmt.Sprintf("%s%s GRANT USAGE, SELECT, UPDATE ON SEQUENCES TO %s", defaults, schema, role),
+			)
+		}
+	case persistancev1.ReadOnlyProfile:
+		for _, s := range privileges.Schemas {
+			schema := pq.QuoteIdentifier(s)
+			stmts = append(stmts,
+				fmt.Sprintf("GRANT USAGE ON SCHEMA %s TO %s", schema, role),
+				fmt.Sprintf("REVOKE INSERT, UPDATE, DELETE, TRUNCATE ON ALL TABLES IN SCHEMA %s FROM %s", schema, role),
+				fmt.Sprintf("GRANT SELECT ON ALL TABLES IN SCHEMA %s TO %s", schema, role),
+				fmt.Sprintf("REVOKE UPDATE ON ALL SEQUENCES IN SCHEMA %s FROM %s", schema, role),
+				fmt.Sprintf("GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA %s TO %s", schema, role),
+				fmt.Sprintf("%s%s REVOKE INSERT, UPDATE, DELETE, TRUNCATE ON TABLES FROM %s", defaults, schema, role),
+				fmt.Sprintf("%s%s GRANT SELECT ON TABLES TO %s", defaults, schema, role),
+				fmt.Sprintf("%s%s REVOKE UPDATE ON SEQUENCES FROM %s", defaults, schema, role),
+				fmt.Sprintf("%s%s GRANT USAGE, SELECT ON SEQUENCES TO %s", defaults, schema, role),
+			)
+		}
+	case persistancev1.CustomProfile:
+		for _, s := range privileges.Schemas {
+			schema := pq.QuoteIdentifier(s)
+			stmts = append(stmts,
+				fmt.Sprintf("%s%s REVOKE ALL ON TABLES FROM %s", defaults, schema, role),
+				fmt.Sprintf("%s%s REVOKE ALL ON SEQUENCES FROM %s", defaults, schema, role),
+			)
+		}
+		for _, g := range privileges.Grants {
+			for _, p := range g.Privileges {
+				if !containsFold(TablePrivileges, p) {
+					return fmt.Errorf("unsupported privilege %s", p)
+				}
+			}
+			schema := pq.QuoteIdentifier(g.Schema)
+			privs := strings.ToUpper(strings.Join(g.Privileges, ", "))
+			stmts = append(stmts, fmt.Sprintf("GRANT USAGE ON SCHEMA %s TO %s", schema, role))
+			if len(g.Tables) == 0 {
+				stmts = append(stmts, fmt.Sprintf("GRANT %s ON ALL TABLES IN SCHEMA %s TO %s", privs, schema, role))
+				continue
+			}
+			tables := make([]string, 0, len(g.Tables))
+			for _, t := range g.Tables {
+				tables = append(tables, schema+"."+pq.QuoteIdentifier(t))
+			}
+			stmts = append(stmts, fmt.Sprintf("GRANT %s ON TABLE %s TO %s", privs, strings.Join(tables, ", "), role))
+		}
+	default:
+		return fmt.Errorf("unknown privilege profile %s", privileges.Profile)
+	}
+
+	for _, stmt := range stmts {
+		if _, err := pc.exec(db, stmt); err != nil {
+			pc.log.Error(err, "could not set privileges of role "+rolename, "statement", stmt)
+			metrics.UsersCreatedErrors.WithLabelValues("grant error").Inc()
+			return fmt.Errorf("could not set privileges of role %s: %s", rolename, err)
+		}
+	}
+	return nil
+}
+
+// containsFold reports whether name is in list, ignoring case
+func containsFold(list []string, name string) bool {
+	for _, p := range list {
+		if strings.EqualFold(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetConnectionLimit sets the connection limit of username, -1 removes the limit.
+// Missing roles are skipped.
+func (pc *client) SetConnectionLimit(username string, limit int) error {
+	var current int
+	err := pc.DB.QueryRow("SELECT rolconnlimit FROM pg_catalog.pg_roles WHERE rolname = $1", username).Scan(&current)
+	if err == sql.ErrNoRows && pc.isPlanned("role", username) {
+		current, err = -1, nil
+	}
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		pc.log.Error(err, "could not query for role")
+		metrics.UsersUpdatedErrors.WithLabelValues("read error").Inc()
+		return err
+	}
+	if current == limit {
+		return nil
+	}
+
+	pc.log.Info("setting connection limit", "role", username, "limit", limit)
+	if _, err := pc.exec(pc.DB, fmt.Sprintf("ALTER ROLE %s CONNECTION LIMIT %d", pq.QuoteIdentifier(username), limit)); err != nil {
+		pc.log.Error(err, "could not set connection limit of role "+username)
+		metrics.UsersUpdatedErrors.WithLabelValues("alter error").Inc()
+		return err
+	}
+	return nil
+}
+
+// SetLogin allows or denies username to log in, missing roles are skipped.
+// Sessions opened before a login is denied are kept.
+func (pc *client) SetLogin(username string, login bool) error {
+	var canLogin bool
+	err := pc.DB.QueryRow("SELECT rolcanlogin FROM pg_catalog.pg_roles WHERE rolname = $1", username).Scan(&canLogin)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		pc.log.Error(err, "could not query for role")
+		metrics.UsersUpdatedErrors.WithLabelValues("read error").Inc()
+		return err
+	}
+	if canLogin == login {
+		return nil
+	}
+
+	attribute := "NOLOGIN"
+	if login {
+		attribute = "LOGIN"
+	}
+	pc.log.Info("altering login of role", "role", username, "attribute", attribute)
+	if _, err := pc.exec(pc.DB, fmt.Sprintf("ALTER ROLE %s %s", pq.QuoteIdentifier(username), attribute)); err != nil {
+		pc.log.Error(err, "could not alter login of role "+username)
+		metrics.UsersUpdatedErrors.WithLabelValues("alter error").Inc()
+		return err
+	}
+	return nil
+}
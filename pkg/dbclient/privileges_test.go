@@ -0,0 +1,163 @@
+package dbclient
+
+import (
+	"testing"
+
+	persistancev1 "github.com/infobloxopen/db-controller/api/v1"
+)
+
+func TestPostgresClientUserPrivileges(t *testing.T) {
+	_, pc := setupClient(t)
+
+	dbName := "privileges_db"
+	owner := "privileges_owner"
+	role := "privileges_user"
+	if _, err := pc.CreateDatabase(dbName); err != nil {
+		t.Fatalf("\t%s CreateDatabase() error = %v", failed, err)
+	}
+	if _, err := pc.CreateGroup(dbName, owner); err != nil {
+		t.Fatalf("\t%s CreateGroup() error = %v", failed, err)
+	}
+	db, err := pc.getDB(dbName)
+	if err != nil {
+		t.Fatalf("\t%s getDB() error = %v", failed, err)
+	}
+	if _, err := db.Exec("CREATE TABLE jobs (id serial PRIMARY KEY)"); err != nil {
+		t.Fatalf("\t%s create table error = %v", failed, err)
+	}
+
+	t.Logf("CreateRole()")
+	created, err := pc.CreateRole(role)
+	if err != nil || !created {
+		t.Fatalf("\t%s CreateRole() created = %v, error = %v", failed, created, err)
+	}
+	created, err = pc.CreateRole(role)
+	if err != nil || created {
+		t.Fatalf("\t%s CreateRole() second call created = %v, error = %v", failed, created, err)
+	}
+	t.Logf("\t%s CreateRole() is passed", succeed)
+
+	hasPrivilege := func(t *testing.T, table, privilege string) bool {
+		var granted bool
+		if err := db.QueryRow("SELECT has_table_privilege($1::name, $2::text, $3::text)", role, table, privilege).Scan(&granted); err != nil {
+			t.Fatalf("\t%s has_table_privilege() error = %v", failed, err)
+		}
+		return granted
+	}
+	isMember := func(t *testing.T) bool {
+		var member bool
+		if err := db.QueryRow("SELECT pg_has_role($1::name, $2::name, 'MEMBER')", role, owner).Scan(&member); err != nil {
+			t.Fatalf("\t%s pg_has_role() error = %v", failed, err)
+		}
+		return member
+	}
+
+	// the cases run in order against the same role, after runs once the privileges are
+	// managed and the tables of granted must have the privileges listed
+	tests := []struct {
+		name       string
+		privileges UserPrivileges
+		after      string
+		granted    map[string][]string
+		denied     []string
+		wantMember bool
+		wantErr    bool
+	}{
+		{
+			// the owner role creates a table, the default privileges must cover it
+			name:       "read-write",
+			privileges: UserPrivileges{Profile: persistancev1.ReadWriteProfile, OwnerRole: owner, Schemas: []string{"public"}},
+			after:      "GRANT CREATE ON SCHEMA public TO privileges_owner; SET ROLE privileges_owner; CREATE TABLE later (id int); RESET ROLE",
+			granted:    map[string][]string{"public.jobs": {"SELECT", "INSERT"}, "public.later": {"INSERT"}},
+			denied:     []string{"TRUNCATE"},
+		},
+		{
+			name:       "read-only",
+			privileges: UserPrivileges{Profile: persistancev1.ReadOnlyProfile, OwnerRole: owner, Schemas: []string{"public"}},
+			granted:    map[string][]string{"public.jobs": {"SELECT"}},
+			denied:     []string{"INSERT"},
+		},
+		{
+			name:       "owner",
+			privileges: UserPrivileges{Profile: persistancev1.OwnerProfile, OwnerRole: owner, Schemas: []string{"public"}},
+			granted:    map[string][]string{"public.jobs": {"TRUNCATE"}},
+			wantMember: true,
+		},
+		{
+			name: "custom",
+			privileges: UserPrivileges{Profile: persistancev1.CustomProfile, OwnerRole: owner,
+				Grants: []Grant{{Privileges: []string{"select", "trigger"}, Schema: "public", Tables: []string{"jobs"}}}},
+			granted: map[string][]string{"public.jobs": {"TRIGGER"}},
+		},
+		{
+			name: "unsupported privilege",
+			privileges: UserPrivileges{Profile: persistancev1.CustomProfile, OwnerRole: owner,
+				Grants: []Grant{{Privileges: []string{"SELECT ON jobs TO PUBLIC; --"}, Schema: "public", Tables: []string{"jobs"}}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := pc.ManageUserPrivileges(dbName, role, tt.privileges)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("\t%s ManageUserPrivileges() error = %v, wantErr %v", failed, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if tt.after != "" {
+				if _, err := db.Exec(tt.after); err != nil {
+					t.Fatalf("\t%s %s error = %v", failed, tt.after, err)
+				}
+			}
+			for table, privileges := range tt.granted {
+				for _, privilege := range privileges {
+					if !hasPrivilege(t, table, privilege) {
+						t.Errorf("\t%s %s is not granted on %s", failed, privilege, table)
+					}
+				}
+			}
+			for _, privilege := range tt.denied {
+				if hasPrivilege(t, "public.jobs", privilege) {
+					t.Errorf("\t%s %s is granted on public.jobs", failed, privilege)
+				}
+			}
+			if member := isMember(t); member != tt.wantMember {
+				t.Errorf("\t%s member of %s = %v, want %v", failed, owner, member, tt.wantMember)
+			}
+			t.Logf("\t%s %s is passed", succeed, tt.name)
+		})
+	}
+
+	t.Logf("SetConnectionLimit()")
+	if err := pc.SetConnectionLimit(role, 3); err != nil {
+		t.Fatalf("\t%s SetConnectionLimit() error = %v", failed, err)
+	}
+	var limit int
+	if err := pc.DB.QueryRow("SELECT rolconnlimit FROM pg_catalog.pg_roles WHERE rolname = $1", role).Scan(&limit); err != nil || limit != 3 {
+		t.Errorf("\t%s SetConnectionLimit() limit = %d, error = %v", failed, limit, err)
+	}
+	if err := pc.SetConnectionLimit("missing_role", 3); err != nil {
+		t.Errorf("\t%s SetConnectionLimit() of missing role error = %v", failed, err)
+	}
+	t.Logf("\t%s SetConnectionLimit() is passed", succeed)
+
+	t.Logf("SetLogin()")
+	canLogin := func() bool {
+		var login bool
+		if err := pc.DB.QueryRow("SELECT rolcanlogin FROM pg_catalog.pg_roles WHERE rolname = $1", role).Scan(&login); err != nil {
+			t.Fatalf("\t%s query rolcanlogin error = %v", failed, err)
+		}
+		return login
+	}
+	if err := pc.SetLogin(role, true); err != nil || !canLogin() {
+		t.Errorf("\t%s SetLogin(true) error = %v", failed, err)
+	}
+	if err := pc.SetLogin(role, false); err != nil || canLogin() {
+		t.Errorf("\t%s SetLogin(false) error = %v", failed, err)
+	}
+	if err := pc.SetLogin("missing_role", false); err != nil {
+		t.Errorf("\t%s SetLogin() of missing role error = %v", failed, err)
+	}
+	t.Logf("\t%s SetLogin() is passed", succeed)
+}
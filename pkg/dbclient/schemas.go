@@ -0,0 +1,206 @@
+package dbclient
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"github.com/infobloxopen/db-controller/pkg/metrics"
+)
+
+const publicSchema = "public"
+
+// CreateSchema creates schemaName in dbName owned by rolename
+func (pc *client) CreateSchema(dbName, schemaName, rolename string) (bool, error) {
+	var exists bool
+	created := false
+
+	db, err := pc.getDB(dbName)
+	if err != nil {
+		pc.log.Error(err, "could not connect to db", "database", dbName)
+		return created, err
+	}
+
+	err = db.QueryRow("SELECT EXISTS(SELECT nspname FROM pg_catalog.pg_namespace WHERE nspname = $1)", schemaName).Scan(&exists)
+	if err != nil {
+		pc.log.Error(err, "could not query for schema")
+		metrics.SchemaErrors.WithLabelValues("read error").Inc()
+		return created, err
+	}
+	if exists {
+		return created, nil
+	}
+
+	pc.log.Info("creating schema", "database", dbName, "schema", schemaName, "owner", rolename)
+	if _, err := pc.exec(db, fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s AUTHORIZATION %s",
+		pq.QuoteIdentifier(schemaName), pq.QuoteIdentifier(rolename))); err != nil {
+		pc.log.Error(err, "could not create schema", "schema", schemaName)
+		metrics.SchemaErrors.WithLabelValues("create error").Inc()
+		return created, err
+	}
+	pc.markPlanned("schema", dbName+"."+schemaName)
+	created = true
+	return created, nil
+}
+
+// schemaCheck is a drift check of the privileges of a role in a schema
+type schemaCheck struct {
+	drift string
+	query string
+	args  []interface{}
+	// repair is executed when query returns true
+	repair string
+}
+
+// ManageSchemaPrivileges checks the ownership, the grants and the default privileges
+// of rolename in schemaName and repairs any drift. Default privileges are checked for
+// every role creating objects in the schema. The returned list describes the drift
+// that was repaired.
+func (pc *client) ManageSchemaPrivileges(dbName, schemaName, rolename string) ([]string, error) {
+	db, err := pc.getDB(dbName)
+	if err != nil {
+		pc.log.Error(err, "could not connect to db", "database", dbName)
+		return nil, err
+	}
+
+	quotedSchema := pq.QuoteIdentifier(schemaName)
+	quotedRole := pq.QuoteIdentifier(rolename)
+
+	checks := []schemaCheck{
+		{
+			drift: "schema owner",
+			query: `SELECT pg_catalog.pg_get_userbyid(nspowner) <> $2
+				FROM pg_catalog.pg_namespace WHERE nspname = $1`,
+			args:   []interface{}{schemaName, rolename},
+			repair: fmt.Sprintf("ALTER SCHEMA %s OWNER TO %s", quotedSchema, quotedRole),
+		},
+		{
+			drift:  "schema privileges",
+			query:  `SELECT NOT (has_schema_privilege($2::name, $1::text, 'USAGE') AND has_schema_privilege($2::name, $1::text, 'CREATE'))`,
+			args:   []interface{}{schemaName, rolename},
+			repair: fmt.Sprintf("GRANT ALL ON SCHEMA %s TO %s", quotedSchema, quotedRole),
+		},
+		{
+			drift: "table privileges",
+			query: `SELECT EXISTS(SELECT 1 FROM pg_catalog.pg_class c
+				JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+				WHERE n.nspname = $1 AND c.relkind IN ('r', 'p', 'v', 'm', 'f')
+				AND NOT (has_table_privilege($2::name, c.oid, 'SELECT') AND has_table_privilege($2::name, c.oid, 'INSERT')
+					AND has_table_privilege($2::name, c.oid, 'UPDATE') AND has_table_privilege($2::name, c.oid, 'DELETE')
+					AND has_table_privilege($2::name, c.oid, 'TRUNCATE') AND has_table_privilege($2::name, c.oid, 'REFERENCES')
+					AND has_table_privilege($2::name, c.oid, 'TRIGGER')))`,
+			args:   []interface{}{schemaName, rolename},
+			repair: fmt.Sprintf("GRANT ALL ON ALL TABLES IN SCHEMA %s TO %s", quotedSchema, quotedRole),
+		},
+		{
+			drift: "sequence privileges",
+			query: `SELECT EXISTS(SELECT 1 FROM pg_catalog.pg_class c
+				JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+				WHERE n.nspname = $1 AND c.relkind = 'S'
+				AND NOT (has_sequence_privilege($2::name, c.oid, 'USAGE') AND has_sequence_privilege($2::name, c.oid, 'SELECT')
+					AND has_sequence_privilege($2::name, c.oid, 'UPDATE')))`,
+			args:   []interface{}{schemaName, rolename},
+			repair: fmt.Sprintf("GRANT ALL ON ALL SEQUENCES IN SCHEMA %s TO %s", quotedSchema, quotedRole),
+		},
+	}
+
+	// default privileges only apply to the objects created by the role they are
+	// defined for, they are set for every role creating objects in the schema
+	creators, err := pc.schemaCreators(db, rolename)
+	if err != nil {
+		pc.log.Error(err, "could not query for roles", "role", rolename)
+		metrics.SchemaErrors.WithLabelValues("read error").Inc()
+		return nil, err
+	}
+	for _, creator := range creators {
+		for _, objects := range []struct{ kind, objType, keyword string }{
+			{"table", "r", "TABLES"},
+			{"sequence", "S", "SEQUENCES"},
+			{"function", "f", "FUNCTIONS"},
+		} {
+			drift := fmt.Sprintf("default %s privileges", objects.kind)
+			forRole := ""
+			if creator != "" {
+				drift += " of " + creator
+				forRole = " FOR ROLE " + pq.QuoteIdentifier(creator)
+			}
+			checks = append(checks, schemaCheck{
+				drift: drift,
+				query: defaultPrivilegesMissingQuery,
+				args:  []interface{}{schemaName, rolename, objects.objType, creator},
+				repair: fmt.Sprintf("ALTER DEFAULT PRIVILEGES%s IN SCHEMA %s GRANT ALL ON %s TO %s",
+					forRole, quotedSchema, objects.keyword, quotedRole),
+			})
+		}
+	}
+
+	var repaired []string
+	for _, c := range checks {
+		// the public schema belongs to the database owner and is shared by every role
+		if c.drift == "schema owner" && schemaName == publicSchema {
+			continue
+		}
+		// objects created by a plan do not exist yet, every grant is planned
+		drifted := pc.isPlanned("schema", dbName+"."+schemaName) || pc.isPlanned("role", rolename)
+		if drifted && c.drift == "schema owner" {
+			continue
+		}
+		var err error
+		if !drifted {
+			err = db.QueryRow(c.query, c.args...).Scan(&drifted)
+		}
+		if err != nil && err != sql.ErrNoRows {
+			pc.log.Error(err, "could not query for "+c.drift, "database", dbName, "schema", schemaName)
+			metrics.SchemaErrors.WithLabelValues("read error").Inc()
+			return repaired, err
+		}
+		if !drifted {
+			continue
+		}
+		pc.log.Info("repairing drift", "database", dbName, "schema", schemaName, "role", rolename, "drift", c.drift)
+		if _, err := pc.exec(db, c.repair); err != nil {
+			pc.log.Error(err, "could not repair "+c.drift, "database", dbName, "schema", schemaName)
+			metrics.SchemaErrors.WithLabelValues("grant error").Inc()
+			return repaired, fmt.Errorf("could not repair %s of schema %s: %s", c.drift, schemaName, err)
+		}
+		repaired = append(repaired, c.drift)
+	}
+	return repaired, nil
+}
+
+// schemaCreators returns the roles creating objects in the schemas of rolename: the
+// connected user, returned as "", rolename and the logins that are members of rolename
+func (pc *client) schemaCreators(db *sql.DB, rolename string) ([]string, error) {
+	creators := []string{"", rolename}
+	if pc.isPlanned("role", rolename) {
+		return creators, nil
+	}
+	rows, err := db.Query(`SELECT u.rolname FROM pg_catalog.pg_auth_members m
+		JOIN pg_catalog.pg_roles g ON g.oid = m.roleid
+		JOIN pg_catalog.pg_roles u ON u.oid = m.member
+		WHERE g.rolname = $1 AND u.rolcanlogin AND u.rolname <> current_user
+		ORDER BY 1`, rolename)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var login string
+		if err := rows.Scan(&login); err != nil {
+			return nil, err
+		}
+		creators = append(creators, login)
+	}
+	return creators, rows.Err()
+}
+
+// defaultPrivilegesMissingQuery reports whether the default privileges of role $4,
+// the connected user when empty, in schema $1 do not grant objects of type $3 to role $2
+const defaultPrivilegesMissingQuery = `SELECT NOT EXISTS(SELECT 1 FROM pg_catalog.pg_default_acl d
+	JOIN pg_catalog.pg_namespace n ON n.oid = d.defaclnamespace,
+	LATERAL aclexplode(d.defaclacl) a
+	WHERE n.nspname = $1
+	AND d.defaclrole = (SELECT oid FROM pg_catalog.pg_roles WHERE rolname = COALESCE(NULLIF($4, ''), current_user))
+	AND d.defaclobjtype = $3
+	AND a.grantee = (SELECT oid FROM pg_catalog.pg_roles WHERE rolname = $2))`
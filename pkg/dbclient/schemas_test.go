@@ -0,0 +1,124 @@
+package dbclient
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPostgresClientSchemas(t *testing.T) {
+	_, pc := setupClient(t)
+
+	dbName := "schema_db"
+	role := "schema_role"
+	schema := "app"
+	if _, err := pc.CreateDatabase(dbName); err != nil {
+		t.Fatalf("\t%s CreateDatabase() error = %v", failed, err)
+	}
+	if _, err := pc.CreateGroup(dbName, role); err != nil {
+		t.Fatalf("\t%s CreateGroup() error = %v", failed, err)
+	}
+
+	t.Logf("CreateSchema()")
+	created, err := pc.CreateSchema(dbName, schema, role)
+	if err != nil || !created {
+		t.Fatalf("\t%s CreateSchema() created = %v, error = %v", failed, created, err)
+	}
+	created, err = pc.CreateSchema(dbName, schema, role)
+	if err != nil || created {
+		t.Fatalf("\t%s CreateSchema() second call created = %v, error = %v", failed, created, err)
+	}
+	t.Logf("\t%s CreateSchema() is passed", succeed)
+
+	db, err := pc.getDB(dbName)
+	if err != nil {
+		t.Fatalf("\t%s getDB() error = %v", failed, err)
+	}
+
+	// the cases run in order against the same schema, tables created by the statements
+	// of a case must be covered by the default privileges
+	tests := []struct {
+		name         string
+		before       []string
+		after        []string
+		covered      string
+		wantRepaired []string
+		// wantIncludes lists repairs expected among others
+		wantIncludes []string
+		anyRepaired  bool
+	}{
+		{
+			name:        "initial grants",
+			anyRepaired: true,
+		},
+		{
+			// the master user creates a table
+			name:    "without drift",
+			after:   []string{"CREATE TABLE app.covered (id int)"},
+			covered: "app.covered",
+		},
+		{
+			// a login of the role creates a table, default privileges must be set for it too
+			name:   "new login",
+			before: []string{"CREATE ROLE schema_login LOGIN IN ROLE schema_role"},
+			after:  []string{"SET ROLE schema_login; CREATE TABLE app.by_login (id int); RESET ROLE"},
+			wantRepaired: []string{
+				"default table privileges of schema_login",
+				"default sequence privileges of schema_login",
+				"default function privileges of schema_login",
+			},
+			covered: "app.by_login",
+		},
+		{
+			name: "with drift",
+			before: []string{
+				"REVOKE ALL ON app.covered FROM schema_role",
+				"ALTER SCHEMA app OWNER TO CURRENT_USER",
+				"ALTER DEFAULT PRIVILEGES IN SCHEMA app REVOKE ALL ON SEQUENCES FROM schema_role",
+			},
+			wantIncludes: []string{"schema owner", "table privileges", "default sequence privileges"},
+			covered:      "app.covered",
+		},
+		{
+			name: "after repair",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, stmt := range tt.before {
+				if _, err := db.Exec(stmt); err != nil {
+					t.Fatalf("\t%s %s error = %v", failed, stmt, err)
+				}
+			}
+			repaired, err := pc.ManageSchemaPrivileges(dbName, schema, role)
+			if err != nil {
+				t.Fatalf("\t%s ManageSchemaPrivileges() error = %v", failed, err)
+			}
+			switch {
+			case tt.anyRepaired:
+				t.Logf("\t%s initial grants %v", succeed, repaired)
+			case tt.wantIncludes != nil:
+				for _, want := range tt.wantIncludes {
+					if !containsFold(repaired, want) {
+						t.Errorf("\t%s ManageSchemaPrivileges() repaired = %v, missing %s", failed, repaired, want)
+					}
+				}
+			case len(repaired) != 0 || len(tt.wantRepaired) != 0:
+				if !reflect.DeepEqual(repaired, tt.wantRepaired) {
+					t.Errorf("\t%s ManageSchemaPrivileges() repaired = %v, want %v", failed, repaired, tt.wantRepaired)
+				}
+			}
+			for _, stmt := range tt.after {
+				if _, err := db.Exec(stmt); err != nil {
+					t.Fatalf("\t%s %s error = %v", failed, stmt, err)
+				}
+			}
+			if tt.covered != "" {
+				var granted bool
+				if err := db.QueryRow("SELECT has_table_privilege($1::name, $2::text, 'DELETE')", role, tt.covered).Scan(&granted); err != nil || !granted {
+					t.Errorf("\t%s default privileges do not cover %s, granted = %v, error = %v", failed, tt.covered, granted, err)
+				}
+			}
+			t.Logf("\t%s %s is passed", succeed, tt.name)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package dbclient
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	scramIterations = 4096
+	scramSaltLength = 16
+)
+
+// scramSHA256Verifier computes the SCRAM-SHA-256 verifier postgres stores for password,
+// so the plaintext password is never sent to the server. The format matches the one
+// of PQencryptPasswordConn: SCRAM-SHA-256$<iterations>:<salt>$<StoredKey>:<ServerKey>
+func scramSHA256Verifier(password string) (string, error) {
+	salt := make([]byte, scramSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("could not generate salt: %s", err)
+	}
+	return scramSHA256VerifierWithSalt(password, salt, scramIterations), nil
+}
+
+func scramSHA256VerifierWithSalt(password string, salt []byte, iterations int) string {
+	saltedPassword := pbkdf2.Key([]byte(saslPrep(password)), salt, iterations, sha256.Size, sha256.New)
+	clientKey := scramHMAC(saltedPassword, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	serverKey := scramHMAC(saltedPassword, "Server Key")
+
+	enc := base64.StdEncoding
+	return fmt.Sprintf("SCRAM-SHA-256$%d:%s$%s:%s", iterations, enc.EncodeToString(salt),
+		enc.EncodeToString(storedKey[:]), enc.EncodeToString(serverKey))
+}
+
+func scramHMAC(key []byte, msg string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(msg))
+	return mac.Sum(nil)
+}
+
+// saslPrep normalizes password like postgres does before hashing it. Passwords that
+// are not valid UTF-8 or contain prohibited characters are used as they are, which
+// is what the server does for them as well.
+func saslPrep(password string) string {
+	if !utf8.ValidString(password) {
+		return password
+	}
+	var b strings.Builder
+	for _, r := range password {
+		switch {
+		case isMappedToNothing(r):
+		case r != ' ' && unicode.Is(unicode.Zs, r):
+			b.WriteRune(' ')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	prepared := norm.NFKC.String(b.String())
+	for _, r := range prepared {
+		if unicode.IsControl(r) || unicode.Is(unicode.Co, r) || unicode.Is(unicode.Cs, r) || r == utf8.RuneError {
+			return password
+		}
+	}
+	return prepared
+}
+
+// isMappedToNothing reports whether r is in table B.1 of RFC 3454
+func isMappedToNothing(r rune) bool {
+	switch {
+	case r == 0x00AD, r == 0x034F, r == 0x1806, r == 0x2060, r == 0xFEFF:
+		return true
+	case r >= 0x180B && r <= 0x180D, r >= 0x200B && r <= 0x200D, r >= 0xFE00 && r <= 0xFE0F:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package dbclient
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestScramSHA256VerifierWithSalt(t *testing.T) {
+	// salt and password of the example of RFC 7677
+	salt, _ := base64.StdEncoding.DecodeString("W22ZaJ0SNY7soEsUEjb6gQ==")
+	want := "SCRAM-SHA-256$4096:W22ZaJ0SNY7soEsUEjb6gQ==$WG5d8oPm3OtcPnkdi4Uo7BkeZkBFzpcXkuLmtbsT4qY=:wfPLwcE6nTWhTAmQ7tl2KeoiWGPlZqQxSrmfPwDl2dU="
+	if got := scramSHA256VerifierWithSalt("pencil", salt, 4096); got != want {
+		t.Errorf("scramSHA256VerifierWithSalt() = %v, want %v", got, want)
+	}
+}
+
+func TestScramSHA256Verifier(t *testing.T) {
+	v1, err := scramSHA256Verifier("secret")
+	if err != nil {
+		t.Fatalf("scramSHA256Verifier() error = %v", err)
+	}
+	v2, _ := scramSHA256Verifier("secret")
+	if !strings.HasPrefix(v1, "SCRAM-SHA-256$4096:") || strings.Contains(v1, "secret") {
+		t.Errorf("scramSHA256Verifier() = %v", v1)
+	}
+	if v1 == v2 {
+		t.Errorf("scramSHA256Verifier() reused the salt")
+	}
+}
+
+func TestSaslPrep(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		want     string
+	}{
+		{"ascii", "pa@ss$){[d~", "pa@ss$){[d~"},
+		{"mapped to nothing", "I\u00adX", "IX"},
+		{"non-ascii space", "a\u00a0b", "a b"},
+		{"compatibility", "\u2168", "IX"},
+		{"prohibited", "a\u0007b", "a\u0007b"},
+		{"invalid utf8", "a\xffb", "a\xffb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := saslPrep(tt.password); got != tt.want {
+				t.Errorf("saslPrep() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostgresClientScramPassword(t *testing.T) {
+	testDB, pc := setupClient(t)
+
+	dbName := "scram_db"
+	role := "scram_group"
+	user := "scram_user"
+	if _, err := pc.CreateDatabase(dbName); err != nil {
+		t.Fatalf("\t%s CreateDatabase() error = %v", failed, err)
+	}
+	if _, err := pc.CreateGroup(dbName, role); err != nil {
+		t.Fatalf("\t%s CreateGroup() error = %v", failed, err)
+	}
+
+	login := func(password string) error {
+		db, err := testDB.OpenUser(dbName, user, password)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		return db.Ping()
+	}
+
+	// the cases run in order against the same user
+	tests := []struct {
+		name         string
+		setPassword  func(password string) error
+		password     string
+		oldPasswords []string
+	}{
+		{
+			name:        "CreateUser()",
+			setPassword: func(password string) error { _, err := pc.CreateUser(user, role, password); return err },
+			password:    "first-p@ss",
+		},
+		{
+			name:         "UpdatePassword()",
+			setPassword:  func(password string) error { return pc.UpdatePassword(user, password) },
+			password:     "second",
+			oldPasswords: []string{"first-p@ss"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.setPassword(tt.password); err != nil {
+				t.Fatalf("\t%s %s error = %v", failed, tt.name, err)
+			}
+			var hash string
+			if err := pc.DB.QueryRow("SELECT rolpassword FROM pg_catalog.pg_authid WHERE rolname = $1", user).Scan(&hash); err != nil {
+				t.Fatalf("\t%s query pg_authid error = %v", failed, err)
+			}
+			if !strings.HasPrefix(hash, "SCRAM-SHA-256$") {
+				t.Fatalf("\t%s %s stored %s", failed, tt.name, hash)
+			}
+			if err := login(tt.password); err != nil {
+				t.Fatalf("\t%s login error = %v", failed, err)
+			}
+			for _, old := range tt.oldPasswords {
+				if err := login(old); err == nil {
+					t.Fatalf("\t%s login with the previous password succeeded", failed)
+				}
+			}
+			t.Logf("\t%s %s is passed", succeed, tt.name)
+		})
+	}
+}
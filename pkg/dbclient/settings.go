@@ -0,0 +1,132 @@
+package dbclient
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/infobloxopen/db-controller/pkg/metrics"
+)
+
+// listSettings take a comma separated list of values
+var listSettings = []string{"search_path", "temp_tablespaces", "local_preload_libraries", "session_preload_libraries"}
+
+var settingNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// IsValidSettingName reports whether name can be used as a configuration parameter name
+func IsValidSettingName(name string) bool {
+	return settingNameRegexp.MatchString(name)
+}
+
+// ManageDatabaseSettings sets settings as defaults of dbName with ALTER DATABASE ... SET
+// and resets the settings in reset. The returned list holds the settings that were
+// changed because they differed from the requested value.
+func (pc *client) ManageDatabaseSettings(dbName string, settings map[string]string, reset []string) ([]string, error) {
+	return pc.manageSettings("DATABASE", dbName, `SELECT s.setconfig FROM pg_catalog.pg_database d
+		LEFT JOIN pg_catalog.pg_db_role_setting s ON s.setdatabase = d.oid AND s.setrole = 0
+		WHERE d.datname = $1`, settings, reset)
+}
+
+// ManageRoleSettings sets settings as defaults of rolename with ALTER ROLE ... SET
+// and resets the settings in reset. Missing roles are skipped. The returned list
+// holds the settings that were changed because they differed from the requested value.
+func (pc *client) ManageRoleSettings(rolename string, settings map[string]string, reset []string) ([]string, error) {
+	return pc.manageSettings("ROLE", rolename, `SELECT s.setconfig FROM pg_catalog.pg_roles r
+		LEFT JOIN pg_catalog.pg_db_role_setting s ON s.setrole = r.oid AND s.setdatabase = 0
+		WHERE r.rolname = $1`, settings, reset)
+}
+
+func (pc *client) manageSettings(objectType, name, query string, settings map[string]string, reset []string) ([]string, error) {
+	var setconfig pq.StringArray
+	err := pc.DB.QueryRow(query, name).Scan(&setconfig)
+	if err == sql.ErrNoRows && pc.isPlanned(strings.ToLower(objectType), name) {
+		err = nil
+	}
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		pc.log.Error(err, "could not query settings", "object", objectType, "name", name)
+		metrics.SettingsErrors.WithLabelValues("read error").Inc()
+		return nil, err
+	}
+	current := map[string]string{}
+	for _, s := range setconfig {
+		if kv := strings.SplitN(s, "=", 2); len(kv) == 2 {
+			current[kv[0]] = kv[1]
+		}
+	}
+
+	var stmts, changed []string
+	// postgres stores setting names in lowercase
+	desired := make(map[string]string, len(settings))
+	for setting, value := range settings {
+		desired[strings.ToLower(setting)] = value
+	}
+	for setting, value := range desired {
+		if !IsValidSettingName(setting) {
+			return nil, fmt.Errorf("invalid setting name %s", setting)
+		}
+		if v, ok := current[setting]; ok && v == normalizeSettingValue(setting, value) {
+			continue
+		}
+		stmts = append(stmts, fmt.Sprintf("ALTER %s %s SET %s TO %s", objectType, pq.QuoteIdentifier(name),
+			setting, quoteSettingValue(setting, value)))
+		changed = append(changed, setting)
+	}
+	for _, setting := range reset {
+		setting = strings.ToLower(setting)
+		if _, ok := current[setting]; !ok || !IsValidSettingName(setting) {
+			continue
+		}
+		if _, ok := desired[setting]; ok {
+			continue
+		}
+		stmts = append(stmts, fmt.Sprintf("ALTER %s %s RESET %s", objectType, pq.QuoteIdentifier(name), setting))
+	}
+
+	for _, stmt := range stmts {
+		pc.log.Info("applying setting", "statement", stmt)
+		if _, err := pc.exec(pc.DB, stmt); err != nil {
+			pc.log.Error(err, "could not apply setting", "object", objectType, "name", name)
+			metrics.SettingsErrors.WithLabelValues("alter error").Inc()
+			return nil, fmt.Errorf("could not apply settings of %s %s: %s", strings.ToLower(objectType), name, err)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+var plainIdentifierRegexp = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// normalizeSettingValue returns value the way postgres stores it in pg_db_role_setting,
+// elements of list settings are quoted like identifiers
+func normalizeSettingValue(setting, value string) string {
+	if !containsFold(listSettings, setting) {
+		return value
+	}
+	parts := strings.Split(value, ",")
+	for i, p := range parts {
+		p = strings.TrimSpace(p)
+		if !plainIdentifierRegexp.MatchString(p) {
+			p = pq.QuoteIdentifier(p)
+		}
+		parts[i] = p
+	}
+	return strings.Join(parts, ", ")
+}
+
+func quoteSettingValue(setting, value string) string {
+	if !containsFold(listSettings, setting) {
+		return pq.QuoteLiteral(value)
+	}
+	parts := strings.Split(value, ",")
+	for i, p := range parts {
+		parts[i] = pq.QuoteLiteral(strings.TrimSpace(p))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,123 @@
+package dbclient
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPostgresClientSettings(t *testing.T) {
+	_, pc := setupClient(t)
+
+	dbName := "settings_db"
+	role := "settings_user"
+	if _, err := pc.CreateDatabase(dbName); err != nil {
+		t.Fatalf("\t%s CreateDatabase() error = %v", failed, err)
+	}
+	if _, err := pc.CreateRole(role); err != nil {
+		t.Fatalf("\t%s CreateRole() error = %v", failed, err)
+	}
+	settings := map[string]string{"statement_timeout": "30s", "search_path": "app, public"}
+
+	// the cases run in order against the same database and role
+	tests := []struct {
+		name          string
+		drift         string
+		objectType    string
+		objectName    string
+		settings      map[string]string
+		reset         []string
+		wantChanged   []string
+		wantSetconfig string
+		wantErr       bool
+	}{
+		{
+			name:        "database settings are set",
+			objectType:  "DATABASE",
+			objectName:  dbName,
+			settings:    settings,
+			wantChanged: []string{"search_path", "statement_timeout"},
+		},
+		{
+			name:       "database settings already set",
+			objectType: "DATABASE",
+			objectName: dbName,
+			settings:   settings,
+		},
+		{
+			// postgres stores setting names in lowercase
+			name:       "mixed-case names already set",
+			objectType: "DATABASE",
+			objectName: dbName,
+			settings:   map[string]string{"Statement_Timeout": "30s", "search_path": "app, public"},
+		},
+		{
+			name:        "database drift is corrected",
+			drift:       "ALTER DATABASE settings_db SET statement_timeout TO '0'",
+			objectType:  "DATABASE",
+			objectName:  dbName,
+			settings:    settings,
+			wantChanged: []string{"statement_timeout"},
+		},
+		{
+			name:          "removed database settings are reset",
+			objectType:    "DATABASE",
+			objectName:    dbName,
+			settings:      map[string]string{"search_path": "app, public"},
+			reset:         []string{"statement_timeout"},
+			wantSetconfig: "search_path=app, public",
+		},
+		{
+			name:        "role settings are set",
+			objectType:  "ROLE",
+			objectName:  role,
+			settings:    map[string]string{"idle_in_transaction_session_timeout": "60s"},
+			wantChanged: []string{"idle_in_transaction_session_timeout"},
+		},
+		{
+			name:       "missing role is skipped",
+			objectType: "ROLE",
+			objectName: "missing_role",
+			settings:   map[string]string{"statement_timeout": "1s"},
+		},
+		{
+			name:       "invalid setting name",
+			objectType: "ROLE",
+			objectName: role,
+			settings:   map[string]string{"statement_timeout; DROP ROLE x": "1s"},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.drift != "" {
+				if _, err := pc.DB.Exec(tt.drift); err != nil {
+					t.Fatalf("\t%s %s error = %v", failed, tt.drift, err)
+				}
+			}
+			manage := pc.ManageDatabaseSettings
+			if tt.objectType == "ROLE" {
+				manage = pc.ManageRoleSettings
+			}
+			changed, err := manage(tt.objectName, tt.settings, tt.reset)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("\t%s Manage%sSettings() error = %v, wantErr %v", failed, tt.objectType, err, tt.wantErr)
+			}
+			if len(changed) != 0 || len(tt.wantChanged) != 0 {
+				if !reflect.DeepEqual(changed, tt.wantChanged) {
+					t.Errorf("\t%s Manage%sSettings() changed = %v, want %v", failed, tt.objectType, changed, tt.wantChanged)
+				}
+			}
+			if tt.wantSetconfig != "" {
+				var setconfig string
+				if err := pc.DB.QueryRow(`SELECT array_to_string(s.setconfig, ';') FROM pg_catalog.pg_db_role_setting s
+					JOIN pg_catalog.pg_database d ON d.oid = s.setdatabase WHERE d.datname = $1 AND s.setrole = 0`, tt.objectName).Scan(&setconfig); err != nil {
+					t.Fatalf("\t%s query settings error = %v", failed, err)
+				}
+				if setconfig != tt.wantSetconfig {
+					t.Errorf("\t%s Manage%sSettings() setconfig = %s, want %s", failed, tt.objectType, setconfig, tt.wantSetconfig)
+				}
+			}
+			t.Logf("\t%s %s is passed", succeed, tt.name)
+		})
+	}
+}
@@ -9,22 +9,45 @@ import (
 const (
 	SuffixA = "_a"
 	SuffixB = "_b"
+
+	// DefaultGenerations is the number of logins alternated by rotation
+	DefaultGenerations = 2
+	// MaxGenerations is the maximum number of logins of a user, suffixed _a to _h
+	MaxGenerations = 8
 )
 
 type DBUser struct {
 	rolename string
-	userA    string
-	userB    string
+	users    []string
 }
 
 func NewDBUser(baseName string) DBUser {
+	return NewDBUserWithGenerations(baseName, DefaultGenerations)
+}
+
+// NewDBUserWithGenerations returns a user rotating over the given number of logins.
+// The number is bounded by DefaultGenerations and MaxGenerations.
+func NewDBUserWithGenerations(baseName string, generations int) DBUser {
+	if generations < DefaultGenerations {
+		generations = DefaultGenerations
+	}
+	if generations > MaxGenerations {
+		generations = MaxGenerations
+	}
+	users := make([]string, 0, generations)
+	for i := 0; i < generations; i++ {
+		users = append(users, baseName+suffix(i))
+	}
 	return DBUser{
 		rolename: baseName,
-		userA:    baseName + SuffixA,
-		userB:    baseName + SuffixB,
+		users:    users,
 	}
 }
 
+func suffix(generation int) string {
+	return "_" + string(rune('a'+generation))
+}
+
 func (dbu DBUser) IsUserChanged(status persistancev1.Status) bool {
 	prevUsername := dbu.TrimUserSuffix(status.ConnectionInfo.Username)
 
@@ -35,33 +58,54 @@ func (dbu DBUser) IsUserChanged(status persistancev1.Status) bool {
 	return false
 }
 
+// TrimUserSuffix returns the base name of a login of any generation
 func (dbu DBUser) TrimUserSuffix(in string) string {
-	var out string
-	if strings.HasSuffix(in, SuffixA) {
-		out = strings.TrimSuffix(in, SuffixA)
-	} else {
-		out = strings.TrimSuffix(in, SuffixB)
+	for i := 0; i < MaxGenerations; i++ {
+		if strings.HasSuffix(in, suffix(i)) {
+			return strings.TrimSuffix(in, suffix(i))
+		}
 	}
-
-	return out
+	return in
 }
 
 func (dbu DBUser) GetUserA() string {
-	return dbu.userA
+	return dbu.rolename + SuffixA
 }
 
 func (dbu DBUser) GetUserB() string {
-	return dbu.userB
+	return dbu.rolename + SuffixB
 }
 
-func (dbu DBUser) NextUser(curUser string) string {
-	var nextUser string
+// Users returns the logins of the user in rotation order
+func (dbu DBUser) Users() []string {
+	return dbu.users
+}
 
-	if dbu.userA == curUser {
-		nextUser = dbu.userB
-	} else {
-		nextUser = dbu.userA
+// NextUser returns the login following curUser in rotation order, the first login
+// when curUser is not one of them
+func (dbu DBUser) NextUser(curUser string) string {
+	for i, u := range dbu.users {
+		if u == curUser {
+			return dbu.users[(i+1)%len(dbu.users)]
+		}
 	}
+	return dbu.users[0]
+}
 
-	return nextUser
+// Generation returns how many rotations ago username was the current login of curUser,
+// 0 for curUser itself. It returns -1 when username is not a login of the user.
+func (dbu DBUser) Generation(curUser, username string) int {
+	cur, idx := -1, -1
+	for i, u := range dbu.users {
+		if u == curUser {
+			cur = i
+		}
+		if u == username {
+			idx = i
+		}
+	}
+	if cur < 0 || idx < 0 {
+		return -1
+	}
+	return (cur - idx + len(dbu.users)) % len(dbu.users)
 }
@@ -87,3 +87,40 @@ func TestDBUser_IsUserChanged(t *testing.T) {
 		})
 	}
 }
+
+func TestDBUser_Generations(t *testing.T) {
+	dbu := NewDBUserWithGenerations("app", 3)
+	if got := dbu.Users(); len(got) != 3 || got[0] != "app_a" || got[2] != "app_c" {
+		t.Fatalf("Users() = %v, want [app_a app_b app_c]", got)
+	}
+	tests := []struct {
+		curUser    string
+		wantNext   string
+		username   string
+		generation int
+	}{
+		{"", "app_a", "app_a", -1},
+		{"app_a", "app_b", "app_a", 0},
+		{"app_b", "app_c", "app_a", 1},
+		{"app_c", "app_a", "app_a", 2},
+		{"app_a", "app_b", "app_c", 1},
+		{"app_d", "app_a", "app_a", -1},
+	}
+	for _, tt := range tests {
+		if got := dbu.NextUser(tt.curUser); got != tt.wantNext {
+			t.Errorf("NextUser(%s) = %s, want %s", tt.curUser, got, tt.wantNext)
+		}
+		if got := dbu.Generation(tt.curUser, tt.username); got != tt.generation {
+			t.Errorf("Generation(%s, %s) = %d, want %d", tt.curUser, tt.username, got, tt.generation)
+		}
+	}
+	if got := dbu.TrimUserSuffix("app_c"); got != "app" {
+		t.Errorf("TrimUserSuffix(app_c) = %s, want app", got)
+	}
+	if got := NewDBUserWithGenerations("app", 20).Users(); len(got) != MaxGenerations {
+		t.Errorf("Users() of 20 generations has %d logins, want %d", len(got), MaxGenerations)
+	}
+	if got := NewDBUser("app").Users(); len(got) != 2 || got[1] != "app"+SuffixB {
+		t.Errorf("NewDBUser() logins = %v", got)
+	}
+}
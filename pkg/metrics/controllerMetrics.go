@@ -38,6 +38,18 @@ var (
 		Name: "user_update_time_seconds",
 		Help: "Histogram of user updating time in seconds",
 	})
+	UsersDeleted = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "users_deleted_total",
+			Help: "Number of deleted users ",
+		},
+	)
+	UsersDeletedErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "users_delete_errors_total",
+			Help: "Number of users deleted with errors",
+		}, []string{"reason"},
+	)
 	DBProvisioningErrors = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "database_provisioning_errors_total",
@@ -50,6 +62,12 @@ var (
 			Help: "Number of created databases ",
 		},
 	)
+	DBDeleted = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "databases_deleted_total",
+			Help: "Number of deleted databases ",
+		},
+	)
 	PasswordRotated = prometheus.NewCounter(
 		prometheus.CounterOpts{
 			Name: "password_rotated_total",
@@ -66,12 +84,90 @@ var (
 		Name: "password_rotation_time_seconds",
 		Help: "Histogram of password rotation time in seconds",
 	})
+	MasterPasswordRotated = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "master_password_rotated_total",
+			Help: "Number of rotated master passwords of cloud hosts",
+		},
+	)
+	MasterPasswordRotateErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "master_password_rotate_errors_total",
+			Help: "Number of master password rotations failed or rolled back",
+		}, []string{"reason"},
+	)
+	ExtensionErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "extension_errors_total",
+			Help: "Number of failed extension operations",
+		}, []string{"reason"},
+	)
+	SchemaErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "schema_errors_total",
+			Help: "Number of failed schema and grant operations",
+		}, []string{"reason"},
+	)
+	SettingsErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "settings_errors_total",
+			Help: "Number of failed database and role setting operations",
+		}, []string{"reason"},
+	)
+	RoleDriftRepaired = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "role_drift_repaired_total",
+			Help: "Number of repaired role attribute, membership and grant drifts",
+		}, []string{"drift"},
+	)
+	AuditRecordErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "audit_record_errors_total",
+			Help: "Number of audit records that could not be written",
+		}, []string{"component"},
+	)
+	MigrationsFailed = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "migrations_failed_total",
+			Help: "Number of migrations failed after exhausting the attempts of a state",
+		},
+	)
+	MigrationTablesTotal = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "migration_tables_total",
+			Help: "Number of tables replicated by the migration of a claim",
+		}, []string{"claim"},
+	)
+	MigrationTablesSynced = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "migration_tables_synced",
+			Help: "Number of tables synchronized with the source by the migration of a claim",
+		}, []string{"claim"},
+	)
+	MigrationReplicationLag = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "migration_replication_lag_bytes",
+			Help: "WAL of the source not confirmed by the migration subscription of a claim",
+		}, []string{"claim"},
+	)
+	MigrationRowsValidated = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "migration_rows_validated",
+			Help: "Number of rows validated on the target by the migration of a claim",
+		}, []string{"claim"},
+	)
 )
 
 func init() {
 	// Register custom metrics with the global prometheus registry
 	metrics.Registry.MustRegister(UsersCreated, UsersCreatedErrors, UsersCreateTime)
 	metrics.Registry.MustRegister(UsersUpdated, UsersUpdatedErrors, UsersUpdateTime)
-	metrics.Registry.MustRegister(DBCreated, DBProvisioningErrors)
+	metrics.Registry.MustRegister(UsersDeleted, UsersDeletedErrors)
+	metrics.Registry.MustRegister(DBCreated, DBDeleted, DBProvisioningErrors)
 	metrics.Registry.MustRegister(PasswordRotated, PasswordRotatedErrors, PasswordRotateTime)
+	metrics.Registry.MustRegister(MasterPasswordRotated, MasterPasswordRotateErrors)
+	metrics.Registry.MustRegister(ExtensionErrors, SchemaErrors, SettingsErrors)
+	metrics.Registry.MustRegister(RoleDriftRepaired, AuditRecordErrors)
+	metrics.Registry.MustRegister(MigrationsFailed)
+	metrics.Registry.MustRegister(MigrationTablesTotal, MigrationTablesSynced, MigrationReplicationLag, MigrationRowsValidated)
 }
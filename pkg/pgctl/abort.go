@@ -0,0 +1,49 @@
+package pgctl
+
+import (
+	"context"
+
+	"github.com/infobloxopen/db-controller/pkg/redact"
+)
+
+// AbortMigration drops the replication objects of the migration of c and grants back
+// the write privileges frozen on its source. The subscription is dropped on the target
+// when c has its dsn, the slot of a subscription whose target is gone is dropped on
+// the source.
+func AbortMigration(ctx context.Context, c Config, grants []FrozenGrant) error {
+	c.Log = redact.Logger(c.Log)
+	log := c.Log.WithValues("state", "abort_migration")
+
+	if c.TargetDBAdminDsn != "" {
+		for _, s := range []State{&disable_subscription_state{config: c}, &delete_subscription_state{config: c}} {
+			if _, err := s.Execute(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := (&delete_publication_state{config: c}).Execute(ctx); err != nil {
+		return err
+	}
+
+	sourceDBAdmin, err := getDB(ctx, c.SourceDBAdminDsn, nil)
+	if err != nil {
+		log.Error(err, "connection test failed for sourceDBAdmin")
+		return err
+	}
+	defer closeDB(log, sourceDBAdmin)
+	if _, err := c.exec(ctx, sourceDBAdmin, c.SourceDBAdminDsn, `
+		SELECT pg_catalog.pg_drop_replication_slot(slot_name)
+		FROM pg_catalog.pg_replication_slots
+		WHERE slot_name = $1 AND NOT active`, c.slot()); err != nil {
+		log.Error(err, "could not drop the replication slot", "slot", c.slot())
+		return err
+	}
+
+	if len(grants) > 0 {
+		if err := RestoreSourceAccess(ctx, c, grants); err != nil {
+			return err
+		}
+	}
+	log.Info("migration aborted")
+	return nil
+}
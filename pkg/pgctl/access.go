@@ -0,0 +1,102 @@
+package pgctl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// FrozenGrant is a write privilege on a table of the source revoked from a role when
+// the source access is disabled. Members of the role inheriting the privilege lost it
+// with the role.
+type FrozenGrant struct {
+	// Role the privileges were revoked from, empty for PUBLIC
+	Role string
+	// Table is the quoted qualified name of the table
+	Table      string
+	Privileges []string
+}
+
+// SourceAccessReport lists the write privileges revoked on the source
+type SourceAccessReport struct {
+	Frozen []FrozenGrant
+}
+
+// SourceAccessReporter is implemented by the states disabling the source access
+type SourceAccessReporter interface {
+	// SourceAccessReport returns the report of the last Execute, nil before it
+	SourceAccessReport() *SourceAccessReport
+}
+
+func (g FrozenGrant) grantee() string {
+	if g.Role == "" {
+		return "PUBLIC"
+	}
+	return pq.QuoteIdentifier(g.Role)
+}
+
+// revoke returns the statement revoking the privileges of g
+func (g FrozenGrant) revoke() string {
+	return fmt.Sprintf("REVOKE %s ON TABLE %s FROM %s", strings.Join(g.Privileges, ", "), g.Table, g.grantee())
+}
+
+// grant returns the statement granting back the privileges of g
+func (g FrozenGrant) grant() string {
+	return fmt.Sprintf("GRANT %s ON TABLE %s TO %s", strings.Join(g.Privileges, ", "), g.Table, g.grantee())
+}
+
+// writeGrants returns the write privileges on the tables of schemas on db granted to
+// the roles, or implied by their ownership, other than the session user and superusers
+func writeGrants(ctx context.Context, db *sql.DB, schemas []string) ([]FrozenGrant, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT COALESCE(r.rolname, ''),
+			quote_ident(n.nspname) || '.' || quote_ident(c.relname),
+			array_agg(a.privilege_type ORDER BY a.privilege_type)
+		FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		CROSS JOIN LATERAL pg_catalog.aclexplode(COALESCE(c.relacl, pg_catalog.acldefault('r', c.relowner))) a
+		LEFT JOIN pg_catalog.pg_roles r ON r.oid = a.grantee
+		WHERE c.relkind IN ('r', 'p')
+			AND n.nspname = ANY($1)
+			AND a.privilege_type IN ('INSERT', 'UPDATE', 'DELETE', 'TRUNCATE')
+			AND (a.grantee = 0 OR (NOT r.rolsuper AND r.rolname <> session_user))
+		GROUP BY 1, 2
+		ORDER BY 1, 2`, pq.Array(schemas))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var grants []FrozenGrant
+	for rows.Next() {
+		var g FrozenGrant
+		if err := rows.Scan(&g.Role, &g.Table, pq.Array(&g.Privileges)); err != nil {
+			return nil, err
+		}
+		grants = append(grants, g)
+	}
+	return grants, rows.Err()
+}
+
+// RestoreSourceAccess grants back on the source of c the write privileges revoked
+// when its access was disabled
+func RestoreSourceAccess(ctx context.Context, c Config, grants []FrozenGrant) error {
+	log := c.Log.WithValues("state", S_DisableSourceAccess.String())
+	sourceDBAdmin, err := getDB(ctx, c.SourceDBAdminDsn, nil)
+	if err != nil {
+		log.Error(err, "connection test failed for sourceDBAdmin")
+		return err
+	}
+	defer closeDB(log, sourceDBAdmin)
+
+	for _, g := range grants {
+		if _, err := c.exec(ctx, sourceDBAdmin, c.SourceDBAdminDsn, g.grant()); err != nil {
+			log.Error(err, "failed restoring access for source db", "role", g.Role, "table", g.Table)
+			return err
+		}
+	}
+	log.Info("source access restored", "grants", len(grants))
+	return nil
+}
@@ -1,21 +1,44 @@
 package pgctl
 
 import (
+	"context"
+	"crypto/sha256"
 	"database/sql"
 	"errors"
 	"fmt"
-	"net/url"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/lib/pq"
+
+	"github.com/infobloxopen/db-controller/pkg/audit"
+	"github.com/infobloxopen/db-controller/pkg/redact"
 )
 
 const (
+	// DefaultPubName and DefaultSubName are used by migrations without ReplicationNames
 	DefaultPubName = "dbc_temp_pub_for_upgrade"
 	DefaultSubName = "dbc_temp_sub_for_upgrade"
+	// maximum length of the claim part of the replication names, below the 63 bytes of an identifier
+	maxReplicationNameLen = 40
+	// DefaultStateTimeout bounds the Execute of the states without a timeout in Config.Timeouts
+	DefaultStateTimeout = 5 * time.Minute
+	// pingTimeout bounds the connection check of a database
+	pingTimeout = 30 * time.Second
 )
 
+// defaultTimeouts are the timeouts of the states copying or reading all the data
+var defaultTimeouts = map[StateEnum]time.Duration{
+	S_CopySchema:              time.Hour,
+	S_ValidateMigrationStatus: time.Hour,
+}
+
+// ErrMigrationFailed is returned by the failed state, the migration exhausted its attempts
+var ErrMigrationFailed = errors.New("migration failed")
+
 type State interface {
-	Execute() (State, error)
+	Execute(ctx context.Context) (State, error)
 	Id() StateEnum
 	String() string
 }
@@ -30,32 +53,171 @@ type Config struct {
 	SourceDBUserDsn  string
 	TargetDBAdminDsn string
 	TargetDBUserDsn  string
-	ExportFilePath   string
+	// Audit receives a record of every statement changing the source or target, none when nil
+	Audit audit.Sink
+	// Claim migrated, as namespace/name
+	Claim string
+	// Schemas migrated, the non-system schemas of the source database when empty.
+	// Listed schemas are dumped with --schema, which skips the extensions.
+	Schemas []string
+	// Replication names the publication, subscription and slot of the migration,
+	// DefaultPubName and DefaultSubName are used when empty
+	Replication ReplicationNames
+	// PreFlightWarnOnly logs the failed pre-flight checks and starts the migration
+	// anyway, it is not started by default
+	PreFlightWarnOnly bool
+	// Verification compares the content of the tables once they are migrated, only their
+	// row counts are by default
+	Verification Verification
+	// RoleMap maps roles of the source to roles of the target for the ownerships and
+	// grants copied, roles missing are kept when the target has them
+	RoleMap map[string]string
+	// SequenceMargin sets the sequences of the target this many values ahead of the
+	// source. It has to be positive: the sequences are set before the writes to the
+	// source are stopped, and cover the values the source uses until then.
+	SequenceMargin int64
+	// Timeouts bound the Execute of each state, states missing use their default timeout
+	Timeouts map[StateEnum]time.Duration
+}
+
+// ReplicationNames are the publication, subscription and replication slot of a migration
+type ReplicationNames struct {
+	Publication  string
+	Subscription string
+	// Slot created on the source for the subscription, named after the subscription when empty
+	Slot string
+}
+
+// NewReplicationNames returns the replication names of the migration of claim, as
+// namespace/name. Names are unique per claim so that migrations sharing a source or
+// a target host do not collide.
+func NewReplicationNames(claim string) ReplicationNames {
+	sum := sha256.Sum256([]byte(claim))
+	name := []byte(strings.ToLower(claim))
+	for i, c := range name {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			name[i] = '_'
+		}
+	}
+	if len(name) > maxReplicationNameLen {
+		name = name[:maxReplicationNameLen]
+	}
+	base := fmt.Sprintf("dbc_%s_%x", name, sum[:4])
+	return ReplicationNames{
+		Publication:  base + "_pub",
+		Subscription: base + "_sub",
+		Slot:         base + "_slot",
+	}
+}
+
+// TableProgress is the replication or validation state of a table
+type TableProgress struct {
+	// Name of the table, qualified with its schema when it is not in the search path
+	Name string
+	// State of the table in the subscription, the srsubstate of pg_subscription_rel:
+	// i initialize, d data copy, f finished copy, s synchronized, r ready
+	State string
+	// Rows counted by the validation
+	SourceRows int64
+	TargetRows int64
+	// Chunks whose checksum was compared by the validation, and the ones not matching
+	ChunksVerified   int
+	ChunksMismatched int
+	// Mismatch describes the first chunk not matching
+	Mismatch string
+}
+
+// Synced reports whether the table data is synchronized with the source
+func (t TableProgress) Synced() bool {
+	return t.State == "r" || t.State == "s"
+}
+
+// Progress is the advance of a migration observed by a state
+type Progress struct {
+	Tables []TableProgress
+	// Tables of the subscription synchronized with the source, out of TablesTotal
+	TablesSynced int
+	TablesTotal  int
+	// LagBytes is the WAL of the source not confirmed by the subscription yet, nil when unknown
+	LagBytes *int64
+	// Tables and rows whose count on the target matches the source
+	TablesValidated int
+	RowsValidated   int64
+}
+
+// ProgressReporter is implemented by the states observing the progress of the migration
+type ProgressReporter interface {
+	// Progress returns the progress observed by the last Execute, nil before it
+	Progress() *Progress
+}
+
+func (c Config) publication() string {
+	if c.Replication.Publication == "" {
+		return DefaultPubName
+	}
+	return c.Replication.Publication
+}
+
+func (c Config) subscription() string {
+	if c.Replication.Subscription == "" {
+		return DefaultSubName
+	}
+	return c.Replication.Subscription
+}
+
+func (c Config) slot() string {
+	if c.Replication.Slot == "" {
+		return c.subscription()
+	}
+	return c.Replication.Slot
 }
 
 type initial_state struct{ config Config }
 type create_publication_state struct{ config Config }
 type validate_connection_state struct{ config Config }
+type pre_flight_check_state struct {
+	config Config
+	report *PreFlightReport
+}
 type copy_schema_state struct{ config Config }
+type migrate_privileges_state struct {
+	config Config
+	report *PrivilegeReport
+}
 type create_subscription_state struct{ config Config }
 type enable_subscription_state struct{ config Config }
-type cut_over_readiness_check_state struct{ config Config }
+type cut_over_readiness_check_state struct {
+	config   Config
+	progress *Progress
+}
 type reset_target_sequence_state struct{ config Config }
 type reroute_target_secret_state struct{ config Config }
 type wait_to_disable_source_state struct{ config Config }
-type disable_source_access_state struct{ config Config }
-type validate_migration_status_state struct{ config Config }
+type disable_source_access_state struct {
+	config Config
+	report *SourceAccessReport
+}
+type validate_migration_status_state struct {
+	config   Config
+	progress *Progress
+}
 type disable_subscription_state struct{ config Config }
 type delete_subscription_state struct{ config Config }
 type delete_publication_state struct{ config Config }
 type completed_state struct{ config Config }
 type retry_state struct{ config Config }
+type failed_state struct {
+	config Config
+	err    error
+}
 
 // validate that each class implements State interface
 var _ State = &initial_state{}
 var _ State = &create_publication_state{}
 var _ State = &validate_connection_state{}
+var _ State = &pre_flight_check_state{}
 var _ State = &copy_schema_state{}
+var _ State = &migrate_privileges_state{}
 var _ State = &create_subscription_state{}
 var _ State = &enable_subscription_state{}
 var _ State = &cut_over_readiness_check_state{}
@@ -69,8 +231,38 @@ var _ State = &delete_subscription_state{}
 var _ State = &delete_publication_state{}
 var _ State = &completed_state{}
 var _ State = &retry_state{}
+var _ State = &failed_state{}
+
+// validate the states observing the progress of the migration
+var _ ProgressReporter = &cut_over_readiness_check_state{}
+var _ ProgressReporter = &validate_migration_status_state{}
+var _ PreFlightReporter = &pre_flight_check_state{}
+var _ PrivilegeReporter = &migrate_privileges_state{}
+var _ SourceAccessReporter = &disable_source_access_state{}
+
+// MarshalLog logs the config with the passwords of its DSNs masked
+func (c Config) MarshalLog() interface{} {
+	return struct {
+		SourceDBAdminDsn string
+		SourceDBUserDsn  string
+		TargetDBAdminDsn string
+		TargetDBUserDsn  string
+		Claim            string
+		Schemas          []string
+		Replication      ReplicationNames
+	}{
+		SourceDBAdminDsn: redact.String(c.SourceDBAdminDsn),
+		SourceDBUserDsn:  redact.String(c.SourceDBUserDsn),
+		TargetDBAdminDsn: redact.String(c.TargetDBAdminDsn),
+		TargetDBUserDsn:  redact.String(c.TargetDBUserDsn),
+		Claim:            c.Claim,
+		Schemas:          c.Schemas,
+		Replication:      c.Replication,
+	}
+}
 
 func GetReplicatorState(name string, c Config) (State, error) {
+	c.Log = redact.Logger(c.Log)
 	log := c.Log.WithValues("state", name)
 
 	stateEnum, err := GetStateEnum(name)
@@ -84,10 +276,14 @@ func GetReplicatorState(name string, c Config) (State, error) {
 		return &initial_state{config: c}, nil
 	case S_ValidateConnection:
 		return &validate_connection_state{config: c}, nil
+	case S_PreFlightCheck:
+		return &pre_flight_check_state{config: c}, nil
 	case S_CreatePublication:
 		return &create_publication_state{config: c}, nil
 	case S_CopySchema:
 		return &copy_schema_state{config: c}, nil
+	case S_MigratePrivileges:
+		return &migrate_privileges_state{config: c}, nil
 	case S_CreateSubscription:
 		return &create_subscription_state{config: c}, nil
 	case S_EnableSubscription:
@@ -112,6 +308,8 @@ func GetReplicatorState(name string, c Config) (State, error) {
 		return &delete_publication_state{config: c}, nil
 	case S_Completed:
 		return &completed_state{config: c}, nil
+	case S_Failed:
+		return &failed_state{config: c}, nil
 	}
 
 	err = errors.New("unknown current state")
@@ -119,7 +317,7 @@ func GetReplicatorState(name string, c Config) (State, error) {
 	return nil, err
 }
 
-func (s *initial_state) Execute() (State, error) {
+func (s *initial_state) Execute(ctx context.Context) (State, error) {
 
 	if s.config.SourceDBAdminDsn == "" {
 		return nil, fmt.Errorf("source db admin dns is empty")
@@ -144,7 +342,9 @@ func (s *initial_state) String() string {
 	return S_Initial.String()
 }
 
-func (s *validate_connection_state) Execute() (State, error) {
+func (s *validate_connection_state) Execute(ctx context.Context) (State, error) {
+	ctx, cancel := s.config.withTimeout(ctx, s.Id())
+	defer cancel()
 
 	log := s.config.Log.WithValues("state", s.String())
 	log.Info("started")
@@ -154,45 +354,41 @@ func (s *validate_connection_state) Execute() (State, error) {
 		valid bool
 	)
 
-	sourceDBAdmin, err := getDB(s.config.SourceDBAdminDsn, nil)
+	sourceDBAdmin, err := getDB(ctx, s.config.SourceDBAdminDsn, nil)
 	if err != nil {
 		log.Error(err, "connection test failed for sourceDBAdmin")
 		return nil, err
 	}
 	defer closeDB(log, sourceDBAdmin)
 
-	sourceDBUser, err := getDB(s.config.SourceDBUserDsn, nil)
+	sourceDBUser, err := getDB(ctx, s.config.SourceDBUserDsn, nil)
 	if err != nil {
 		log.Error(err, "connection test failed for sourceDBUser")
 		return nil, err
 	}
 	defer closeDB(log, sourceDBUser)
-	targetDBAdmin, err := getDB(s.config.TargetDBAdminDsn, nil)
+	targetDBAdmin, err := getDB(ctx, s.config.TargetDBAdminDsn, nil)
 	if err != nil {
 		log.Error(err, "connection test failed for targetDBAdmin")
 		return nil, err
 	}
 	defer closeDB(log, targetDBAdmin)
-	targetDBUser, err := getDB(s.config.TargetDBUserDsn, nil)
+	targetDBUser, err := getDB(ctx, s.config.TargetDBUserDsn, nil)
 	if err != nil {
 		log.Error(err, "connection test failed for targetDBUser")
 		return nil, err
 	}
 	defer targetDBUser.Close()
 
-	if valid, err = isAdminUser(sourceDBAdmin); !valid || err != nil {
+	if valid, err = isAdminUser(ctx, sourceDBAdmin); !valid || err != nil {
 		return nil, fmt.Errorf("%w; Source DB Admin user lacks  required permission", err)
 	}
-	if valid, err = isAdminUser(targetDBAdmin); !valid || err != nil {
+	if valid, err = isAdminUser(ctx, targetDBAdmin); !valid || err != nil {
 		return nil, fmt.Errorf("%w; Target DB Admin user lacks  required permission", err)
 	}
-	if valid, err = isLogical(sourceDBAdmin); !valid || err != nil {
-		log.Error(err, "wal_level check failed")
-		return nil, err
-	}
 
 	log.Info("completed")
-	return &create_publication_state{
+	return &pre_flight_check_state{
 		config: s.config,
 	}, nil
 }
@@ -203,7 +399,76 @@ func (s *validate_connection_state) String() string {
 	return S_ValidateConnection.String()
 }
 
-func (s *create_publication_state) Execute() (State, error) {
+func (s *pre_flight_check_state) Execute(ctx context.Context) (State, error) {
+	ctx, cancel := s.config.withTimeout(ctx, s.Id())
+	defer cancel()
+	log := s.config.Log.WithValues("state", s.String())
+	log.Info("started")
+
+	sourceDBAdmin, err := getDB(ctx, s.config.SourceDBAdminDsn, nil)
+	if err != nil {
+		log.Error(err, "connection test failed for sourceDBAdmin")
+		return nil, err
+	}
+	defer closeDB(log, sourceDBAdmin)
+
+	targetDBAdmin, err := getDB(ctx, s.config.TargetDBAdminDsn, nil)
+	if err != nil {
+		log.Error(err, "connection test failed for targetDBAdmin")
+		return nil, err
+	}
+	defer closeDB(log, targetDBAdmin)
+
+	schemas, err := s.config.schemas(ctx, sourceDBAdmin)
+	if err != nil {
+		log.Error(err, "could not query for the schemas")
+		return nil, err
+	}
+
+	report := &PreFlightReport{}
+	checks := []func() error{
+		func() error { return checkWalLevel(ctx, sourceDBAdmin, report) },
+		func() error { return checkReplicationSlots(ctx, sourceDBAdmin, s.config.slot(), report) },
+		func() error { return checkReplicaIdentity(ctx, sourceDBAdmin, schemas, report) },
+		func() error { return checkExtensions(ctx, sourceDBAdmin, targetDBAdmin, report) },
+		func() error { return checkColumnTypes(ctx, sourceDBAdmin, schemas, report) },
+	}
+	for _, check := range checks {
+		if err := check(); err != nil {
+			log.Error(err, "pre-flight check failed to run")
+			return nil, err
+		}
+	}
+	s.report = report
+
+	for _, c := range report.Checks {
+		log.Info("pre-flight check", "check", c.Name, "passed", c.Passed, "message", c.Message)
+	}
+	if !report.Passed() {
+		if !s.config.PreFlightWarnOnly {
+			return nil, fmt.Errorf("pre-flight checks failed: %s", report)
+		}
+		log.Info("pre-flight checks failed, migration started anyway", "report", report.String())
+	}
+
+	log.Info("completed")
+	return &create_publication_state{
+		config: s.config,
+	}, nil
+}
+func (s *pre_flight_check_state) PreFlightReport() *PreFlightReport {
+	return s.report
+}
+func (s *pre_flight_check_state) Id() StateEnum {
+	return S_PreFlightCheck
+}
+func (s *pre_flight_check_state) String() string {
+	return S_PreFlightCheck.String()
+}
+
+func (s *create_publication_state) Execute(ctx context.Context) (State, error) {
+	ctx, cancel := s.config.withTimeout(ctx, s.Id())
+	defer cancel()
 	log := s.config.Log.WithValues("state", s.String())
 	log.Info("started")
 
@@ -212,7 +477,7 @@ func (s *create_publication_state) Execute() (State, error) {
 		sourceDBAdmin *sql.DB
 	)
 
-	if sourceDBAdmin, err = getDB(s.config.SourceDBAdminDsn, nil); err != nil {
+	if sourceDBAdmin, err = getDB(ctx, s.config.SourceDBAdminDsn, nil); err != nil {
 		log.Error(err, "connection test failed for sourceDBAdmin")
 
 		return nil, err
@@ -220,6 +485,7 @@ func (s *create_publication_state) Execute() (State, error) {
 	defer closeDB(log, sourceDBAdmin)
 
 	var exists bool
+	pubName := s.config.publication()
 
 	q := `
 		SELECT EXISTS(
@@ -229,22 +495,35 @@ func (s *create_publication_state) Execute() (State, error) {
 
 	createPub := fmt.Sprintf(`
 		CREATE PUBLICATION %s 
-		FOR ALL TABLES`, DefaultPubName)
+		FOR ALL TABLES`, pq.QuoteIdentifier(pubName))
 
-	err = sourceDBAdmin.QueryRow(q, DefaultPubName).Scan(&exists)
+	// only the tables of the listed schemas exist on the target
+	if len(s.config.Schemas) > 0 {
+		tables, err := schemaTables(ctx, sourceDBAdmin, s.config.Schemas)
+		if err != nil {
+			log.Error(err, "could not query for the tables of the schemas", "schemas", s.config.Schemas)
+			return nil, err
+		}
+		createPub = fmt.Sprintf("CREATE PUBLICATION %s", pq.QuoteIdentifier(pubName))
+		if len(tables) > 0 {
+			createPub += " FOR TABLE " + strings.Join(tables, ", ")
+		}
+	}
+
+	err = sourceDBAdmin.QueryRowContext(ctx, q, pubName).Scan(&exists)
 	if err != nil {
 		log.Error(err, "could not query for publication name")
 		return nil, err
 	}
 	if !exists {
-		log.Info("creating publication:", "with name", DefaultPubName)
-		if _, err := sourceDBAdmin.Exec(createPub); err != nil {
+		log.Info("creating publication:", "with name", pubName)
+		if _, err := s.config.exec(ctx, sourceDBAdmin, s.config.SourceDBAdminDsn, createPub); err != nil {
 			log.Error(err, "create publication failed")
 			return nil, err
 		}
-		log.Info("publication created", "name", DefaultPubName)
+		log.Info("publication created", "name", pubName)
 	} else {
-		log.Info("publication already exists", "with name", DefaultPubName)
+		log.Info("publication already exists", "with name", pubName)
 	}
 
 	return &copy_schema_state{
@@ -258,14 +537,15 @@ func (s *create_publication_state) String() string {
 	return S_CreatePublication.String()
 }
 
-func (s *copy_schema_state) Execute() (State, error) {
+func (s *copy_schema_state) Execute(ctx context.Context) (State, error) {
+	ctx, cancel := s.config.withTimeout(ctx, s.Id())
+	defer cancel()
 	log := s.config.Log.WithValues("state", s.String())
 	log.Info("started")
 
 	dump := NewDump(s.config.SourceDBAdminDsn)
 
 	dump.SetupFormat("p")
-	dump.SetPath(s.config.ExportFilePath)
 
 	dump.EnableVerbose()
 
@@ -273,29 +553,30 @@ func (s *copy_schema_state) Execute() (State, error) {
 		"--schema-only",
 		"--no-publication",
 		"--no-subscriptions",
+		"--no-owner",
 		"--no-privileges",
 	})
-
-	dumpExec := dump.Exec(ExecOptions{StreamPrint: true})
-	log.Info("executing", "full command", dumpExec.FullCommand)
-
-	if dumpExec.Error != nil {
-		return nil, dumpExec.Error.Err
-	}
+	dump.SetSchemas(s.config.Schemas)
 
 	restore := NewRestore(s.config.TargetDBAdminDsn)
 	restore.EnableVerbose()
 
-	restore.Path = s.config.ExportFilePath
-	restoreExec := restore.Exec(dumpExec.FileName, ExecOptions{StreamPrint: true})
-
-	log.Info("restore", "full command", restoreExec.FullCommand)
+	// the dump is piped into the restore, no file is written
+	start := time.Now()
+	dumpExec, restoreExec := Stream(ctx, dump, restore, ExecOptions{StreamPrint: true})
+	log.Info("executing", "full command", redact.String(dumpExec.FullCommand))
+	log.Info("restore", "full command", redact.String(restoreExec.FullCommand))
+	s.config.auditCommand(s.config.SourceDBAdminDsn, PGDump+" "+dumpExec.FullCommand, start, dumpExec.Error)
+	s.config.auditCommand(s.config.TargetDBAdminDsn, PSQL+" "+restoreExec.FullCommand, start, restoreExec.Error)
 
+	if dumpExec.Error != nil {
+		return nil, dumpExec.Error.Err
+	}
 	if restoreExec.Error != nil {
 		return nil, restoreExec.Error.Err
 	}
 	log.Info("completed")
-	return &create_subscription_state{
+	return &migrate_privileges_state{
 		config: s.config,
 	}, nil
 
@@ -307,17 +588,110 @@ func (s *copy_schema_state) String() string {
 	return S_CopySchema.String()
 }
 
+// Execute replays on the target the ownerships, grants and default privileges of the
+// source the schema copy left out, with the roles of the source mapped to the target
+func (s *migrate_privileges_state) Execute(ctx context.Context) (State, error) {
+	ctx, cancel := s.config.withTimeout(ctx, s.Id())
+	defer cancel()
+	log := s.config.Log.WithValues("state", s.String())
+	log.Info("started")
+
+	sourceDBAdmin, err := getDB(ctx, s.config.SourceDBAdminDsn, nil)
+	if err != nil {
+		log.Error(err, "connection test failed for sourceDBAdmin")
+		return nil, err
+	}
+	defer closeDB(log, sourceDBAdmin)
+
+	targetDBAdmin, err := getDB(ctx, s.config.TargetDBAdminDsn, nil)
+	if err != nil {
+		log.Error(err, "connection test failed for targetDBAdmin")
+		return nil, err
+	}
+	defer closeDB(log, targetDBAdmin)
+
+	schemas, err := s.config.schemas(ctx, sourceDBAdmin)
+	if err != nil {
+		log.Error(err, "could not query for the schemas")
+		return nil, err
+	}
+	owners, err := sourceOwners(ctx, sourceDBAdmin, schemas)
+	if err != nil {
+		log.Error(err, "could not query for the owners")
+		return nil, err
+	}
+	privileges, err := sourcePrivileges(ctx, sourceDBAdmin, schemas)
+	if err != nil {
+		log.Error(err, "could not query for the privileges")
+		return nil, err
+	}
+	defaults, err := sourceDefaultPrivileges(ctx, sourceDBAdmin, schemas)
+	if err != nil {
+		log.Error(err, "could not query for the default privileges")
+		return nil, err
+	}
+
+	// the roles created are reported even when a later statement fails
+	report := &PrivilegeReport{}
+	s.report = report
+	mapper, err := newRoleMapper(ctx, sourceDBAdmin, targetDBAdmin, s.config.RoleMap, report)
+	if err != nil {
+		log.Error(err, "could not query for the roles")
+		return nil, err
+	}
+	mapper.create = func(role string) error {
+		log.Info("creating role", "role", role)
+		_, err := s.config.exec(ctx, targetDBAdmin, s.config.TargetDBAdminDsn,
+			fmt.Sprintf("CREATE ROLE %s NOLOGIN", pq.QuoteIdentifier(role)))
+		return err
+	}
+	stmts, err := privilegeStatements(mapper, owners, privileges, defaults, func(roles []string) ([]roleMembership, error) {
+		return sourceMemberships(ctx, sourceDBAdmin, roles)
+	})
+	if err != nil {
+		log.Error(err, "could not map the roles")
+		return nil, err
+	}
+	for _, stmt := range stmts {
+		if _, err := s.config.exec(ctx, targetDBAdmin, s.config.TargetDBAdminDsn, stmt); err != nil {
+			log.Error(err, "could not migrate privilege", "stmt", stmt)
+			return nil, err
+		}
+	}
+
+	if len(report.Unmapped) > 0 {
+		log.Info("roles without an equivalent on the target, their privileges were not migrated",
+			"roles", report.Unmapped)
+	}
+	log.Info("completed", "created roles", report.Created, "grants", report.Grants,
+		"default privileges", report.DefaultPrivileges, "owners", report.Owners)
+	return &create_subscription_state{
+		config: s.config,
+	}, nil
+}
+func (s *migrate_privileges_state) PrivilegeReport() *PrivilegeReport {
+	return s.report
+}
+func (s *migrate_privileges_state) Id() StateEnum {
+	return S_MigratePrivileges
+}
+func (s *migrate_privileges_state) String() string {
+	return S_MigratePrivileges.String()
+}
+
 // The following var is used only by createSubscription. This is provided so that it can be overridden during unit test.
 var getSourceDbAdminDSNForCreateSubscription = func(c *Config) string {
 	return c.SourceDBAdminDsn
 }
 
-func (s *create_subscription_state) Execute() (State, error) {
+func (s *create_subscription_state) Execute(ctx context.Context) (State, error) {
+	ctx, cancel := s.config.withTimeout(ctx, s.Id())
+	defer cancel()
 	log := s.config.Log.WithValues("state", s.String())
 	log.Info("started")
 	var exists bool
 
-	targetDBAdmin, err := getDB(s.config.TargetDBAdminDsn, nil)
+	targetDBAdmin, err := getDB(ctx, s.config.TargetDBAdminDsn, nil)
 	if err != nil {
 		log.Error(err, "connection test failed for targetDBAdmin")
 		return nil, err
@@ -329,26 +703,27 @@ func (s *create_subscription_state) Execute() (State, error) {
 			FROM pg_catalog.pg_subscription 
 			WHERE subname = $1
 		)`
+	subName := s.config.subscription()
 	createSub := fmt.Sprintf(`
 		CREATE SUBSCRIPTION %s 
 		CONNECTION '%s' 
 		PUBLICATION %s 
-		WITH (enabled=false)`, DefaultSubName,
+		WITH (enabled=false, slot_name=%s)`, pq.QuoteIdentifier(subName),
 		getSourceDbAdminDSNForCreateSubscription(&s.config),
-		DefaultPubName)
+		pq.QuoteIdentifier(s.config.publication()), pq.QuoteLiteral(s.config.slot()))
 
-	err = targetDBAdmin.QueryRow(q, DefaultSubName).Scan(&exists)
+	err = targetDBAdmin.QueryRowContext(ctx, q, subName).Scan(&exists)
 	if err != nil {
 		log.Error(err, "could not query for subsription name", "stmt", createSub)
 		return nil, err
 	}
 	if !exists {
-		log.Info("creating subsription:", "with name", DefaultSubName)
-		if _, err := targetDBAdmin.Exec(createSub); err != nil {
+		log.Info("creating subsription:", "with name", subName)
+		if _, err := s.config.exec(ctx, targetDBAdmin, s.config.TargetDBAdminDsn, createSub); err != nil {
 			log.Error(err, "could not create subsription")
 			return nil, err
 		}
-		log.Info("subsription created", "name", DefaultSubName)
+		log.Info("subsription created", "name", subName)
 	}
 	log.Info("completed")
 	return &enable_subscription_state{
@@ -362,11 +737,13 @@ func (s *create_subscription_state) String() string {
 	return S_CreateSubscription.String()
 }
 
-func (s *enable_subscription_state) Execute() (State, error) {
+func (s *enable_subscription_state) Execute(ctx context.Context) (State, error) {
+	ctx, cancel := s.config.withTimeout(ctx, s.Id())
+	defer cancel()
 	log := s.config.Log.WithValues("state", s.String())
 	log.Info("started")
 
-	targetDBAdmin, err := getDB(s.config.TargetDBAdminDsn, nil)
+	targetDBAdmin, err := getDB(ctx, s.config.TargetDBAdminDsn, nil)
 	if err != nil {
 		log.Error(err, "connection test failed for targetDBAdmin")
 		return nil, err
@@ -379,23 +756,24 @@ func (s *enable_subscription_state) Execute() (State, error) {
 			FROM pg_catalog.pg_subscription 
 			WHERE subname = $1)`
 
-	alterSub := fmt.Sprintf("ALTER SUBSCRIPTION %s ENABLE", DefaultSubName)
+	subName := s.config.subscription()
+	alterSub := fmt.Sprintf("ALTER SUBSCRIPTION %s ENABLE", pq.QuoteIdentifier(subName))
 
 	var exists bool
 
-	err = targetDBAdmin.QueryRow(q, DefaultSubName).Scan(&exists)
+	err = targetDBAdmin.QueryRowContext(ctx, q, subName).Scan(&exists)
 	if err != nil {
 		log.Error(err, "could not query for Subscription name")
 		return nil, err
 	}
 	if exists {
 		log.Info("enabling Subscription")
-		if _, err := targetDBAdmin.Exec(alterSub); err != nil {
+		if _, err := s.config.exec(ctx, targetDBAdmin, s.config.TargetDBAdminDsn, alterSub); err != nil {
 			log.Error(err, "could not enable Subscription")
 			return nil, err
 		}
 	} else {
-		return nil, fmt.Errorf("unable to enable subscription. subscription not found - %s", DefaultSubName)
+		return nil, fmt.Errorf("unable to enable subscription. subscription not found - %s", subName)
 	}
 
 	log.Info("completed")
@@ -411,52 +789,77 @@ func (s *enable_subscription_state) String() string {
 	return S_EnableSubscription.String()
 }
 
-func (s *cut_over_readiness_check_state) Execute() (State, error) {
+func (s *cut_over_readiness_check_state) Execute(ctx context.Context) (State, error) {
+	ctx, cancel := s.config.withTimeout(ctx, s.Id())
+	defer cancel()
 	log := s.config.Log.WithValues("state", s.String())
 	log.Info("started")
 	var exists bool
-	var count int
 
-	sourceDBAdmin, err := getDB(s.config.SourceDBAdminDsn, nil)
+	sourceDBAdmin, err := getDB(ctx, s.config.SourceDBAdminDsn, nil)
 	if err != nil {
 		log.Error(err, "connection test failed for sourceDBAdmin")
 		return nil, err
 	}
 	defer closeDB(log, sourceDBAdmin)
 
-	targetDBAdmin, err := getDB(s.config.TargetDBAdminDsn, nil)
+	targetDBAdmin, err := getDB(ctx, s.config.TargetDBAdminDsn, nil)
 	if err != nil {
 		log.Error(err, "connection test failed for targetDBAdmin")
 		return nil, err
 	}
 	defer closeDB(log, targetDBAdmin)
 
-	pubQuery := fmt.Sprintf(`
+	subName := s.config.subscription()
+	var subOid int64
+	err = targetDBAdmin.QueryRowContext(ctx, "SELECT oid FROM pg_catalog.pg_subscription WHERE subname = $1", subName).Scan(&subOid)
+	if err != nil {
+		log.Error(err, "could not query for subscription", "name", subName)
+		return nil, err
+	}
+
+	// the table synchronization slots of the subscription, named
+	// pg_<subscription oid>_sync_<relid>_<system id> since postgres 14 and
+	// <slot>_<subscription oid>_sync_<relid> before
+	syncSlotQuery := `
 		SELECT EXISTS
 		(
 			SELECT 1 
 			FROM pg_replication_slots 
 			WHERE slot_type = 'logical' 
-			AND slot_name like '%s_%%'
-			AND temporary = 't'
-		)`, DefaultPubName)
-	relExistsQuery := fmt.Sprintf(`
-		SELECT EXISTS
-		(
-			SELECT 1
-			FROM pg_subscription s, pg_subscription_rel sr
-			WHERE s.oid = sr.srsubid
-			AND s.subname = '%s'
-		)`, DefaultSubName)
-
-	subQuery := fmt.Sprintf(`
-		SELECT count(srrelid) 
+			AND (slot_name LIKE $1 OR slot_name LIKE $2)
+		)`
+	tablesQuery := `
+		SELECT sr.srrelid::regclass::text, sr.srsubstate
 		FROM pg_subscription s, pg_subscription_rel sr
 		WHERE s.oid = sr.srsubid
-		AND sr.srsubstate not in ('r', 's')
-		AND s.subname = '%s'`, DefaultSubName)
+		AND s.subname = $1
+		ORDER BY 1`
+	lagQuery := `
+		SELECT pg_wal_lsn_diff(pg_current_wal_lsn(), confirmed_flush_lsn)::bigint
+		FROM pg_replication_slots
+		WHERE slot_name = $1`
+
+	progress, err := subscriptionProgress(ctx, targetDBAdmin, tablesQuery, subName)
+	if err != nil {
+		log.Error(err, "could not query for the tables of the subscription")
+		return nil, err
+	}
+	var lag sql.NullInt64
+	err = sourceDBAdmin.QueryRowContext(ctx, lagQuery, s.config.slot()).Scan(&lag)
+	if err != nil && err != sql.ErrNoRows {
+		log.Error(err, "could not query for the replication lag")
+		return nil, err
+	}
+	if lag.Valid {
+		progress.LagBytes = &lag.Int64
+	}
+	s.progress = progress
+	log.Info("progress", "tablesSynced", progress.TablesSynced, "tablesTotal", progress.TablesTotal, "lagBytes", lag.Int64)
 
-	err = sourceDBAdmin.QueryRow(pubQuery).Scan(&exists)
+	err = sourceDBAdmin.QueryRowContext(ctx, syncSlotQuery,
+		fmt.Sprintf(`pg\_%d\_sync\_%%`, subOid),
+		fmt.Sprintf(`%s\_%d\_sync\_%%`, escapeLike(s.config.slot()), subOid)).Scan(&exists)
 	if err != nil {
 		log.Error(err, "could not query for pg_replication_slots")
 		return nil, err
@@ -466,22 +869,11 @@ func (s *cut_over_readiness_check_state) Execute() (State, error) {
 		return retry(s.config), nil
 	}
 
-	err = targetDBAdmin.QueryRow(relExistsQuery).Scan(&exists)
-	if err != nil {
-		log.Error(err, "could not query for subscription rel for records present")
-		return nil, err
-	}
-	if !exists {
+	if progress.TablesTotal == 0 {
 		log.Info("target yet to start receiving data - retry check in a few seconds")
 		return retry(s.config), nil
 	}
-
-	err = targetDBAdmin.QueryRow(subQuery).Scan(&count)
-	if err != nil {
-		log.Error(err, "could not query for subscription for completion")
-		return nil, err
-	}
-	if count > 0 {
+	if progress.TablesSynced < progress.TablesTotal {
 		log.Info("migration not complete in target - retry check in a few seconds")
 		return retry(s.config), nil
 	}
@@ -490,6 +882,9 @@ func (s *cut_over_readiness_check_state) Execute() (State, error) {
 		config: s.config,
 	}, nil
 }
+func (s *cut_over_readiness_check_state) Progress() *Progress {
+	return s.progress
+}
 func (s *cut_over_readiness_check_state) Id() StateEnum {
 	return S_CutOverReadinessCheck
 }
@@ -497,62 +892,52 @@ func (s *cut_over_readiness_check_state) String() string {
 	return S_CutOverReadinessCheck.String()
 }
 
-func (s *reset_target_sequence_state) Execute() (State, error) {
+func (s *reset_target_sequence_state) Execute(ctx context.Context) (State, error) {
+	ctx, cancel := s.config.withTimeout(ctx, s.Id())
+	defer cancel()
 	log := s.config.Log.WithValues("state", s.String())
 	log.Info("started")
 
-	type seqCount struct {
-		seqName string
-		lastVal int64
+	sourceDBAdmin, err := getDB(ctx, s.config.SourceDBAdminDsn, nil)
+	if err != nil {
+		log.Error(err, "connection test failed for sourceDBAdmin")
+		return nil, err
 	}
-	var (
-		seqName string
-		lastVal int64
-		seqs    []seqCount
-	)
+	defer closeDB(log, sourceDBAdmin)
 
-	targetDBUser, err := getDB(s.config.TargetDBUserDsn, nil)
+	targetDBAdmin, err := getDB(ctx, s.config.TargetDBAdminDsn, nil)
 	if err != nil {
-		log.Error(err, "connection test failed for targetDBUser")
+		log.Error(err, "connection test failed for targetDBAdmin")
 		return nil, err
 	}
-	defer closeDB(log, targetDBUser)
+	defer closeDB(log, targetDBAdmin)
 
-	sourceDBUser, err := getDB(s.config.SourceDBUserDsn, nil)
+	schemas, err := s.config.schemas(ctx, sourceDBAdmin)
 	if err != nil {
-		log.Error(err, "connection test failed for sourceDBAdmin")
+		log.Error(err, "failed getting schemas")
 		return nil, err
 	}
-	defer closeDB(log, sourceDBUser)
-
-	seqsQ := "SELECT sequence_name FROM information_schema.sequences"
-	seqCountQ := "SELECT ROUND(last_value + 10000, -4)  FROM %s"
-
-	rows, err := sourceDBUser.Query(seqsQ)
+	seqs, err := sourceSequences(ctx, sourceDBAdmin, schemas)
 	if err != nil {
-		log.Error(err, "failed getting sequence names", seqsQ)
+		log.Error(err, "failed getting sequences")
 		return nil, err
 	}
-	defer rows.Close()
-	for rows.Next() {
-		rows.Scan(&seqName)
-		err = sourceDBUser.QueryRow(fmt.Sprintf(seqCountQ, seqName)).Scan(&lastVal)
+	for _, seq := range seqs {
+		if err := s.config.readSequence(ctx, sourceDBAdmin, s.config.SourceDBAdminDsn, &seq); err != nil {
+			log.Error(err, "failed to read sequence", "sequence", seq.name)
+			return nil, err
+		}
+		value, isCalled, err := seq.setval(s.config.SequenceMargin)
 		if err != nil {
-			log.Error(err, "Failed to get seq number - "+seqName)
 			return nil, err
 		}
-		seqs = append(seqs, seqCount{seqName: seqName, lastVal: lastVal})
-	}
-	if err = rows.Err(); err != nil {
-		return nil, err
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	for _, s := range seqs {
-		_, err := targetDBUser.Exec(fmt.Sprintf("SELECT SETVAL('%s', %d)", s.seqName, s.lastVal))
+		name, err := targetSequence(ctx, targetDBAdmin, seq)
 		if err != nil {
-			log.Error(err, "Failed to update", "seq number", seqName)
+			log.Error(err, "failed to find the target sequence", "sequence", seq.name)
+			return nil, err
+		}
+		if err := s.config.setSequence(ctx, targetDBAdmin, s.config.TargetDBAdminDsn, name, value, isCalled); err != nil {
+			log.Error(err, "failed to update", "sequence", name)
 			return nil, err
 		}
 	}
@@ -568,7 +953,7 @@ func (s *reset_target_sequence_state) String() string {
 	return S_ResetTargetSequence.String()
 }
 
-func (s *reroute_target_secret_state) Execute() (State, error) {
+func (s *reroute_target_secret_state) Execute(ctx context.Context) (State, error) {
 	//log := s.config.log.WithValues("state", s.String())
 	return &wait_to_disable_source_state{
 		config: s.config,
@@ -581,7 +966,7 @@ func (s *reroute_target_secret_state) String() string {
 	return S_RerouteTargetSecret.String()
 }
 
-func (s *wait_to_disable_source_state) Execute() (State, error) {
+func (s *wait_to_disable_source_state) Execute(ctx context.Context) (State, error) {
 	//log := s.config.log.WithValues("state", s.String())
 	return &disable_source_access_state{
 		config: s.config,
@@ -594,41 +979,48 @@ func (s *wait_to_disable_source_state) String() string {
 	return S_WaitToDisableSource.String()
 }
 
-func (s *disable_source_access_state) Execute() (State, error) {
+func (s *disable_source_access_state) Execute(ctx context.Context) (State, error) {
+	ctx, cancel := s.config.withTimeout(ctx, s.Id())
+	defer cancel()
 	log := s.config.Log.WithValues("state", s.String())
 	log.Info("started")
 
-	sourceDBAdmin, err := getDB(s.config.SourceDBAdminDsn, nil)
+	sourceDBAdmin, err := getDB(ctx, s.config.SourceDBAdminDsn, nil)
 	if err != nil {
 		log.Error(err, "connection test failed for sourceDBAdmin")
 		return nil, err
 	}
 	defer closeDB(log, sourceDBAdmin)
 
-	u, err := url.Parse(s.config.SourceDBUserDsn)
+	schemas, err := s.config.schemas(ctx, sourceDBAdmin)
 	if err != nil {
-		log.Error(err, "parsing sourcedbuserdsn failed")
+		log.Error(err, "failed getting schemas")
 		return nil, err
 	}
-
-	//if user name is sample_user_a, the role inherited is sample_user
-	//remove "_x" to get role name
-	//the role has all the permission - which needs to be removed now
-	user := u.User.Username()
-	stmt := `
-		REVOKE insert, delete, update
-		ON ALL TABLES IN SCHEMA PUBLIC FROM %s`
-
-	_, err = sourceDBAdmin.Exec(fmt.Sprintf(stmt, user[:len(user)-2]))
+	// writes are revoked from the roles they are granted to, the members of the roles
+	// inheriting them lose them too
+	grants, err := writeGrants(ctx, sourceDBAdmin, schemas)
 	if err != nil {
-		log.Error(err, "failed revoking access for source db")
+		log.Error(err, "failed getting the roles allowed to write")
 		return nil, err
 	}
-	log.Info("completed")
+	report := &SourceAccessReport{}
+	s.report = report
+	for _, g := range grants {
+		if _, err := s.config.exec(ctx, sourceDBAdmin, s.config.SourceDBAdminDsn, g.revoke()); err != nil {
+			log.Error(err, "failed revoking access for source db", "role", g.Role, "table", g.Table)
+			return nil, err
+		}
+		report.Frozen = append(report.Frozen, g)
+	}
+	log.Info("completed", "frozen grants", len(report.Frozen))
 	return &validate_migration_status_state{
 		config: s.config,
 	}, nil
 }
+func (s *disable_source_access_state) SourceAccessReport() *SourceAccessReport {
+	return s.report
+}
 func (s *disable_source_access_state) Id() StateEnum {
 	return S_DisableSourceAccess
 }
@@ -636,29 +1028,34 @@ func (s *disable_source_access_state) String() string {
 	return S_DisableSourceAccess.String()
 }
 
-func (s *validate_migration_status_state) Execute() (State, error) {
+func (s *validate_migration_status_state) Execute(ctx context.Context) (State, error) {
+	ctx, cancel := s.config.withTimeout(ctx, s.Id())
+	defer cancel()
 	log := s.config.Log.WithValues("state", s.String())
 	log.Info("started")
 
 	var (
-		sourceTableName  string
-		sourceTableCount int64
-		targetTableCount int64
+		sourceTableSchema string
+		sourceTableName   string
+		sourceTableCount  int64
+		targetTableCount  int64
 	)
 
-	targetDBUser, err := getDB(s.config.TargetDBUserDsn, nil)
+	// the tables are counted as the admins, disable_source_access revoked the access of
+	// the user to the source and the user may not read every discovered schema
+	targetDBAdmin, err := getDB(ctx, s.config.TargetDBAdminDsn, nil)
 	if err != nil {
-		log.Error(err, "connection test failed for targetDBUser")
+		log.Error(err, "connection test failed for targetDBAdmin")
 		return nil, err
 	}
-	defer closeDB(log, targetDBUser)
+	defer closeDB(log, targetDBAdmin)
 
-	sourceDBUser, err := getDB(s.config.SourceDBUserDsn, nil)
+	sourceDBAdmin, err := getDB(ctx, s.config.SourceDBAdminDsn, nil)
 	if err != nil {
 		log.Error(err, "connection test failed for sourceDBAdmin")
 		return nil, err
 	}
-	defer closeDB(log, sourceDBUser)
+	defer closeDB(log, sourceDBAdmin)
 
 	allTableCountQ := `
 		WITH tbl AS (
@@ -666,9 +1063,9 @@ func (s *validate_migration_status_state) Execute() (State, error) {
 			FROM information_schema.tables 
 			WHERE TABLE_NAME not like 'pg_%' 
 				AND table_type = 'BASE TABLE'
-				AND table_schema = 'public'
+				AND table_schema = ANY($1)
 		) 
-        SELECT 	table_name, 
+        SELECT 	table_schema, table_name, 
 				(xpath('/row/c/text()', 
 					query_to_xml(format('select count(*) as c from %I.%I', table_schema, TABLE_NAME), FALSE, TRUE, '')
 					)
@@ -677,37 +1074,96 @@ func (s *validate_migration_status_state) Execute() (State, error) {
 	`
 	tableCountQ := "SELECT count(*) From %s"
 
-	rows, err := sourceDBUser.Query(allTableCountQ)
+	schemas, err := s.config.schemas(ctx, sourceDBAdmin)
+	if err != nil {
+		log.Error(err, "failed getting schemas")
+		return nil, err
+	}
+
+	var sourceConn, targetConn *sql.Conn
+	if s.config.Verification.Checksums {
+		if sourceConn, err = checksumConn(ctx, sourceDBAdmin); err != nil {
+			log.Error(err, "could not open the source connection of the checksums")
+			return nil, err
+		}
+		defer sourceConn.Close()
+		if targetConn, err = checksumConn(ctx, targetDBAdmin); err != nil {
+			log.Error(err, "could not open the target connection of the checksums")
+			return nil, err
+		}
+		defer targetConn.Close()
+	}
+
+	rows, err := sourceDBAdmin.QueryContext(ctx, allTableCountQ, pq.Array(schemas))
 	if err != nil {
 		log.Error(err, "failed getting source table count", tableCountQ)
 		return nil, err
 	}
 	defer rows.Close()
 
+	progress := &Progress{}
 	deuce := true
 	for rows.Next() {
-		rows.Scan(&sourceTableName, &sourceTableCount)
-		err = targetDBUser.QueryRow(fmt.Sprintf(tableCountQ, sourceTableName)).Scan(&targetTableCount)
+		if err := rows.Scan(&sourceTableSchema, &sourceTableName, &sourceTableCount); err != nil {
+			return nil, err
+		}
+		qualifiedTableName := qualifiedName(sourceTableSchema, sourceTableName)
+		err = targetDBAdmin.QueryRowContext(ctx, fmt.Sprintf(tableCountQ, qualifiedTableName)).Scan(&targetTableCount)
 		if err != nil {
-			log.Error(err, "failed to query target table count - "+sourceTableName)
+			log.Error(err, "failed to query target table count - "+qualifiedTableName)
 			return nil, err
 		}
 
-		if targetTableCount < sourceTableCount {
+		table := TableProgress{
+			Name:       qualifiedTableName,
+			SourceRows: sourceTableCount,
+			TargetRows: targetTableCount,
+		}
+		// the content is compared once the target caught up, extra rows of the
+		// target make a chunk mismatch
+		if s.config.Verification.Checksums && targetTableCount >= sourceTableCount {
+			checksum, err := newTableChecksum(ctx, sourceConn, targetConn, sourceTableSchema, sourceTableName)
+			if err != nil {
+				log.Error(err, "failed to read the columns of "+qualifiedTableName)
+				return nil, err
+			}
+			if err := checksum.compare(ctx, s.config.Verification, sourceTableCount, &table); err != nil {
+				log.Error(err, "failed to compare the checksums of "+qualifiedTableName)
+				return nil, err
+			}
+		}
+		progress.Tables = append(progress.Tables, table)
+		if table.ChunksMismatched > 0 {
+			deuce = false
+			log.Error(fmt.Errorf("warning: table checksum not matching. intervention required if this message repeates idenfinetly"),
+				"tableName", qualifiedTableName,
+				"chunksVerified", table.ChunksVerified,
+				"chunksMismatched", table.ChunksMismatched,
+				"mismatch", table.Mismatch,
+			)
+		} else if targetTableCount < sourceTableCount {
 			deuce = false
 			log.Error(fmt.Errorf("warning: table count not matching. intervention required if this message repeates idenfinetly"),
-				"tableName", sourceTableName,
+				"tableName", qualifiedTableName,
 				"sourceTableCount", sourceTableCount,
 				"targetTableCount", targetTableCount,
 			)
 		} else {
+			progress.TablesValidated++
+			progress.RowsValidated += sourceTableCount
 			log.Info("table count looks ok",
-				"tableName", sourceTableName,
+				"tableName", qualifiedTableName,
 				"sourceTableCount", sourceTableCount,
 				"targetTableCount", targetTableCount,
 			)
 		}
 	}
+	if err := rows.Err(); err != nil {
+		log.Error(err, "failed getting source table count")
+		return nil, err
+	}
+	// only the progress of a complete validation is reported
+	s.progress = progress
 	if deuce {
 		log.Info("completed")
 		return &disable_subscription_state{
@@ -721,6 +1177,9 @@ func (s *validate_migration_status_state) Execute() (State, error) {
 	}
 }
 
+func (s *validate_migration_status_state) Progress() *Progress {
+	return s.progress
+}
 func (s *validate_migration_status_state) Id() StateEnum {
 	return S_ValidateMigrationStatus
 }
@@ -728,13 +1187,15 @@ func (s *validate_migration_status_state) String() string {
 	return S_ValidateMigrationStatus.String()
 }
 
-func (s *disable_subscription_state) Execute() (State, error) {
+func (s *disable_subscription_state) Execute(ctx context.Context) (State, error) {
+	ctx, cancel := s.config.withTimeout(ctx, s.Id())
+	defer cancel()
 	log := s.config.Log.WithValues("state", s.String())
 	log.Info("started")
 
 	var exists bool
 
-	targetDBAdmin, err := getDB(s.config.TargetDBAdminDsn, nil)
+	targetDBAdmin, err := getDB(ctx, s.config.TargetDBAdminDsn, nil)
 	if err != nil {
 		log.Error(err, "connection test failed for targetDBAdmin")
 
@@ -744,13 +1205,14 @@ func (s *disable_subscription_state) Execute() (State, error) {
 
 	q := "SELECT EXISTS(SELECT subname FROM pg_catalog.pg_subscription WHERE subname = $1)"
 
-	err = targetDBAdmin.QueryRow(q, DefaultSubName).Scan(&exists)
+	subName := s.config.subscription()
+	err = targetDBAdmin.QueryRowContext(ctx, q, subName).Scan(&exists)
 	if err != nil {
 		log.Error(err, "could not query for Subscription name")
 		return nil, err
 	}
 	if exists {
-		if _, err := targetDBAdmin.Exec(fmt.Sprintf("ALTER SUBSCRIPTION %s DISABLE", DefaultSubName)); err != nil {
+		if _, err := s.config.exec(ctx, targetDBAdmin, s.config.TargetDBAdminDsn, fmt.Sprintf("ALTER SUBSCRIPTION %s DISABLE", pq.QuoteIdentifier(subName))); err != nil {
 			log.Error(err, "could not disable Subscription")
 			return nil, err
 		}
@@ -774,12 +1236,14 @@ func (s *disable_subscription_state) String() string {
 	return S_DisableSubscription.String()
 }
 
-func (s *delete_subscription_state) Execute() (State, error) {
+func (s *delete_subscription_state) Execute(ctx context.Context) (State, error) {
+	ctx, cancel := s.config.withTimeout(ctx, s.Id())
+	defer cancel()
 	log := s.config.Log.WithValues("state", s.String())
 	log.Info("started")
 
 	var exists bool
-	targetDBAdmin, err := getDB(s.config.TargetDBAdminDsn, nil)
+	targetDBAdmin, err := getDB(ctx, s.config.TargetDBAdminDsn, nil)
 	if err != nil {
 		log.Error(err, "connection test failed for targetDBAdmin")
 		return nil, err
@@ -790,9 +1254,10 @@ func (s *delete_subscription_state) Execute() (State, error) {
 			SELECT subname 
 			FROM pg_catalog.pg_subscription 
 			WHERE subname = $1)`
-	delete := fmt.Sprintf("DROP SUBSCRIPTION %s", (DefaultSubName))
+	subName := s.config.subscription()
+	delete := fmt.Sprintf("DROP SUBSCRIPTION %s", pq.QuoteIdentifier(subName))
 
-	err = targetDBAdmin.QueryRow(q, DefaultSubName).Scan(&exists)
+	err = targetDBAdmin.QueryRowContext(ctx, q, subName).Scan(&exists)
 
 	if err != nil {
 		log.Error(err, "failed to query subscription by name")
@@ -800,7 +1265,7 @@ func (s *delete_subscription_state) Execute() (State, error) {
 	}
 	if exists {
 		log.Info("deleting subscription", "with", delete)
-		if _, err := targetDBAdmin.Exec(delete); err != nil {
+		if _, err := s.config.exec(ctx, targetDBAdmin, s.config.TargetDBAdminDsn, delete); err != nil {
 			log.Error(err, "could not delete subscription")
 			return nil, err
 		}
@@ -818,13 +1283,15 @@ func (s *delete_subscription_state) String() string {
 	return S_DeleteSubscription.String()
 }
 
-func (s *delete_publication_state) Execute() (State, error) {
+func (s *delete_publication_state) Execute(ctx context.Context) (State, error) {
+	ctx, cancel := s.config.withTimeout(ctx, s.Id())
+	defer cancel()
 	log := s.config.Log.WithValues("state", s.String())
 	log.Info("started")
 
 	var exists bool
 
-	sourceDBAdmin, err := getDB(s.config.SourceDBAdminDsn, nil)
+	sourceDBAdmin, err := getDB(ctx, s.config.SourceDBAdminDsn, nil)
 	if err != nil {
 		log.Error(err, "connection test failed for sourceDBAdmin")
 		return nil, err
@@ -837,15 +1304,16 @@ func (s *delete_publication_state) Execute() (State, error) {
 			FROM pg_catalog.pg_publication 
 			WHERE pubname = $1)`
 
-	err = sourceDBAdmin.QueryRow(q, DefaultPubName).Scan(&exists)
+	pubName := s.config.publication()
+	err = sourceDBAdmin.QueryRowContext(ctx, q, pubName).Scan(&exists)
 
 	if err != nil {
 		log.Error(err, "query for publication name failed")
 		return nil, err
 	}
 	if exists {
-		log.Info("deleting publication")
-		if _, err := sourceDBAdmin.Exec(fmt.Sprintf("DROP PUBLICATION %s", DefaultPubName)); err != nil {
+		log.Info("deleting publication", "name", pubName)
+		if _, err := s.config.exec(ctx, sourceDBAdmin, s.config.SourceDBAdminDsn, fmt.Sprintf("DROP PUBLICATION %s", pq.QuoteIdentifier(pubName))); err != nil {
 			log.Error(err, "delete publication failed")
 			return nil, err
 		}
@@ -865,7 +1333,7 @@ func (s *delete_publication_state) String() string {
 	return S_DeletePublication.String()
 }
 
-func (s *completed_state) Execute() (State, error) {
+func (s *completed_state) Execute(ctx context.Context) (State, error) {
 
 	return nil, nil
 }
@@ -876,7 +1344,7 @@ func (s *completed_state) String() string {
 	return S_Completed.String()
 }
 
-func (s *retry_state) Execute() (State, error) {
+func (s *retry_state) Execute(ctx context.Context) (State, error) {
 	return nil, nil
 }
 func (s *retry_state) Id() StateEnum {
@@ -891,3 +1359,42 @@ func retry(c Config) State {
 		config: c,
 	}
 }
+
+func (s *failed_state) Execute(ctx context.Context) (State, error) {
+	if s.err == nil {
+		return nil, ErrMigrationFailed
+	}
+	return nil, fmt.Errorf("%w: %s", ErrMigrationFailed, s.err)
+}
+func (s *failed_state) Id() StateEnum {
+	return S_Failed
+}
+func (s *failed_state) String() string {
+	return S_Failed.String()
+}
+
+// Err returns the last error of the migration, nil when it is not known
+func (s *failed_state) Err() error {
+	return s.err
+}
+
+// Failed returns the terminal state of a migration that exhausted its attempts, err is
+// the last error of the migration
+func Failed(c Config, err error) State {
+	return &failed_state{
+		config: c,
+		err:    err,
+	}
+}
+
+// withTimeout returns ctx bounded by the timeout of state id
+func (c Config) withTimeout(ctx context.Context, id StateEnum) (context.Context, context.CancelFunc) {
+	timeout, ok := c.Timeouts[id]
+	if !ok {
+		timeout, ok = defaultTimeouts[id]
+	}
+	if !ok {
+		timeout = DefaultStateTimeout
+	}
+	return context.WithTimeout(ctx, timeout)
+}
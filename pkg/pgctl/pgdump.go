@@ -1,6 +1,8 @@
 package pgctl
 
 import (
+	"context"
+	"crypto/rand"
 	"fmt"
 	"os/exec"
 	"strings"
@@ -40,6 +42,7 @@ type Dump struct {
 	Path     string
 	Format   *string
 	Options  []string
+	Schemas  []string
 	fileName string
 }
 
@@ -48,20 +51,33 @@ func NewDump(DsnUri string) *Dump {
 }
 
 func (x *Dump) Exec(opts ExecOptions) Result {
+	return x.ExecContext(context.Background(), opts)
+}
+
+// ExecContext runs pg_dump, it is killed when ctx is done
+func (x *Dump) ExecContext(ctx context.Context, opts ExecOptions) Result {
 	result := Result{Mine: "application/x-tar"}
 	result.FileName = x.GetFileName()
 	options := append(x.dumpOptions(), fmt.Sprintf(`-f%s%v`, x.Path, result.FileName))
 	result.FullCommand = strings.Join(options, " ")
-	cmd := exec.Command(PGDump, options...)
+	cmd := exec.CommandContext(ctx, PGDump, options...)
 	// cmd.Env = append(os.Environ(), x.EnvPassword)
 	stderrIn, _ := cmd.StderrPipe()
 	go func() {
 		result.Output = streamExecOutput(stderrIn, opts)
 	}()
-	cmd.Start()
+	if err := cmd.Start(); err != nil {
+		result.Error = &ResultError{Err: err, ExitCode: -1}
+		return result
+	}
 	err := cmd.Wait()
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
 	if exitError, ok := err.(*exec.ExitError); ok {
 		result.Error = &ResultError{Err: err, ExitCode: exitError.ExitCode(), CmdOutput: result.Output}
+	} else if err != nil {
+		result.Error = &ResultError{Err: err, ExitCode: -1, CmdOutput: result.Output}
 	}
 	return result
 }
@@ -93,9 +109,15 @@ func (x *Dump) SetPath(path string) {
 	x.Path = path
 }
 
+func (x *Dump) SetSchemas(schemas []string) {
+	x.Schemas = schemas
+}
+
+// newFileName returns a name unique to the dump, dumps started in the same second do not collide
 func (x *Dump) newFileName() string {
-	fmt.Println(pq.ParseURL(x.DsnUri))
-	return fmt.Sprintf(`%v_%v.sql`, "pub", time.Now().Unix())
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return fmt.Sprintf(`%v_%v_%x.sql`, "pub", time.Now().Unix(), suffix)
 }
 
 func (x *Dump) dumpOptions() []string {
@@ -110,6 +132,10 @@ func (x *Dump) dumpOptions() []string {
 	if x.Verbose {
 		options = append(options, "-v")
 	}
+	// quoted patterns match the schema name exactly
+	for _, schema := range x.Schemas {
+		options = append(options, "--schema="+pq.QuoteIdentifier(schema))
+	}
 	return options
 }
 
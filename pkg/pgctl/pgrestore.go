@@ -1,6 +1,7 @@
 package pgctl
 
 import (
+	"context"
 	"fmt"
 	"os/exec"
 	"strings"
@@ -20,26 +21,39 @@ type Restore struct {
 }
 
 func NewRestore(DsnUri string) *Restore {
-	return &Restore{Options: PGDRestoreOpts, DsnUri: DsnUri, Schemas: []string{"public"}}
+	return &Restore{Options: PGDRestoreOpts, DsnUri: DsnUri}
 }
 
 func (x *Restore) Exec(filename string, opts ExecOptions) Result {
+	return x.ExecContext(context.Background(), filename, opts)
+}
+
+// ExecContext runs psql on filename, it is killed when ctx is done
+func (x *Restore) ExecContext(ctx context.Context, filename string, opts ExecOptions) Result {
 	result := Result{}
 	options := []string{x.DsnUri, "-vON_ERROR_STOP=ON",
 		fmt.Sprintf("--file=%s%s", x.Path, filename)}
 	options = append(options, x.restoreOptions()...)
 	result.FullCommand = strings.Join(options, " ")
-	cmd := exec.Command(PSQL, options...)
+	cmd := exec.CommandContext(ctx, PSQL, options...)
 
 	//cmd.Env = append(os.Environ(), x.EnvPassword)
 	stderrIn, _ := cmd.StderrPipe()
 	go func() {
 		result.Output = streamExecOutput(stderrIn, opts)
 	}()
-	cmd.Start()
+	if err := cmd.Start(); err != nil {
+		result.Error = &ResultError{Err: err, ExitCode: -1}
+		return result
+	}
 	err := cmd.Wait()
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
 	if exitError, ok := err.(*exec.ExitError); ok {
 		result.Error = &ResultError{Err: err, ExitCode: exitError.ExitCode(), CmdOutput: result.Output}
+	} else if err != nil {
+		result.Error = &ResultError{Err: err, ExitCode: -1, CmdOutput: result.Output}
 	}
 	return result
 }
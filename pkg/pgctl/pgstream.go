@@ -0,0 +1,105 @@
+package pgctl
+
+import (
+	"context"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Stream restores the output of dump with restore without writing it to a file. The
+// statements are run in a transaction that is only committed once pg_dump succeeded,
+// nothing is restored when either command fails.
+func Stream(ctx context.Context, dump *Dump, restore *Restore, opts ExecOptions) (dumpResult Result, restoreResult Result) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	dumpOptions := dump.dumpOptions()
+	dumpResult = Result{FullCommand: strings.Join(dumpOptions, " ")}
+	restoreOptions := append([]string{restore.DsnUri, "-vON_ERROR_STOP=ON"}, restore.restoreOptions()...)
+	restoreResult = Result{FullCommand: strings.Join(restoreOptions, " ")}
+
+	dumpCmd := exec.CommandContext(ctx, PGDump, dumpOptions...)
+	restoreCmd := exec.CommandContext(ctx, PSQL, restoreOptions...)
+	dumpOut, err := dumpCmd.StdoutPipe()
+	if err != nil {
+		dumpResult.Error = &ResultError{Err: err, ExitCode: -1}
+		return
+	}
+	restoreIn, err := restoreCmd.StdinPipe()
+	if err != nil {
+		restoreResult.Error = &ResultError{Err: err, ExitCode: -1}
+		return
+	}
+	restoreStderr := captureStderr(restoreCmd, &restoreResult, opts)
+	if err := restoreCmd.Start(); err != nil {
+		restoreResult.Error = &ResultError{Err: err, ExitCode: -1}
+		return
+	}
+	dumpStderr := captureStderr(dumpCmd, &dumpResult, opts)
+	if err := dumpCmd.Start(); err != nil {
+		dumpResult.Error = &ResultError{Err: err, ExitCode: -1}
+		// psql rolls back the open transaction when its input is closed
+		restoreIn.Close()
+		restoreStderr.Wait()
+		restoreCmd.Wait()
+		return
+	}
+
+	_, copyErr := io.WriteString(restoreIn, "BEGIN;\n")
+	if copyErr == nil {
+		_, copyErr = io.Copy(restoreIn, dumpOut)
+	}
+	if copyErr != nil {
+		// psql stopped reading, pg_dump would block on its output
+		cancel()
+	}
+	dumpStderr.Wait()
+	dumpErr := dumpCmd.Wait()
+	if dumpErr != nil && ctx.Err() != nil {
+		dumpErr = ctx.Err()
+	}
+	if copyErr == nil && dumpErr == nil {
+		_, copyErr = io.WriteString(restoreIn, "COMMIT;\n")
+	}
+	restoreIn.Close()
+	restoreStderr.Wait()
+	restoreErr := restoreCmd.Wait()
+
+	// pg_dump was killed because psql failed, the error of psql is reported
+	if copyErr != nil && restoreErr == nil {
+		restoreErr = copyErr
+	}
+	restoreResult.Error = resultError(restoreErr, restoreResult.Output)
+	if copyErr == nil {
+		dumpResult.Error = resultError(dumpErr, dumpResult.Output)
+	}
+	return
+}
+
+// captureStderr streams the standard error of cmd into result.Output, the returned
+// group is done once cmd closed it
+func captureStderr(cmd *exec.Cmd, result *Result, opts ExecOptions) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return &wg
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		result.Output = streamExecOutput(stderr, opts)
+	}()
+	return &wg
+}
+
+func resultError(err error, output string) *ResultError {
+	if err == nil {
+		return nil
+	}
+	if exitError, ok := err.(*exec.ExitError); ok {
+		return &ResultError{Err: err, ExitCode: exitError.ExitCode(), CmdOutput: output}
+	}
+	return &ResultError{Err: err, ExitCode: -1, CmdOutput: output}
+}
@@ -0,0 +1,242 @@
+package pgctl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// names of the pre-flight checks
+const (
+	CheckWalLevel           = "wal_level"
+	CheckReplicationSlots   = "replication_slots"
+	CheckReplicaIdentity    = "replica_identity"
+	CheckExtensions         = "extensions"
+	CheckColumnTypes        = "column_types"
+	maxPreFlightCheckDetail = 10
+)
+
+// PreFlightCheck is the outcome of a check run before the migration starts
+type PreFlightCheck struct {
+	Name    string
+	Passed  bool
+	Message string
+}
+
+// PreFlightReport lists the checks run before the migration starts
+type PreFlightReport struct {
+	Checks []PreFlightCheck
+}
+
+// PreFlightReporter is implemented by the states checking the migration can run
+type PreFlightReporter interface {
+	// PreFlightReport returns the report of the last Execute, nil before it
+	PreFlightReport() *PreFlightReport
+}
+
+// Passed reports whether every check of the report passed
+func (r *PreFlightReport) Passed() bool {
+	for _, c := range r.Checks {
+		if !c.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the messages of the failed checks
+func (r *PreFlightReport) String() string {
+	var failed []string
+	for _, c := range r.Checks {
+		if !c.Passed {
+			failed = append(failed, c.Name+": "+c.Message)
+		}
+	}
+	return strings.Join(failed, "; ")
+}
+
+func (r *PreFlightReport) add(name string, passed bool, format string, a ...interface{}) {
+	r.Checks = append(r.Checks, PreFlightCheck{Name: name, Passed: passed, Message: fmt.Sprintf(format, a...)})
+}
+
+// checkWalLevel checks the source publishes logical changes
+func checkWalLevel(ctx context.Context, source *sql.DB, report *PreFlightReport) error {
+	var level string
+	if err := source.QueryRowContext(ctx, "SELECT current_setting('wal_level')").Scan(&level); err != nil {
+		return err
+	}
+	report.add(CheckWalLevel, level == "logical", "wal_level of the source is %s, logical is required", level)
+	return nil
+}
+
+// checkReplicationSlots checks the source has a replication slot left for the subscription
+func checkReplicationSlots(ctx context.Context, source *sql.DB, slot string, report *PreFlightReport) error {
+	var (
+		max, used int
+		exists    bool
+	)
+	err := source.QueryRowContext(ctx, `
+		SELECT current_setting('max_replication_slots')::int,
+			(SELECT count(*) FROM pg_catalog.pg_replication_slots),
+			EXISTS(SELECT 1 FROM pg_catalog.pg_replication_slots WHERE slot_name = $1)`, slot).
+		Scan(&max, &used, &exists)
+	if err != nil {
+		return err
+	}
+	report.add(CheckReplicationSlots, exists || used < max,
+		"%d of the %d replication slots of the source are used", used, max)
+	return nil
+}
+
+// checkReplicaIdentity checks UPDATE and DELETE of the tables of schemas can be replicated,
+// which requires a primary key or a replica identity
+func checkReplicaIdentity(ctx context.Context, source *sql.DB, schemas []string, report *PreFlightReport) error {
+	tables, err := queryNames(ctx, source, `
+		SELECT n.nspname, c.relname
+		FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind = 'r'
+			AND n.nspname = ANY($1)
+			AND (c.relreplident = 'n'
+				OR (c.relreplident = 'd' AND NOT EXISTS (
+					SELECT 1 FROM pg_catalog.pg_index i
+					WHERE i.indrelid = c.oid AND i.indisprimary)))
+		ORDER BY 1, 2`, pq.Array(schemas))
+	if err != nil {
+		return err
+	}
+	if len(tables) == 0 {
+		report.add(CheckReplicaIdentity, true, "all tables have a primary key or a replica identity")
+		return nil
+	}
+	report.add(CheckReplicaIdentity, false, "%d tables have no primary key or replica identity: %s",
+		len(tables), summarize(tables))
+	return nil
+}
+
+// checkExtensions checks the extensions of the source are available on the target
+func checkExtensions(ctx context.Context, source, target *sql.DB, report *PreFlightReport) error {
+	rows, err := source.QueryContext(ctx, `
+		SELECT extname
+		FROM pg_catalog.pg_extension
+		WHERE extname <> 'plpgsql'
+		ORDER BY extname`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var extensions []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		extensions = append(extensions, name)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	available := make(map[string]bool)
+	rows, err = target.QueryContext(ctx, `
+		SELECT name
+		FROM pg_catalog.pg_available_extensions
+		WHERE name = ANY($1)`, pq.Array(extensions))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		available[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, name := range extensions {
+		if !available[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		report.add(CheckExtensions, true, "%d extensions of the source are available on the target", len(extensions))
+		return nil
+	}
+	report.add(CheckExtensions, false, "extensions not available on the target: %s", summarize(missing))
+	return nil
+}
+
+// checkColumnTypes checks the types of the columns of the tables of schemas are created
+// on the target, only the types of schemas and of the system schemas are
+func checkColumnTypes(ctx context.Context, source *sql.DB, schemas []string, report *PreFlightReport) error {
+	copied := append([]string{"pg_catalog", "information_schema"}, schemas...)
+	rows, err := source.QueryContext(ctx, `
+		SELECT n.nspname, c.relname, a.attname, format_type(a.atttypid, a.atttypmod)
+		FROM pg_catalog.pg_attribute a
+		JOIN pg_catalog.pg_class c ON c.oid = a.attrelid
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_catalog.pg_type t ON t.oid = a.atttypid
+		JOIN pg_catalog.pg_namespace tn ON tn.oid = t.typnamespace
+		WHERE c.relkind = 'r'
+			AND a.attnum > 0
+			AND NOT a.attisdropped
+			AND n.nspname = ANY($1)
+			AND tn.nspname <> ALL($2)
+		ORDER BY 1, 2, 3`, pq.Array(schemas), pq.Array(copied))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var columns []string
+	for rows.Next() {
+		var schema, table, column, typ string
+		if err := rows.Scan(&schema, &table, &column, &typ); err != nil {
+			return err
+		}
+		columns = append(columns, fmt.Sprintf("%s.%s (%s)", qualifiedName(schema, table), pq.QuoteIdentifier(column), typ))
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		report.add(CheckColumnTypes, true, "all column types are created on the target")
+		return nil
+	}
+	report.add(CheckColumnTypes, false, "%d columns have a type of a schema that is not migrated: %s",
+		len(columns), summarize(columns))
+	return nil
+}
+
+// queryNames returns the qualified names of the schema and name rows of query
+func queryNames(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var schema, name string
+		if err := rows.Scan(&schema, &name); err != nil {
+			return nil, err
+		}
+		names = append(names, qualifiedName(schema, name))
+	}
+	return names, rows.Err()
+}
+
+// summarize joins the first maxPreFlightCheckDetail names
+func summarize(names []string) string {
+	if len(names) > maxPreFlightCheckDetail {
+		return fmt.Sprintf("%s and %d more", strings.Join(names[:maxPreFlightCheckDetail], ", "), len(names)-maxPreFlightCheckDetail)
+	}
+	return strings.Join(names, ", ")
+}
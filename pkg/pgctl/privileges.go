@@ -0,0 +1,450 @@
+package pgctl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// PrivilegeReport lists the roles and privileges of the source migrated to the target
+type PrivilegeReport struct {
+	// Created are the non-login roles of the source created on the target
+	Created []string
+	// Unmapped are the roles of the source without an equivalent on the target, their
+	// grants and ownerships are not migrated
+	Unmapped []string
+	// Grants, default privileges and ownerships replayed on the target
+	Grants            int
+	DefaultPrivileges int
+	Owners            int
+}
+
+// PrivilegeReporter is implemented by the states migrating the privileges
+type PrivilegeReporter interface {
+	// PrivilegeReport returns the report of the last Execute, nil before it
+	PrivilegeReport() *PrivilegeReport
+}
+
+// privilegeObjects are the schemas, relations, routines, types and domains of the
+// schemas $1, with their kind in ALTER statements. Objects of extensions are left to
+// them, as are the row types of relations and the array and multirange types created
+// with another type, which follow it. Operators, operator classes and families,
+// collations, conversions and text search objects are not covered.
+const privilegeObjects = `
+	WITH objects(kind, name, owner, acl) AS (
+		SELECT 'SCHEMA', quote_ident(n.nspname), n.nspowner, n.nspacl
+		FROM pg_catalog.pg_namespace n
+		WHERE n.nspname = ANY($1)
+		UNION ALL
+		SELECT CASE c.relkind
+				WHEN 'S' THEN 'SEQUENCE'
+				WHEN 'v' THEN 'VIEW'
+				WHEN 'm' THEN 'MATERIALIZED VIEW'
+				WHEN 'f' THEN 'FOREIGN TABLE'
+				ELSE 'TABLE' END,
+			quote_ident(n.nspname) || '.' || quote_ident(c.relname), c.relowner, c.relacl
+		FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = ANY($1)
+			AND c.relkind IN ('r', 'p', 'v', 'm', 'S', 'f')
+			AND NOT EXISTS (SELECT 1 FROM pg_catalog.pg_depend d
+				WHERE d.classid = 'pg_catalog.pg_class'::regclass AND d.objid = c.oid AND d.deptype = 'e')
+		UNION ALL
+		SELECT 'ROUTINE',
+			quote_ident(n.nspname) || '.' || quote_ident(p.proname) ||
+				'(' || pg_catalog.pg_get_function_identity_arguments(p.oid) || ')',
+			p.proowner, p.proacl
+		FROM pg_catalog.pg_proc p
+		JOIN pg_catalog.pg_namespace n ON n.oid = p.pronamespace
+		WHERE n.nspname = ANY($1)
+			AND NOT EXISTS (SELECT 1 FROM pg_catalog.pg_depend d
+				WHERE d.classid = 'pg_catalog.pg_proc'::regclass AND d.objid = p.oid AND d.deptype = 'e')
+		UNION ALL
+		SELECT CASE t.typtype WHEN 'd' THEN 'DOMAIN' ELSE 'TYPE' END,
+			quote_ident(n.nspname) || '.' || quote_ident(t.typname), t.typowner, t.typacl
+		FROM pg_catalog.pg_type t
+		JOIN pg_catalog.pg_namespace n ON n.oid = t.typnamespace
+		WHERE n.nspname = ANY($1)
+			AND t.typtype IN ('b', 'c', 'd', 'e', 'r')
+			AND (t.typrelid = 0 OR EXISTS (SELECT 1 FROM pg_catalog.pg_class c
+				WHERE c.oid = t.typrelid AND c.relkind = 'c'))
+			AND NOT EXISTS (SELECT 1 FROM pg_catalog.pg_type e
+				WHERE e.oid = t.typelem AND e.typarray = t.oid)
+			AND NOT EXISTS (SELECT 1 FROM pg_catalog.pg_depend d
+				WHERE d.classid = 'pg_catalog.pg_type'::regclass AND d.objid = t.oid AND d.deptype = 'e')
+	)`
+
+// defaultPrivilegeTypes are the object types of ALTER DEFAULT PRIVILEGES by defaclobjtype
+var defaultPrivilegeTypes = map[string]string{
+	"r": "TABLES",
+	"S": "SEQUENCES",
+	"f": "FUNCTIONS",
+	"T": "TYPES",
+	"n": "SCHEMAS",
+}
+
+// objectOwner is an object of the source and its owner
+type objectOwner struct {
+	kind, name, owner string
+	// hasACL is set when the privileges of the object are not the default ones
+	hasACL bool
+}
+
+// objectPrivilege is a privilege granted on an object of the source
+type objectPrivilege struct {
+	kind, name, owner string
+	// grantee is empty for PUBLIC
+	grantee   string
+	privilege string
+	grantable bool
+}
+
+// defaultPrivilege is a privilege granted on the objects role creates, in schema or
+// in every schema when it is empty
+type defaultPrivilege struct {
+	role, schema, objectType string
+	// grantee is empty for PUBLIC
+	grantee   string
+	privilege string
+	grantable bool
+}
+
+// roleMembership is a membership of member in role
+type roleMembership struct {
+	role, member string
+	admin        bool
+}
+
+// roleMapper maps the roles of the source to their equivalent on the target
+type roleMapper struct {
+	// roles maps roles of the source to roles of the target, before any other rule
+	roles map[string]string
+	// admins of the source and the target, the owner of the copied schema
+	sourceAdmin, targetAdmin string
+	// canLogin and super are the attributes of the roles of the source
+	canLogin, super map[string]bool
+	// exists are the roles of the target
+	exists map[string]bool
+	// create creates a non-login role on the target
+	create func(role string) error
+	mapped map[string]string
+	report *PrivilegeReport
+}
+
+// newRoleMapper reads the roles of source and target
+func newRoleMapper(ctx context.Context, source, target *sql.DB, roles map[string]string, report *PrivilegeReport) (*roleMapper, error) {
+	m := &roleMapper{
+		roles:    roles,
+		canLogin: make(map[string]bool),
+		super:    make(map[string]bool),
+		exists:   make(map[string]bool),
+		mapped:   make(map[string]string),
+		report:   report,
+	}
+	if err := source.QueryRowContext(ctx, "SELECT session_user").Scan(&m.sourceAdmin); err != nil {
+		return nil, err
+	}
+	if err := target.QueryRowContext(ctx, "SELECT session_user").Scan(&m.targetAdmin); err != nil {
+		return nil, err
+	}
+	rows, err := source.QueryContext(ctx, "SELECT rolname, rolcanlogin, rolsuper FROM pg_catalog.pg_roles")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			name            string
+			canLogin, super bool
+		)
+		if err := rows.Scan(&name, &canLogin, &super); err != nil {
+			return nil, err
+		}
+		m.canLogin[name] = canLogin
+		m.super[name] = super
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows, err = target.QueryContext(ctx, "SELECT rolname FROM pg_catalog.pg_roles")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		m.exists[name] = true
+	}
+	return m, rows.Err()
+}
+
+// target returns the role of the target equivalent to role of the source, the role
+// itself when the target has it. Missing non-login roles are created, false is
+// returned for the other roles missing.
+func (m *roleMapper) target(role string) (string, bool, error) {
+	if mapped, ok := m.mapped[role]; ok {
+		return mapped, mapped != "", nil
+	}
+	mapped := ""
+	switch {
+	case m.roles[role] != "":
+		mapped = m.roles[role]
+	case role == m.sourceAdmin:
+		mapped = m.targetAdmin
+	case m.exists[role]:
+		mapped = role
+	case m.super[role]:
+		// superusers of the source, like the bootstrap user, are not migrated
+		mapped = m.targetAdmin
+	case !m.canLogin[role] && !strings.HasPrefix(role, "pg_"):
+		if err := m.create(role); err != nil {
+			return "", false, err
+		}
+		m.exists[role] = true
+		m.report.Created = append(m.report.Created, role)
+		mapped = role
+	default:
+		m.report.Unmapped = append(m.report.Unmapped, role)
+		sort.Strings(m.report.Unmapped)
+	}
+	m.mapped[role] = mapped
+	return mapped, mapped != "", nil
+}
+
+// grantKind returns the kind of GRANT statements of the objects of kind, every
+// relation but sequences is a table
+func grantKind(kind string) string {
+	switch kind {
+	case "VIEW", "MATERIALIZED VIEW", "FOREIGN TABLE":
+		return "TABLE"
+	}
+	return kind
+}
+
+// grantee returns the grantee of the target equivalent to grantee of the source
+func (m *roleMapper) grantee(grantee string) (string, bool, error) {
+	if grantee == "" {
+		return "PUBLIC", true, nil
+	}
+	role, ok, err := m.target(grantee)
+	return pq.QuoteIdentifier(role), ok, err
+}
+
+// privilegeStatements returns the statements replaying on the target the privileges,
+// default privileges and ownerships of the source, mapping their roles with m. The
+// memberships of the roles created are read with memberships.
+func privilegeStatements(m *roleMapper, owners []objectOwner, privileges []objectPrivilege, defaults []defaultPrivilege,
+	memberships func(roles []string) ([]roleMembership, error)) ([]string, error) {
+	var stmts []string
+	withGrantOption := func(grantable bool, option string) string {
+		if grantable {
+			return " WITH " + option + " OPTION"
+		}
+		return ""
+	}
+
+	// the default privileges of the objects with an ACL are replaced by the ones granted
+	for _, o := range owners {
+		if o.hasACL {
+			stmts = append(stmts, fmt.Sprintf("REVOKE ALL ON %s %s FROM PUBLIC", grantKind(o.kind), o.name))
+		}
+	}
+	for _, p := range privileges {
+		// the privileges of the owner follow the ownership
+		if p.grantee == p.owner {
+			continue
+		}
+		grantee, ok, err := m.grantee(p.grantee)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		stmts = append(stmts, fmt.Sprintf("GRANT %s ON %s %s TO %s%s",
+			p.privilege, grantKind(p.kind), p.name, grantee, withGrantOption(p.grantable, "GRANT")))
+		m.report.Grants++
+	}
+
+	for _, d := range defaults {
+		if d.grantee == d.role {
+			continue
+		}
+		objectType, ok := defaultPrivilegeTypes[d.objectType]
+		if !ok {
+			continue
+		}
+		role, ok, err := m.target(d.role)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		grantee, ok, err := m.grantee(d.grantee)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		inSchema := ""
+		if d.schema != "" {
+			inSchema = " IN SCHEMA " + pq.QuoteIdentifier(d.schema)
+		}
+		stmts = append(stmts, fmt.Sprintf("ALTER DEFAULT PRIVILEGES FOR ROLE %s%s GRANT %s ON %s TO %s%s",
+			pq.QuoteIdentifier(role), inSchema, d.privilege, objectType, grantee, withGrantOption(d.grantable, "GRANT")))
+		m.report.DefaultPrivileges++
+	}
+
+	// the restore made the target admin the owner of every object
+	var owned []string
+	for _, o := range owners {
+		owner, ok, err := m.target(o.owner)
+		if err != nil {
+			return nil, err
+		}
+		if !ok || owner == m.targetAdmin {
+			continue
+		}
+		owned = append(owned, fmt.Sprintf("ALTER %s %s OWNER TO %s", o.kind, o.name, pq.QuoteIdentifier(owner)))
+		m.report.Owners++
+	}
+
+	// roles created while mapping the memberships have theirs replayed too
+	granted := make(map[roleMembership]bool)
+	for done := 0; done < len(m.report.Created); {
+		created := m.report.Created[done:]
+		done = len(m.report.Created)
+		members, err := memberships(created)
+		if err != nil {
+			return nil, err
+		}
+		for _, am := range members {
+			if granted[am] {
+				continue
+			}
+			granted[am] = true
+			role, ok, err := m.target(am.role)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			member, ok, err := m.target(am.member)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			stmts = append(stmts, fmt.Sprintf("GRANT %s TO %s%s",
+				pq.QuoteIdentifier(role), pq.QuoteIdentifier(member), withGrantOption(am.admin, "ADMIN")))
+		}
+	}
+	return append(stmts, owned...), nil
+}
+
+// sourceOwners returns the objects of schemas on db with their owner
+func sourceOwners(ctx context.Context, db *sql.DB, schemas []string) ([]objectOwner, error) {
+	rows, err := db.QueryContext(ctx, privilegeObjects+`
+		SELECT kind, name, pg_catalog.pg_get_userbyid(owner), acl IS NOT NULL
+		FROM objects
+		ORDER BY kind, name`, pq.Array(schemas))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var owners []objectOwner
+	for rows.Next() {
+		var o objectOwner
+		if err := rows.Scan(&o.kind, &o.name, &o.owner, &o.hasACL); err != nil {
+			return nil, err
+		}
+		owners = append(owners, o)
+	}
+	return owners, rows.Err()
+}
+
+// sourcePrivileges returns the privileges granted on the objects of schemas on db
+func sourcePrivileges(ctx context.Context, db *sql.DB, schemas []string) ([]objectPrivilege, error) {
+	rows, err := db.QueryContext(ctx, privilegeObjects+`
+		SELECT o.kind, o.name, pg_catalog.pg_get_userbyid(o.owner), COALESCE(r.rolname, ''),
+			a.privilege_type, a.is_grantable
+		FROM objects o
+		CROSS JOIN LATERAL pg_catalog.aclexplode(o.acl) a
+		LEFT JOIN pg_catalog.pg_roles r ON r.oid = a.grantee
+		ORDER BY o.kind, o.name, 4, a.privilege_type`, pq.Array(schemas))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var privileges []objectPrivilege
+	for rows.Next() {
+		var p objectPrivilege
+		if err := rows.Scan(&p.kind, &p.name, &p.owner, &p.grantee, &p.privilege, &p.grantable); err != nil {
+			return nil, err
+		}
+		privileges = append(privileges, p)
+	}
+	return privileges, rows.Err()
+}
+
+// sourceDefaultPrivileges returns the default privileges of the database of db, the
+// global ones and the ones of schemas
+func sourceDefaultPrivileges(ctx context.Context, db *sql.DB, schemas []string) ([]defaultPrivilege, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT pg_catalog.pg_get_userbyid(d.defaclrole), COALESCE(n.nspname, ''), d.defaclobjtype,
+			COALESCE(r.rolname, ''), a.privilege_type, a.is_grantable
+		FROM pg_catalog.pg_default_acl d
+		LEFT JOIN pg_catalog.pg_namespace n ON n.oid = d.defaclnamespace
+		CROSS JOIN LATERAL pg_catalog.aclexplode(d.defaclacl) a
+		LEFT JOIN pg_catalog.pg_roles r ON r.oid = a.grantee
+		WHERE d.defaclnamespace = 0 OR n.nspname = ANY($1)
+		ORDER BY 1, 2, 3, 4, 5`, pq.Array(schemas))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var defaults []defaultPrivilege
+	for rows.Next() {
+		var d defaultPrivilege
+		if err := rows.Scan(&d.role, &d.schema, &d.objectType, &d.grantee, &d.privilege, &d.grantable); err != nil {
+			return nil, err
+		}
+		defaults = append(defaults, d)
+	}
+	return defaults, rows.Err()
+}
+
+// sourceMemberships returns the memberships of db in or of roles
+func sourceMemberships(ctx context.Context, db *sql.DB, roles []string) ([]roleMembership, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT r.rolname, m.rolname, am.admin_option
+		FROM pg_catalog.pg_auth_members am
+		JOIN pg_catalog.pg_roles r ON r.oid = am.roleid
+		JOIN pg_catalog.pg_roles m ON m.oid = am.member
+		WHERE r.rolname = ANY($1) OR m.rolname = ANY($1)
+		ORDER BY 1, 2`, pq.Array(roles))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var memberships []roleMembership
+	for rows.Next() {
+		var am roleMembership
+		if err := rows.Scan(&am.role, &am.member, &am.admin); err != nil {
+			return nil, err
+		}
+		memberships = append(memberships, am)
+	}
+	return memberships, rows.Err()
+}
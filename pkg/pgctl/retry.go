@@ -0,0 +1,49 @@
+package pgctl
+
+import "time"
+
+// RetryPolicy bounds the attempts of a migration state that fails or has to be retried
+type RetryPolicy struct {
+	// InitialInterval is waited after the first attempt, doubled after each following
+	// one up to MaxInterval
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	// MaxAttempts of a state before the migration fails, unbounded when 0
+	MaxAttempts int
+	// PollInterval is waited, without counting an attempt, before a state waiting on
+	// the migration is retried when the migration progressed
+	PollInterval time.Duration
+}
+
+// DefaultRetryPolicy waits from a minute to half an hour between attempts, the migration
+// fails after 100 attempts of a state, about two days. A progressing migration is polled
+// every 30 seconds.
+var DefaultRetryPolicy = RetryPolicy{
+	InitialInterval: time.Minute,
+	MaxInterval:     30 * time.Minute,
+	MaxAttempts:     100,
+	PollInterval:    30 * time.Second,
+}
+
+// Backoff returns the time to wait after attempt, counted from 1
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	interval := p.InitialInterval
+	if interval <= 0 {
+		interval = DefaultRetryPolicy.InitialInterval
+	}
+	for i := 1; i < attempt; i++ {
+		interval *= 2
+		if p.MaxInterval > 0 && interval >= p.MaxInterval {
+			return p.MaxInterval
+		}
+	}
+	if p.MaxInterval > 0 && interval > p.MaxInterval {
+		return p.MaxInterval
+	}
+	return interval
+}
+
+// Exhausted reports whether no attempt is left after attempts
+func (p RetryPolicy) Exhausted(attempts int) bool {
+	return p.MaxAttempts > 0 && attempts >= p.MaxAttempts
+}
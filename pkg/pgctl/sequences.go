@@ -0,0 +1,163 @@
+package pgctl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/big"
+
+	"github.com/lib/pq"
+)
+
+// DefaultSequenceMargin is the margin the controller sets the sequences of the target
+// ahead of the source by, unless configured otherwise
+const DefaultSequenceMargin = 10000
+
+// sequence is a sequence of the source and its state
+type sequence struct {
+	// quoted qualified name of the sequence
+	name string
+	// table, quoted and qualified, and column of the serial or identity column owning
+	// the sequence, empty for the other sequences
+	table, column       string
+	increment, min, max int64
+	lastValue           int64
+	isCalled            bool
+}
+
+// setval returns the arguments of setval making the sequence return next, on the target,
+// the value margin values after the one it returns next on the source. The margin has
+// to be positive: the sequences are set before the writes to the source stop, the
+// values the source uses meanwhile are replicated to the target.
+func (s sequence) setval(margin int64) (int64, bool, error) {
+	if margin <= 0 {
+		return 0, false, fmt.Errorf("sequence %s: the sequence margin has to be positive, got %d", s.name, margin)
+	}
+	next := big.NewInt(s.lastValue)
+	if s.isCalled {
+		next.Add(next, big.NewInt(s.increment))
+	}
+	if s.increment < 0 {
+		margin = -margin
+	}
+	next.Add(next, big.NewInt(margin))
+	if next.Cmp(big.NewInt(s.min)) < 0 || next.Cmp(big.NewInt(s.max)) > 0 {
+		return 0, false, fmt.Errorf("sequence %s: %s is out of its range %d to %d, lower the sequence margin",
+			s.name, next, s.min, s.max)
+	}
+	return next.Int64(), false, nil
+}
+
+// sourceSequences returns the sequences of schemas on db, with their owning column
+func sourceSequences(ctx context.Context, db *sql.DB, schemas []string) ([]sequence, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT quote_ident(n.nspname) || '.' || quote_ident(c.relname),
+			COALESCE(quote_ident(tn.nspname) || '.' || quote_ident(t.relname), ''),
+			COALESCE(a.attname, ''),
+			s.seqincrement, s.seqmin, s.seqmax
+		FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_catalog.pg_sequence s ON s.seqrelid = c.oid
+		LEFT JOIN pg_catalog.pg_depend d ON d.classid = 'pg_catalog.pg_class'::regclass
+			AND d.objid = c.oid
+			AND d.refclassid = 'pg_catalog.pg_class'::regclass
+			AND d.deptype IN ('a', 'i')
+		LEFT JOIN pg_catalog.pg_class t ON t.oid = d.refobjid
+		LEFT JOIN pg_catalog.pg_namespace tn ON tn.oid = t.relnamespace
+		LEFT JOIN pg_catalog.pg_attribute a ON a.attrelid = d.refobjid AND a.attnum = d.refobjsubid
+		WHERE c.relkind = 'S'
+			AND n.nspname = ANY($1)
+		ORDER BY 1`, pq.Array(schemas))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var seqs []sequence
+	for rows.Next() {
+		var seq sequence
+		if err := rows.Scan(&seq.name, &seq.table, &seq.column, &seq.increment, &seq.min, &seq.max); err != nil {
+			return nil, err
+		}
+		seqs = append(seqs, seq)
+	}
+	return seqs, rows.Err()
+}
+
+// targetSequence returns the name on db of the sequence seq of the source, the one of
+// its owning column when it has one since identity sequences may be named differently
+func targetSequence(ctx context.Context, db *sql.DB, seq sequence) (string, error) {
+	if seq.table == "" {
+		return seq.name, nil
+	}
+	var name sql.NullString
+	err := db.QueryRowContext(ctx, "SELECT pg_catalog.pg_get_serial_sequence($1, $2)", seq.table, seq.column).Scan(&name)
+	if err != nil {
+		return "", err
+	}
+	if !name.Valid {
+		return seq.name, nil
+	}
+	return name.String, nil
+}
+
+// sequenceConn returns a connection of db, connected with dsn, allowed privilege on
+// sequence seq. The role of the owner of seq is set when the session user is not
+// allowed it but is a member of the owner. The connection is released with the
+// returned function.
+func (c Config) sequenceConn(ctx context.Context, db *sql.DB, dsn, seq, privilege string) (*sql.Conn, func(), error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		allowed, member bool
+		owner           string
+	)
+	err = conn.QueryRowContext(ctx, `
+		SELECT pg_catalog.has_sequence_privilege($1, $2),
+			pg_catalog.pg_has_role(c.relowner, 'MEMBER'),
+			pg_catalog.pg_get_userbyid(c.relowner)
+		FROM pg_catalog.pg_class c
+		WHERE c.oid = $1::regclass`, seq, privilege).Scan(&allowed, &member, &owner)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	if allowed {
+		return conn, func() { conn.Close() }, nil
+	}
+	if !member {
+		conn.Close()
+		return nil, nil, fmt.Errorf("%s is not allowed on sequence %s owned by %s", privilege, seq, owner)
+	}
+	if _, err := c.exec(ctx, conn, dsn, "SET ROLE "+pq.QuoteIdentifier(owner)); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return conn, func() {
+		// the connection goes back to the pool of db
+		conn.ExecContext(context.Background(), "RESET ROLE")
+		conn.Close()
+	}, nil
+}
+
+// readSequence reads the state of seq on db, connected with dsn
+func (c Config) readSequence(ctx context.Context, db *sql.DB, dsn string, seq *sequence) error {
+	conn, release, err := c.sequenceConn(ctx, db, dsn, seq.name, "SELECT")
+	if err != nil {
+		return err
+	}
+	defer release()
+	return conn.QueryRowContext(ctx, "SELECT last_value, is_called FROM "+seq.name).Scan(&seq.lastValue, &seq.isCalled)
+}
+
+// setSequence sets sequence name on db, connected with dsn, to value
+func (c Config) setSequence(ctx context.Context, db *sql.DB, dsn, name string, value int64, isCalled bool) error {
+	conn, release, err := c.sequenceConn(ctx, db, dsn, name, "UPDATE")
+	if err != nil {
+		return err
+	}
+	defer release()
+	_, err = c.exec(ctx, conn, dsn, "SELECT pg_catalog.setval($1::regclass, $2, $3)", name, value, isCalled)
+	return err
+}
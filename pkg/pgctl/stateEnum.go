@@ -7,8 +7,10 @@ type StateEnum int
 const (
 	S_Initial StateEnum = iota
 	S_ValidateConnection
+	S_PreFlightCheck
 	S_CreatePublication
 	S_CopySchema
+	S_MigratePrivileges
 	S_CreateSubscription
 	S_EnableSubscription
 	S_CutOverReadinessCheck
@@ -22,6 +24,7 @@ const (
 	S_DeletePublication
 	S_Completed
 	S_Retry
+	S_Failed
 )
 
 var stateMap = make(map[string]StateEnum)
@@ -32,10 +35,14 @@ func (s StateEnum) String() string {
 		return "initial"
 	case S_ValidateConnection:
 		return "validate_connection"
+	case S_PreFlightCheck:
+		return "pre_flight_check"
 	case S_CreatePublication:
 		return "create_publication"
 	case S_CopySchema:
 		return "copy_schema"
+	case S_MigratePrivileges:
+		return "migrate_privileges"
 	case S_CreateSubscription:
 		return "create_subscription"
 	case S_EnableSubscription:
@@ -62,6 +69,8 @@ func (s StateEnum) String() string {
 		return "completed"
 	case S_Retry:
 		return "retry"
+	case S_Failed:
+		return "failed"
 
 	}
 
@@ -72,8 +81,10 @@ func init() {
 	stateMap[""] = S_Initial
 	stateMap[S_Initial.String()] = S_Initial
 	stateMap[S_ValidateConnection.String()] = S_ValidateConnection
+	stateMap[S_PreFlightCheck.String()] = S_PreFlightCheck
 	stateMap[S_CreatePublication.String()] = S_CreatePublication
 	stateMap[S_CopySchema.String()] = S_CopySchema
+	stateMap[S_MigratePrivileges.String()] = S_MigratePrivileges
 	stateMap[S_CreateSubscription.String()] = S_CreateSubscription
 	stateMap[S_EnableSubscription.String()] = S_EnableSubscription
 	stateMap[S_CutOverReadinessCheck.String()] = S_CutOverReadinessCheck
@@ -87,6 +98,7 @@ func init() {
 	stateMap[S_DeletePublication.String()] = S_DeletePublication
 	stateMap[S_Completed.String()] = S_Completed
 	stateMap[S_Retry.String()] = S_Retry
+	stateMap[S_Failed.String()] = S_Failed
 }
 
 func GetStateEnum(name string) (StateEnum, error) {
@@ -244,6 +244,51 @@ LANGUAGE plpgsql;
 
 GRANT SELECT ON tab_1 TO appuser;
 
+-- tables outside of the public schema
+CREATE SCHEMA IF NOT EXISTS inventory;
+
+CREATE TABLE inventory.items(
+    id serial PRIMARY KEY,
+    name text
+);
+
+INSERT INTO inventory.items(name)
+    SELECT 'item' || generate_series(1, :end);
+
+GRANT USAGE ON SCHEMA inventory TO appuser_a;
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON inventory.items TO appuser_a;
+
+GRANT USAGE, SELECT ON SEQUENCE inventory.items_id_seq TO appuser_a;
+
+-- writes inherited from a group role
+CREATE ROLE writers NOLOGIN;
+
+GRANT INSERT ON tab_2 TO writers;
+
+GRANT writers TO appuser_a;
+
+-- identity column
+CREATE TABLE inventory.orders(
+    id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    item_id int REFERENCES inventory.items(id)
+);
+
+INSERT INTO inventory.orders(item_id)
+    SELECT generate_series(1, :end);
+
+-- privileges of a reporting role, migrated with the schema
+CREATE ROLE reporting NOLOGIN;
+
+GRANT USAGE ON SCHEMA inventory TO reporting;
+
+ALTER DEFAULT PRIVILEGES IN SCHEMA inventory GRANT SELECT ON TABLES TO reporting;
+
+ALTER VIEW vw_tab_1_2 OWNER TO reporting;
+
+ALTER TYPE blox_text OWNER TO reporting;
+GRANT USAGE ON TYPE blox_text TO writers;
+
 --OPERATOR, OPERATOR CLASS, OPERATOR FAMILY and FUNCTION
 CREATE DOMAIN soa_serial_number AS BIGINT
 -- serial value should be in [0..2^32-1] range according to RFC-1982
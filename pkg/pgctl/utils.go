@@ -3,22 +3,29 @@ package pgctl
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"database/sql"
 	"fmt"
 	"io"
 	"os/exec"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/lib/pq"
+
+	"github.com/infobloxopen/db-controller/pkg/audit"
+	"github.com/infobloxopen/db-controller/pkg/redact"
 )
 
-func isAdminUser(db *sql.DB) (bool, error) {
+func isAdminUser(ctx context.Context, db *sql.DB) (bool, error) {
 	var (
 		exists bool
 		user   string
 		count  int
 	)
 
-	err := db.QueryRow("SELECT EXISTS(select  rolname from pg_roles where rolsuper = 't' and rolreplication ='t' and rolname = (select session_user))").Scan(&exists)
+	err := db.QueryRowContext(ctx, "SELECT EXISTS(select  rolname from pg_roles where rolsuper = 't' and rolreplication ='t' and rolname = (select session_user))").Scan(&exists)
 
 	if err != nil {
 		// pc.log.Error(err, "could not query for Subscription name")
@@ -28,7 +35,7 @@ func isAdminUser(db *sql.DB) (bool, error) {
 		return true, nil
 	}
 	//AWS uses a different mechanism to grant admin/replication rules
-	_ = db.QueryRow("SELECT session_user").Scan(&user)
+	_ = db.QueryRowContext(ctx, "SELECT session_user").Scan(&user)
 
 	awsAdminQuery := fmt.Sprintf(`
 	SELECT count(1) 
@@ -36,7 +43,7 @@ func isAdminUser(db *sql.DB) (bool, error) {
 	WHERE pg_has_role( '%s', oid, 'member')
   	AND rolname in ( 'rds_superuser', 'rds_replication');`, user)
 
-	err = db.QueryRow(awsAdminQuery).Scan(&count)
+	err = db.QueryRowContext(ctx, awsAdminQuery).Scan(&count)
 	if err != nil {
 		return false, err
 	}
@@ -47,23 +54,6 @@ func isAdminUser(db *sql.DB) (bool, error) {
 	return true, nil
 }
 
-func isLogical(db *sql.DB) (bool, error) {
-	var exists bool
-
-	err := db.QueryRow("SELECT EXISTS(select  name from pg_settings where name ='wal_level' and setting = 'logical')").Scan(&exists)
-
-	if err != nil {
-		// pc.log.Error(err, "could not query for Subscription name")
-		return false, err
-	}
-	if !exists {
-		// pc.log.Info("creating Subscription:", "with name", subName)
-		return false, fmt.Errorf("db wal_level not set to logical")
-	}
-
-	return true, nil
-}
-
 type ExecOptions struct {
 	StreamPrint bool
 }
@@ -83,7 +73,8 @@ func streamExecOutput(out io.ReadCloser, options ExecOptions) string {
 	return output
 }
 
-func getDB(dsn string, db *sql.DB) (*sql.DB, error) {
+// getDB opens dsn, unless db is given, and checks it is reachable within pingTimeout
+func getDB(ctx context.Context, dsn string, db *sql.DB) (*sql.DB, error) {
 
 	var err error
 	if db == nil {
@@ -91,7 +82,10 @@ func getDB(dsn string, db *sql.DB) (*sql.DB, error) {
 			return nil, err
 		}
 	}
-	if err = db.Ping(); err != nil {
+	pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	if err = db.PingContext(pingCtx); err != nil {
+		db.Close()
 		return nil, err
 	}
 
@@ -106,11 +100,11 @@ func Exec(name string, arg ...string) (string, error) {
 	err := cmd.Run()
 	if err != nil {
 		fmt.Println(err)
-		outStr, errStr := stdout.String(), stderr.String()
+		outStr, errStr := stdout.String(), redact.String(stderr.String())
 
 		if exitError, ok := err.(*exec.ExitError); ok {
 			fmt.Println(exitError.ExitCode(), errStr)
-			return outStr, fmt.Errorf("command %s\nfailed with\n%s", cmd.String(), errStr)
+			return outStr, fmt.Errorf("command %s\nfailed with\n%s", redact.String(cmd.String()), errStr)
 		}
 		return outStr, err
 	}
@@ -124,3 +118,100 @@ func closeDB(log logr.Logger, db *sql.DB) error {
 	}
 	return nil
 }
+
+// exec runs query on db, connected with dsn, and records it in the audit log
+func (c Config) exec(ctx context.Context, db audit.Execer, dsn, query string, args ...interface{}) (sql.Result, error) {
+	return audit.ExecContext(ctx, c.Audit, audit.NewRecord("pgctl", c.Claim, dsn), db, query, args...)
+}
+
+// auditCommand records command, run against dsn since start, in the audit log
+func (c Config) auditCommand(dsn, command string, start time.Time, resultErr *ResultError) {
+	rec := audit.NewRecord("pgctl", c.Claim, dsn)
+	rec.Statement = command
+	var err error
+	if resultErr != nil {
+		err = resultErr.Err
+	}
+	audit.Write(c.Audit, rec, start, err)
+}
+
+// schemas returns the schemas migrated, c.Schemas or the non-system schemas of db
+func (c Config) schemas(ctx context.Context, db *sql.DB) ([]string, error) {
+	if len(c.Schemas) > 0 {
+		return c.Schemas, nil
+	}
+	rows, err := db.QueryContext(ctx, `
+		SELECT nspname
+		FROM pg_catalog.pg_namespace
+		WHERE nspname <> 'information_schema'
+			AND nspname NOT LIKE 'pg\_%'
+		ORDER BY nspname`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var schemas []string
+	for rows.Next() {
+		var schema string
+		if err := rows.Scan(&schema); err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, schema)
+	}
+	return schemas, rows.Err()
+}
+
+// schemaTables returns the qualified names of the tables of schemas
+func schemaTables(ctx context.Context, db *sql.DB, schemas []string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT schemaname, tablename
+		FROM pg_catalog.pg_tables
+		WHERE schemaname = ANY($1)
+		ORDER BY schemaname, tablename`, pq.Array(schemas))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tables []string
+	for rows.Next() {
+		var schema, table string
+		if err := rows.Scan(&schema, &table); err != nil {
+			return nil, err
+		}
+		tables = append(tables, qualifiedName(schema, table))
+	}
+	return tables, rows.Err()
+}
+
+// qualifiedName returns the quoted name of the object name of schema
+func qualifiedName(schema, name string) string {
+	return pq.QuoteIdentifier(schema) + "." + pq.QuoteIdentifier(name)
+}
+
+// escapeLike escapes the wildcards of s for a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// subscriptionProgress returns the tables of subscription subName listed by tablesQuery
+// with their synchronization state
+func subscriptionProgress(ctx context.Context, db *sql.DB, tablesQuery, subName string) (*Progress, error) {
+	rows, err := db.QueryContext(ctx, tablesQuery, subName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	progress := &Progress{}
+	for rows.Next() {
+		var table TableProgress
+		if err := rows.Scan(&table.Name, &table.State); err != nil {
+			return nil, err
+		}
+		progress.Tables = append(progress.Tables, table)
+		progress.TablesTotal++
+		if table.Synced() {
+			progress.TablesSynced++
+		}
+	}
+	return progress, rows.Err()
+}
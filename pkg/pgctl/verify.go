@@ -0,0 +1,257 @@
+package pgctl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// DefaultChunkSize is the number of rows hashed together when Verification.ChunkSize is 0
+const DefaultChunkSize = 10000
+
+// Verification configures the comparison of the content of the tables, on top of their
+// row counts, by validate_migration_status
+type Verification struct {
+	// Checksums compares a hash of the rows of each chunk of the tables, ranges of
+	// ChunkSize primary keys. Tables without a primary key are hashed at once.
+	Checksums bool
+	ChunkSize int
+	// Tables with more than SampleRows rows only have SamplePercent of their chunks
+	// compared, every chunk is when either is 0
+	SampleRows    int64
+	SamplePercent int
+}
+
+func (v Verification) chunkSize() int {
+	if v.ChunkSize <= 0 {
+		return DefaultChunkSize
+	}
+	return v.ChunkSize
+}
+
+// sampleStride returns every how many chunks one is compared for a table of rows
+func (v Verification) sampleStride(rows int64) int {
+	if v.SampleRows <= 0 || rows <= v.SampleRows || v.SamplePercent <= 0 || v.SamplePercent >= 100 {
+		return 1
+	}
+	return (100 + v.SamplePercent - 1) / v.SamplePercent
+}
+
+// checksumSettings make the text of the rows independent of the settings of each host
+var checksumSettings = []string{
+	"SET TIME ZONE 'UTC'",
+	"SET DateStyle = 'ISO, YMD'",
+	"SET IntervalStyle = 'postgres'",
+	"SET extra_float_digits = 3",
+	"SET bytea_output = 'hex'",
+}
+
+// checksumConn returns a connection of db with the checksumSettings applied
+func checksumConn(ctx context.Context, db *sql.DB) (*sql.Conn, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, setting := range checksumSettings {
+		if _, err := conn.ExecContext(ctx, setting); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// tableChecksum compares the chunks of table between source and target
+type tableChecksum struct {
+	source, target *sql.Conn
+	// quoted qualified name of the table
+	table   string
+	columns []string
+	keys    []string
+}
+
+// newTableChecksum reads the columns and primary key of table, schema.name, on the source
+func newTableChecksum(ctx context.Context, source, target *sql.Conn, schema, name string) (*tableChecksum, error) {
+	t := &tableChecksum{source: source, target: target, table: qualifiedName(schema, name)}
+	rows, err := source.QueryContext(ctx, `
+		SELECT a.attname,
+			COALESCE(array_position(i.indkey::int2[], a.attnum), 0)
+		FROM pg_catalog.pg_attribute a
+		LEFT JOIN pg_catalog.pg_index i ON i.indrelid = a.attrelid AND i.indisprimary
+		WHERE a.attrelid = $1::regclass
+			AND a.attnum > 0
+			AND NOT a.attisdropped
+		ORDER BY a.attnum`, t.table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	keys := make(map[int]string)
+	for rows.Next() {
+		var (
+			column string
+			key    int
+		)
+		if err := rows.Scan(&column, &key); err != nil {
+			return nil, err
+		}
+		t.columns = append(t.columns, pq.QuoteIdentifier(column))
+		if key > 0 {
+			keys[key] = pq.QuoteIdentifier(column)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := 1; i <= len(keys); i++ {
+		t.keys = append(t.keys, keys[i])
+	}
+	return t, nil
+}
+
+// chunk is a range of primary keys, from after lo to hi included, unbounded when nil
+type chunk struct {
+	lo, hi []interface{}
+}
+
+func (c chunk) String() string {
+	bound := func(b []interface{}, none string) string {
+		if b == nil {
+			return none
+		}
+		s := make([]string, len(b))
+		for i, v := range b {
+			s[i] = fmt.Sprint(v)
+		}
+		return "(" + strings.Join(s, ", ") + ")"
+	}
+	return bound(c.lo, "start") + " to " + bound(c.hi, "end")
+}
+
+// where returns the condition selecting the rows of c, its parameters numbered from 1
+func (t *tableChecksum) where(c chunk) (string, []interface{}) {
+	var (
+		conds []string
+		args  []interface{}
+	)
+	keys := "(" + strings.Join(t.keys, ", ") + ")"
+	params := func(b []interface{}) string {
+		p := make([]string, len(b))
+		for i := range b {
+			args = append(args, b[i])
+			p[i] = fmt.Sprintf("$%d", len(args))
+		}
+		return "(" + strings.Join(p, ", ") + ")"
+	}
+	if c.lo != nil {
+		conds = append(conds, keys+" > "+params(c.lo))
+	}
+	if c.hi != nil {
+		conds = append(conds, keys+" <= "+params(c.hi))
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// next returns the last primary key of the chunk of size rows following lo, nil when
+// fewer rows are left
+func (t *tableChecksum) next(ctx context.Context, lo []interface{}, size int) ([]interface{}, error) {
+	texts := make([]string, len(t.keys))
+	for i, k := range t.keys {
+		texts[i] = k + "::text"
+	}
+	where, args := t.where(chunk{lo: lo})
+	query := fmt.Sprintf("SELECT %s FROM %s%s ORDER BY %s OFFSET %d LIMIT 1",
+		strings.Join(texts, ", "), t.table, where, strings.Join(t.keys, ", "), size-1)
+	hi := make([]interface{}, len(t.keys))
+	dest := make([]interface{}, len(t.keys))
+	values := make([]string, len(t.keys))
+	for i := range dest {
+		dest[i] = &values[i]
+	}
+	err := t.source.QueryRowContext(ctx, query, args...).Scan(dest...)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range values {
+		hi[i] = v
+	}
+	return hi, nil
+}
+
+// sum returns the number of rows of c and a hash of their content on conn, ordered by
+// primary key or by the hash of the rows when the table has none
+func (t *tableChecksum) sum(ctx context.Context, conn *sql.Conn, c chunk) (int64, string, error) {
+	row := fmt.Sprintf("md5(ROW(%s)::text)", strings.Join(t.columns, ", "))
+	order := row
+	if len(t.keys) > 0 {
+		order = strings.Join(t.keys, ", ")
+	}
+	where, args := t.where(c)
+	query := fmt.Sprintf("SELECT count(*), COALESCE(md5(string_agg(%s, '' ORDER BY %s)), '') FROM %s%s",
+		row, order, t.table, where)
+	var (
+		count int64
+		hash  string
+	)
+	err := conn.QueryRowContext(ctx, query, args...).Scan(&count, &hash)
+	return count, hash, err
+}
+
+// compare hashes the chunks of the table, of rows rows on the source, on both sides and
+// records the chunks compared and mismatched in progress
+func (t *tableChecksum) compare(ctx context.Context, v Verification, rows int64, progress *TableProgress) error {
+	compareChunk := func(c chunk) error {
+		sourceCount, sourceHash, err := t.sum(ctx, t.source, c)
+		if err != nil {
+			return fmt.Errorf("source checksum of %s: %w", t.table, err)
+		}
+		targetCount, targetHash, err := t.sum(ctx, t.target, c)
+		if err != nil {
+			return fmt.Errorf("target checksum of %s: %w", t.table, err)
+		}
+		progress.ChunksVerified++
+		if sourceCount != targetCount || sourceHash != targetHash {
+			progress.ChunksMismatched++
+			if progress.Mismatch == "" {
+				progress.Mismatch = fmt.Sprintf("rows %s: %d rows on the source, %d on the target",
+					c, sourceCount, targetCount)
+				if sourceCount == targetCount {
+					progress.Mismatch = fmt.Sprintf("rows %s: content differs", c)
+				}
+			}
+		}
+		return nil
+	}
+
+	if len(t.keys) == 0 {
+		return compareChunk(chunk{})
+	}
+	stride := v.sampleStride(rows)
+	var lo []interface{}
+	for i := 0; ; i++ {
+		hi, err := t.next(ctx, lo, v.chunkSize())
+		if err != nil {
+			return fmt.Errorf("chunk of %s: %w", t.table, err)
+		}
+		c := chunk{lo: lo, hi: hi}
+		// the last chunk is always compared, it holds the rows added to the target only
+		if i%stride == 0 || hi == nil {
+			if err := compareChunk(c); err != nil {
+				return err
+			}
+		}
+		if hi == nil {
+			return nil
+		}
+		lo = hi
+	}
+}
@@ -0,0 +1,99 @@
+// Package redact masks the passwords of connection strings and statements before
+// they are logged or recorded.
+package redact
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/go-logr/logr"
+)
+
+// Mask replaces redacted passwords
+const Mask = "***"
+
+var (
+	passwordLiteralRegexp = regexp.MustCompile(`(?i)(password\s+)'(?:[^']|'')*'`)
+	passwordKeywordRegexp = regexp.MustCompile(`(?i)(password\s*=\s*)('(?:[^'\\]|\\.)*'|[^\s']+)`)
+	urlCredentialsRegexp  = regexp.MustCompile(`([a-z][a-z0-9+.-]*://[^:/@\s']+:)[^\s']+@`)
+)
+
+// String masks the password literals of statements, the password of key=value
+// connection strings and the credentials of URLs found in s
+func String(s string) string {
+	s = passwordLiteralRegexp.ReplaceAllString(s, "$1'"+Mask+"'")
+	s = passwordKeywordRegexp.ReplaceAllString(s, "${1}"+Mask)
+	return urlCredentialsRegexp.ReplaceAllString(s, "${1}"+Mask+"@")
+}
+
+// Value returns v with its passwords masked. Strings, errors and fmt.Stringers are
+// masked with String, logr.Marshalers are replaced by their MarshalLog value.
+func Value(v interface{}) interface{} {
+	if m, ok := v.(logr.Marshaler); ok {
+		v = m.MarshalLog()
+	}
+	switch val := v.(type) {
+	case string:
+		return String(val)
+	case error:
+		return errors.New(String(val.Error()))
+	case fmt.Stringer:
+		return String(val.String())
+	}
+	return v
+}
+
+// Logger returns a logger masking the passwords of the messages, errors and values
+// logged through it
+func Logger(log logr.Logger) logr.Logger {
+	if _, ok := log.GetSink().(*sink); ok || log.GetSink() == nil {
+		return log
+	}
+	s := log.GetSink()
+	// account for the frame of the redacting sink
+	if cd, ok := s.(logr.CallDepthLogSink); ok {
+		s = cd.WithCallDepth(1)
+	}
+	return logr.New(&sink{LogSink: s})
+}
+
+type sink struct {
+	logr.LogSink
+}
+
+var _ logr.CallDepthLogSink = &sink{}
+
+func (s *sink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.LogSink.Info(level, String(msg), values(keysAndValues)...)
+}
+
+func (s *sink) Error(err error, msg string, keysAndValues ...interface{}) {
+	if err != nil {
+		err = errors.New(String(err.Error()))
+	}
+	s.LogSink.Error(err, String(msg), values(keysAndValues)...)
+}
+
+func (s *sink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return &sink{LogSink: s.LogSink.WithValues(values(keysAndValues)...)}
+}
+
+func (s *sink) WithName(name string) logr.LogSink {
+	return &sink{LogSink: s.LogSink.WithName(name)}
+}
+
+func (s *sink) WithCallDepth(depth int) logr.LogSink {
+	if cd, ok := s.LogSink.(logr.CallDepthLogSink); ok {
+		return &sink{LogSink: cd.WithCallDepth(depth)}
+	}
+	return s
+}
+
+func values(keysAndValues []interface{}) []interface{} {
+	redacted := make([]interface{}, len(keysAndValues))
+	for i, v := range keysAndValues {
+		redacted[i] = Value(v)
+	}
+	return redacted
+}